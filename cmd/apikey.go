@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/Sergi-Ch/WB_L0_2025/internal/auth"
+)
+
+// runAPIKey печатает новый ключ (отдается клиенту) и его хэш (в auth.api_keys)
+func runAPIKey(_ context.Context, args []string) error {
+	fs := flag.NewFlagSet("apikey", flag.ContinueOnError)
+	name := fs.String("name", "client", "key name for auth.api_keys")
+	role := fs.String("role", string(auth.RoleReader), "comma-separated roles: reader, writer or admin, plus pii to see personal data")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	roles := strings.Split(*role, ",")
+	for i, r := range roles {
+		roles[i] = strings.TrimSpace(r)
+		if _, err := auth.ParseRole(roles[i]); err != nil {
+			return err
+		}
+	}
+
+	key, hash, err := auth.NewAPIKey()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("key: %s\n\nauth:\n  api_keys:\n    - name: %s\n      hash: %s\n      roles: [%s]\n", key, *name, hash, strings.Join(roles, ", "))
+	return nil
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/Sergi-Ch/WB_L0_2025/internal/archive"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/auth"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/config"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/kafka"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/logger"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/metrics"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/pii"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/repository"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/service"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/tracing"
+)
+
+// app - общая для всех подкоманд сборка зависимостей
+type app struct {
+	cfg     *config.Config
+	log     *slog.Logger
+	metrics *metrics.Metrics
+
+	store storage
+	redis *repository.RedisCache
+
+	// service - сервис без декоратора метрик (нужен CacheWarmer'у),
+	// orders - он же с метриками для HTTP и Kafka
+	service *service.OrderService
+	orders  service.OrderServiceInterface
+
+	shutdownTracing func(context.Context) error
+}
+
+// storage - хранилище заказов выбранного драйвера (storage.driver)
+type storage interface {
+	repository.PostgresRepInterface
+	Ping(ctx context.Context) error
+	Close() error
+}
+
+// loadConfig разбирает флаги подкоманды вместе с флагами конфигурации
+// и создает логгер
+func loadConfig(fs *flag.FlagSet, args []string) (*config.Config, *slog.Logger, error) {
+	cfg, err := config.Load(fs, args)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	log, err := logger.New(os.Stdout, cfg.Log.Level, cfg.Log.Format)
+	if err != nil {
+		return nil, nil, err
+	}
+	slog.SetDefault(log)
+	return cfg, log, nil
+}
+
+func newApp(ctx context.Context, cfg *config.Config, log *slog.Logger) (*app, error) {
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing.Exporter, cfg.Tracing.ServiceName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup tracing: %w", err)
+	}
+
+	m := metrics.New()
+	var store storage
+	switch cfg.Storage.Driver {
+	case "sqlite":
+		if store, err = repository.NewSQLiteRepository(cfg.SQLite.Path, log); err != nil {
+			return nil, fmt.Errorf("failed to open sqlite: %w", err)
+		}
+	default:
+		pg, err := repository.NewPostgresRepository(cfg.Postgres.ConnString(), log)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to postgres: %w", err)
+		}
+		m.RegisterPgxPool(pg)
+		store = pg
+	}
+
+	redisCache := repository.NewRedisCache(cfg.Redis.Addr, cfg.Redis.Password, cfg.Redis.DB, cfg.Redis.TTL, log)
+	svc := service.NewOrderService(store, m.InstrumentCache(redisCache), log)
+
+	return &app{
+		cfg:             cfg,
+		log:             log,
+		metrics:         m,
+		store:           store,
+		redis:           redisCache,
+		service:         svc,
+		orders:          m.InstrumentService(svc),
+		shutdownTracing: shutdownTracing,
+	}, nil
+}
+
+// Close закрывает соединения в обратном порядке создания
+func (a *app) Close(ctx context.Context) {
+	if err := a.redis.Close(); err != nil {
+		a.log.Error("error of graceful shutdown redis", logger.Err(err))
+	}
+	if err := a.store.Close(); err != nil {
+		a.log.Error("error closing storage", logger.Err(err))
+	}
+	// отправка оставшихся спанов
+	if err := a.shutdownTracing(ctx); err != nil {
+		a.log.Error("error flushing traces", logger.Err(err))
+	}
+}
+
+// partitionJob - обслуживание партиций; nil, если оно выключено или
+// хранилище без партиций (sqlite)
+func (a *app) partitionJob() *service.PartitionJob {
+	store, ok := a.store.(service.PartitionMaintainer)
+	if !a.cfg.Partitions.Enabled || !ok {
+		return nil
+	}
+	p := a.cfg.Partitions
+	return service.NewPartitionJob(store, service.PartitionJobConfig{
+		Interval:        p.Interval,
+		Premake:         p.Premake,
+		RetentionMonths: p.RetentionMonths,
+		Detach:          p.RetentionMode == "detach",
+	}, a.log)
+}
+
+// archiver - архивация старых заказов из хранилища (archive.*)
+func (a *app) archiver() *archive.Archiver {
+	return archive.NewArchiver(a.store, archive.Options{
+		Dir:         a.cfg.Archive.Dir,
+		Compression: a.cfg.Archive.Compression,
+		ChunkSize:   a.cfg.Archive.ChunkSize,
+		BatchSize:   a.cfg.Archive.BatchSize,
+	}, a.log)
+}
+
+// kafkaSecurity переводит настройки TLS/SASL из конфигурации в формат пакета kafka
+func kafkaSecurity(cfg *config.Config) kafka.SecurityConfig {
+	tls, sasl := cfg.Kafka.TLS, cfg.Kafka.SASL
+	return kafka.SecurityConfig{
+		TLS: kafka.TLSConfig{
+			Enabled:            tls.Enabled,
+			CAFile:             tls.CAFile,
+			CertFile:           tls.CertFile,
+			KeyFile:            tls.KeyFile,
+			ServerName:         tls.ServerName,
+			InsecureSkipVerify: tls.InsecureSkipVerify,
+		},
+		SASL: kafka.SASLConfig{
+			Mechanism:    sasl.Mechanism,
+			Username:     sasl.Username,
+			Password:     sasl.Password,
+			PasswordFile: sasl.PasswordFile,
+		},
+	}
+}
+
+// newAuthenticator - проверка ключей и токенов из auth, nil - аутентификация выключена
+func newAuthenticator(cfg *config.Config) (*auth.Authenticator, error) {
+	if !cfg.Auth.Enabled {
+		return nil, nil
+	}
+	keys := make([]auth.APIKey, 0, len(cfg.Auth.APIKeys))
+	for _, k := range cfg.Auth.APIKeys {
+		key := auth.APIKey{Name: k.Name, Hash: k.Hash}
+		for _, name := range k.Roles {
+			role, err := auth.ParseRole(name)
+			if err != nil {
+				return nil, fmt.Errorf("api key %s: %w", k.Name, err)
+			}
+			key.Roles = append(key.Roles, role)
+		}
+		keys = append(keys, key)
+	}
+	jwt := cfg.Auth.JWT
+	return auth.NewAuthenticator(keys, auth.JWTConfig{
+		JWKSFile:   jwt.JWKSFile,
+		Issuer:     jwt.Issuer,
+		Audience:   jwt.Audience,
+		RolesClaim: jwt.RolesClaim,
+		Leeway:     jwt.Leeway,
+	})
+}
+
+// piiPolicy - правила маскирования из pii.fields
+func piiPolicy(cfg *config.Config) (*pii.Policy, error) {
+	policy, err := pii.NewPolicy(cfg.PII.Fields)
+	if err != nil {
+		return nil, fmt.Errorf("pii.fields: %w", err)
+	}
+	return policy, nil
+}
+
+// kafkaTopics - топики из конфигурации в формате kafka.Manager
+func kafkaTopics(cfg *config.Config) []kafka.TopicConfig {
+	var topics []kafka.TopicConfig
+	for _, t := range cfg.Kafka.TopicList() {
+		topics = append(topics, kafka.TopicConfig{
+			Topic:       t.Name,
+			Handler:     t.Handler,
+			Decoding:    t.Decoding,
+			Concurrency: t.Concurrency,
+			DLQTopic:    t.DLQTopic,
+		})
+	}
+	return topics
+}
+
+// kafkaHandlers - типы обработчиков, доступные топикам в kafka.topics[].handler
+func (a *app) kafkaHandlers() *kafka.HandlerRegistry {
+	handlers := kafka.NewHandlerRegistry()
+	handlers.Register("order", kafka.OrderHandlerFactory(a.orders))
+	handlers.Register("order_cancel", kafka.OrderCancelHandlerFactory(a.orders))
+	return handlers
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Sergi-Ch/WB_L0_2025/internal/archive"
+)
+
+func runArchive(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("archive", flag.ContinueOnError)
+	before := fs.String("before", "", "archive orders created before this RFC3339 timestamp")
+	olderThan := fs.Duration("older-than", 0, "archive orders older than this, e.g. 8760h (alternative to -before)")
+	del := fs.Bool("delete", true, "delete archived orders after the archive is verified")
+
+	cfg, log, err := loadConfig(fs, args)
+	if err != nil {
+		return err
+	}
+
+	var cutoff time.Time
+	switch {
+	case *before != "" && *olderThan != 0:
+		return errors.New("use either -before or -older-than")
+	case *before != "":
+		if cutoff, err = parseTime(*before); err != nil {
+			return fmt.Errorf("invalid -before: %w", err)
+		}
+	case *olderThan > 0:
+		cutoff = time.Now().Add(-*olderThan)
+	default:
+		return errors.New("-before or -older-than is required")
+	}
+
+	a, err := newApp(ctx, cfg, log)
+	if err != nil {
+		return err
+	}
+	defer a.Close(context.Background())
+
+	res, err := a.archiver().Archive(ctx, cutoff, *del)
+	if err != nil {
+		return err
+	}
+	if res.Dir != "" {
+		log.Info("archive created",
+			slog.String("dir", res.Dir),
+			slog.Int("orders", res.Manifest.Orders),
+			slog.Int("deleted", res.Deleted),
+		)
+	}
+	return nil
+}
+
+func runRestore(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	dir := fs.String("dir", "", "archive directory (with manifest.json)")
+	verifyOnly := fs.Bool("verify", false, "only check the archive against its manifest")
+
+	cfg, log, err := loadConfig(fs, args)
+	if err != nil {
+		return err
+	}
+	if *dir == "" {
+		return errors.New("-dir is required")
+	}
+
+	if *verifyOnly {
+		m, err := archive.Read(*dir, nil)
+		if err != nil {
+			return err
+		}
+		log.Info("archive is valid",
+			slog.Int("orders", m.Orders),
+			slog.Int("chunks", len(m.Chunks)),
+			slog.Time("from", m.From),
+			slog.Time("to", m.To),
+		)
+		return nil
+	}
+
+	a, err := newApp(ctx, cfg, log)
+	if err != nil {
+		return err
+	}
+	defer a.Close(context.Background())
+
+	_, err = a.archiver().Restore(ctx, *dir)
+	return err
+}
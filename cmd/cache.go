@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+
+	"github.com/Sergi-Ch/WB_L0_2025/internal/service"
+)
+
+func runCache(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errors.New("expected subcommand: cache warm | cache flush")
+	}
+
+	switch args[0] {
+	case "warm":
+		fs := flag.NewFlagSet("cache warm", flag.ContinueOnError)
+		limit := fs.Int("limit", -1, "number of newest orders to load (default cache.warmup_limit)")
+		cfg, log, err := loadConfig(fs, args[1:])
+		if err != nil {
+			return err
+		}
+		if *limit >= 0 {
+			cfg.Cache.WarmupLimit = *limit
+		}
+
+		a, err := newApp(ctx, cfg, log)
+		if err != nil {
+			return err
+		}
+		defer a.Close(context.Background())
+
+		return service.NewCacheWarmer(a.service, cfg.Cache.WarmupLimit, log).Run(ctx)
+
+	case "flush":
+		fs := flag.NewFlagSet("cache flush", flag.ContinueOnError)
+		cfg, log, err := loadConfig(fs, args[1:])
+		if err != nil {
+			return err
+		}
+
+		a, err := newApp(ctx, cfg, log)
+		if err != nil {
+			return err
+		}
+		defer a.Close(context.Background())
+
+		n, err := a.redis.Flush(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to flush cache: %w", err)
+		}
+		log.Info("cache flushed", slog.Int("deleted", n))
+		return nil
+
+	default:
+		return fmt.Errorf("unknown cache subcommand %q (expected warm or flush)", args[0])
+	}
+}
+
+func runConfig(_ context.Context, args []string) error {
+	fs := flag.NewFlagSet("config", flag.ContinueOnError)
+	cfg, _, err := loadConfig(fs, args)
+	if err != nil {
+		return err
+	}
+	fmt.Print(cfg)
+	return nil
+}
@@ -2,147 +2,75 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"github.com/Sergi-Ch/WB_L0_2025/internal/kafka"
-	"github.com/go-chi/chi/v5"
-	"github.com/jackc/pgx/v5/pgxpool"
-	"log"
-	"net/http"
 	"os"
 	"os/signal"
+	"sort"
 	"syscall"
-	"time"
-
-	prHttp "github.com/Sergi-Ch/WB_L0_2025/internal/delivery/http"
-	"github.com/Sergi-Ch/WB_L0_2025/internal/repository"
-	"github.com/Sergi-Ch/WB_L0_2025/internal/service"
 )
 
-func runMigrations(dsn string) error {
-	ctx := context.Background()
-
-	// Используем pgxpool вместо database/sql
-	pool, err := pgxpool.New(ctx, dsn)
-	if err != nil {
-		return fmt.Errorf("failed to create connection pool: %w", err)
-	}
-	defer pool.Close()
-
-	// Проверяем соединение
-	if err := pool.Ping(ctx); err != nil {
-		return fmt.Errorf("failed to ping database: %w", err)
-	}
-
-	// Читаем файл миграции
-	migrationSQL, err := os.ReadFile("migrations/001_init.up.sql")
-	if err != nil {
-		return fmt.Errorf("failed to read migration file: %w", err)
-	}
-
-	// Выполняем миграцию
-	_, err = pool.Exec(ctx, string(migrationSQL))
-	if err != nil {
-		return fmt.Errorf("failed to execute migration: %w", err)
-	}
+// command - подкоманда бинаря: order-service <command> [flags]
+type command struct {
+	usage string
+	run   func(ctx context.Context, args []string) error
+}
 
-	log.Println("Migrations executed successfully")
-	return nil
+var commands = map[string]command{
+	"serve":        {usage: "run HTTP API and Kafka consumer (each can be disabled)", run: runServe},
+	"consume-only": {usage: "run only the Kafka consumer", run: runConsumeOnly},
+	"migrate":      {usage: "apply pending database migrations", run: runMigrate},
+	"replay":       {usage: "re-ingest a Kafka partition from an offset or timestamp", run: runReplay},
+	"export":       {usage: "export orders to NDJSON", run: runExport},
+	"import":       {usage: "import orders from NDJSON", run: runImport},
+	"cache":        {usage: "cache maintenance: cache warm | cache flush", run: runCache},
+	"archive":      {usage: "move old orders to compressed NDJSON files", run: runArchive},
+	"restore":      {usage: "re-import an archive created by archive", run: runRestore},
+	"partitions":   {usage: "monthly partitions: partitions list | partitions maintain", run: runPartitions},
+	"config":       {usage: "print the effective configuration with secrets redacted", run: runConfig},
+	"apikey":       {usage: "generate an API key and the hash for auth.api_keys", run: runAPIKey},
 }
-func main() {
-	// подгрузка переменных окружения
-	//err := godotenv.Load(".env")
-	//if err != nil {
-	//	log.Fatalf("error of loading .env %v", err)
-	//}
 
-	password := os.Getenv("DATABASE_PASSWORD")
-	dataBaseName := os.Getenv("DATABASE_NAME")
-	userName := os.Getenv("USER_NAME")
-	port := os.Getenv("APP_PORT")
-	dsn := "postgres://" + userName + ":" + password + "@postgres:5432/" + dataBaseName
-	pgRepo, err := repository.NewPostgresRepository(dsn)
-	if err != nil {
-		log.Fatalf("failed to connect to postgres: %v", err)
+func main() {
+	name, args := "serve", os.Args[1:]
+	// без подкоманды (или сразу с флагами) бинарь работает как раньше - serve
+	if len(args) > 0 && args[0] != "" && args[0][0] != '-' {
+		name, args = args[0], args[1:]
 	}
-
-	log.Println("Running database migrations...")
-	if err := runMigrations(dsn); err != nil {
-		log.Fatalf("Migrations failed: %v", err)
+	if name == "help" {
+		usage()
+		return
 	}
 
-	//инициализация слоев
-	redisCache := repository.NewRedisCache("redis:6379", 30*time.Minute)
-	orderService := service.NewOrderService(pgRepo, redisCache)
-
-	handler := prHttp.NewOrderHandler(orderService)
-	r := chi.NewRouter()
-	handler.RegisterRoutes(r)
-
-	srv := &http.Server{
-		Addr:         ":" + port,
-		Handler:      r,
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 10 * time.Second,
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+		usage()
+		os.Exit(2)
 	}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	//подключение kafka
-	brokers := []string{"kafka:29092"}
-	topic := "orders"
-	groupID := "order-service"
-
-	consumer := kafka.NewConsumer(brokers, topic, groupID, orderService)
-	go func() {
-		if err := consumer.Start(ctx); err != nil {
-			log.Fatalf("kafka consumer failed: %v", err)
-		}
-	}()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	go func() {
-		//запуск сервера
-		log.Printf("Server started on :%s\n", port)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("server failed: %v", err)
+	if err := cmd.run(ctx, args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
 		}
-	}()
-
-	//graceful shutdown
-	stop := make(chan os.Signal, 1)
-	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
-
-	<-stop
-	log.Println("Shutting down...")
-	cancel()
-
-	// shutdown http server
-	ctxTimeOut, cancelTimeOut := context.WithTimeout(context.Background(), time.Second*5)
-	defer cancelTimeOut()
-	if err := srv.Shutdown(ctxTimeOut); err != nil {
-		log.Printf("http server shutdown error: %v", err)
+		fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+		os.Exit(1)
 	}
+}
 
-	// kafka
-	if err := consumer.Close(); err != nil {
-		log.Printf("Error closing kafka consumer>>> %v", err)
-	}
-	log.Println("Server stopped gracefully")
-
-	// Redis соединение
-	if redisCache != nil {
-		if err := redisCache.Close(); err != nil {
-			log.Printf("error of graceful shutdown redis: %v", err)
-		} else {
-			log.Printf("succesful of graceful shutdown redis")
-		}
-
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: order-service <command> [flags]\n\ncommands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
 	}
-
-	// PostgreSQL соединение
-	if err := pgRepo.Close(); err != nil {
-		log.Printf("error closing postgres: %v", err)
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-13s %s\n", name, commands[name].usage)
 	}
-
-	log.Println("Server stopped gracefully")
+	fmt.Fprintln(os.Stderr, "\nrun 'order-service <command> -h' for command flags")
 }
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+
+	"github.com/Sergi-Ch/WB_L0_2025/internal/config"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/migrate"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/repository"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func runMigrate(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	cfg, log, err := loadConfig(fs, args)
+	if err != nil {
+		return err
+	}
+	return migrateUp(ctx, cfg, log)
+}
+
+func migrateUp(ctx context.Context, cfg *config.Config, log *slog.Logger) error {
+	if cfg.Storage.Driver == "sqlite" {
+		return migrateSQLite(ctx, cfg, log)
+	}
+
+	pool, err := pgxpool.New(ctx, cfg.Postgres.ConnString())
+	if err != nil {
+		return fmt.Errorf("failed to create connection pool: %w", err)
+	}
+	defer pool.Close()
+
+	// Проверяем соединение
+	if err := pool.Ping(ctx); err != nil {
+		return fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	log.Info("running database migrations", slog.String("dir", cfg.Postgres.MigrationsDir))
+	applied, err := migrate.Up(ctx, pool, cfg.Postgres.MigrationsDir, log)
+	if err != nil {
+		return fmt.Errorf("migrations failed: %w", err)
+	}
+	log.Info("migrations executed successfully", slog.Int("applied", len(applied)))
+	return nil
+}
+
+func migrateSQLite(ctx context.Context, cfg *config.Config, log *slog.Logger) error {
+	db, err := repository.OpenSQLite(cfg.SQLite.Path)
+	if err != nil {
+		return fmt.Errorf("failed to open sqlite: %w", err)
+	}
+	defer db.Close()
+
+	log.Info("running database migrations", slog.String("dir", cfg.SQLite.MigrationsDir), slog.String("path", cfg.SQLite.Path))
+	applied, err := migrate.UpSQLite(ctx, db, cfg.SQLite.MigrationsDir, log)
+	if err != nil {
+		return fmt.Errorf("migrations failed: %w", err)
+	}
+	log.Info("migrations executed successfully", slog.Int("applied", len(applied)))
+	return nil
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/Sergi-Ch/WB_L0_2025/domain"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/logger"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/pii"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/repository"
+)
+
+// maxNDJSONLine - максимальный размер одного заказа в файле импорта
+const maxNDJSONLine = 10 << 20
+
+func runExport(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	out := fs.String("out", "-", "output file, - for stdout")
+	since := fs.String("since", "", "only orders created at or after this RFC3339 timestamp")
+	until := fs.String("until", "", "only orders created before this RFC3339 timestamp")
+	customer := fs.String("customer", "", "only orders of this customer_id")
+	includeDeleted := fs.Bool("include-deleted", false, "also export soft-deleted orders")
+	batch := fs.Int("batch", 500, "orders loaded per query")
+	unmasked := fs.Bool("unmasked", false, "write personal data as is; without it fields are masked by pii.fields "+
+		"and the file is not meant for import")
+
+	cfg, log, err := loadConfig(fs, args)
+	if err != nil {
+		return err
+	}
+	// персональные данные маскируются по умолчанию, как в ответах API;
+	// полный файл для import - только по явному -unmasked
+	var masking *pii.Policy
+	if !*unmasked {
+		if masking, err = piiPolicy(cfg); err != nil {
+			return err
+		}
+	}
+
+	filter := repository.OrderFilter{CustomerID: *customer, IncludeDeleted: *includeDeleted, Limit: *batch}
+	if filter.CreatedFrom, err = parseTime(*since); err != nil {
+		return fmt.Errorf("invalid -since: %w", err)
+	}
+	if filter.CreatedTo, err = parseTime(*until); err != nil {
+		return fmt.Errorf("invalid -until: %w", err)
+	}
+
+	w := io.Writer(os.Stdout)
+	if *out != "-" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+
+	a, err := newApp(ctx, cfg, log)
+	if err != nil {
+		return err
+	}
+	defer a.Close(context.Background())
+
+	exported := 0
+	for {
+		orders, err := a.service.ListOrders(ctx, filter)
+		if err != nil {
+			return err
+		}
+		for _, order := range orders {
+			var v any = order
+			if masking != nil {
+				if v, err = masking.Order(order); err != nil {
+					return err
+				}
+			}
+			if err := enc.Encode(v); err != nil {
+				return fmt.Errorf("failed to write order %s: %w", order.OrderUid, err)
+			}
+		}
+		exported += len(orders)
+		if len(orders) < filter.Limit {
+			break
+		}
+		filter.Offset += filter.Limit
+	}
+
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	log.Info("orders exported", slog.Int("orders", exported), slog.String("out", *out), slog.Bool("masked", masking != nil))
+	return nil
+}
+
+func runImport(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	in := fs.String("in", "-", "input NDJSON file, - for stdin")
+
+	cfg, log, err := loadConfig(fs, args)
+	if err != nil {
+		return err
+	}
+
+	r := io.Reader(os.Stdin)
+	if *in != "-" {
+		f, err := os.Open(*in)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	a, err := newApp(ctx, cfg, log)
+	if err != nil {
+		return err
+	}
+	defer a.Close(context.Background())
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxNDJSONLine)
+
+	imported, failed, line := 0, 0, 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var order domain.Order
+		if err := json.Unmarshal(scanner.Bytes(), &order); err != nil {
+			failed++
+			log.Warn("invalid order line", slog.Int("line", line), logger.Err(err))
+			continue
+		}
+		if err := a.orders.SaveOrder(ctx, &order); err != nil {
+			failed++
+			log.Warn("failed to import order", slog.Int("line", line), slog.String("order_uid", order.OrderUid), logger.Err(err))
+			continue
+		}
+		imported++
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read input at line %d: %w", line+1, err)
+	}
+
+	log.Info("orders imported", slog.Int("imported", imported), slog.Int("failed", failed))
+	if failed > 0 {
+		return fmt.Errorf("%d of %d orders were not imported", failed, imported+failed)
+	}
+	return nil
+}
+
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
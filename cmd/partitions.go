@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/Sergi-Ch/WB_L0_2025/internal/repository"
+)
+
+func runPartitions(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errors.New("expected subcommand: partitions list | partitions maintain")
+	}
+
+	fs := flag.NewFlagSet("partitions "+args[0], flag.ContinueOnError)
+	cfg, log, err := loadConfig(fs, args[1:])
+	if err != nil {
+		return err
+	}
+	a, err := newApp(ctx, cfg, log)
+	if err != nil {
+		return err
+	}
+	defer a.Close(context.Background())
+
+	pg, ok := a.store.(*repository.PostgresRepository)
+	if !ok {
+		return fmt.Errorf("partitions are not supported by storage.driver %s", cfg.Storage.Driver)
+	}
+
+	switch args[0] {
+	case "list":
+		months, err := pg.Partitions(ctx)
+		if err != nil {
+			return err
+		}
+		for _, m := range months {
+			fmt.Println(m.Format("2006-01"))
+		}
+		return nil
+
+	case "maintain":
+		// один проход фоновой задачи, даже если partitions.enabled=false
+		cfg.Partitions.Enabled = true
+		return a.partitionJob().RunOnce(ctx)
+
+	default:
+		return fmt.Errorf("unknown partitions subcommand %q (expected list or maintain)", args[0])
+	}
+}
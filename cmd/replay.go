@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Sergi-Ch/WB_L0_2025/internal/kafka"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/tracing"
+)
+
+func runReplay(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	topic := fs.String("topic", "", "topic to replay (default kafka.topic)")
+	handlerName := fs.String("handler", "", "handler type (default: the topic's handler from config, or order, e.g. for a DLQ)")
+	partitions := fs.String("partitions", "", "comma-separated partitions, empty for all")
+	fromOffset := fs.Int64("from-offset", -1, "first offset to replay")
+	toOffset := fs.Int64("to-offset", -1, "stop before this offset (default end of partition)")
+	fromTime := fs.String("from-time", "", "replay messages written at or after this RFC3339 timestamp")
+	toTime := fs.String("to-time", "", "stop at messages written at or after this RFC3339 timestamp")
+	dryRun := fs.Bool("dry-run", false, "only decode and validate, do not save")
+	details := fs.Bool("details", false, "print a result line per message (always on with -dry-run)")
+	reportPath := fs.String("report", "", "also write the summary report as JSON to this file")
+	idleTimeout := fs.Duration("idle-timeout", kafka.DefaultReplayIdleTimeout, "stop reading a partition after this long without messages")
+	timeout := fs.Duration("timeout", 0, "stop the whole replay after this long (0 - no limit)")
+
+	cfg, log, err := loadConfig(fs, args)
+	if err != nil {
+		return err
+	}
+
+	rng := kafka.ReplayRange{Topic: cfg.Kafka.Topic, FromOffset: *fromOffset, ToOffset: *toOffset}
+	if *topic != "" {
+		rng.Topic = *topic
+	}
+	if rng.Partitions, err = parsePartitions(*partitions); err != nil {
+		return err
+	}
+	if rng.FromTime, err = parseTime(*fromTime); err != nil {
+		return fmt.Errorf("invalid -from-time: %w", err)
+	}
+	if rng.ToTime, err = parseTime(*toTime); err != nil {
+		return fmt.Errorf("invalid -to-time: %w", err)
+	}
+	switch {
+	case !rng.FromTime.IsZero() && *fromOffset >= 0:
+		return errors.New("use either -from-offset or -from-time, not both")
+	case !rng.ToTime.IsZero() && *toOffset >= 0:
+		return errors.New("use either -to-offset or -to-time, not both")
+	case rng.FromTime.IsZero() && *fromOffset < 0:
+		return errors.New("-from-offset or -from-time is required")
+	}
+
+	a, err := newApp(ctx, cfg, log)
+	if err != nil {
+		return err
+	}
+	defer a.Close(context.Background())
+
+	replayer, err := kafka.NewReplayer(cfg.Kafka.Brokers, kafkaSecurity(cfg), log)
+	if err != nil {
+		return err
+	}
+	replayer.DryRun = *dryRun
+	replayer.IdleTimeout = *idleTimeout
+	if *details || *dryRun {
+		enc := json.NewEncoder(os.Stdout)
+		replayer.OnResult = func(res kafka.ReplayResult) { _ = enc.Encode(res) }
+	}
+
+	// обработчик и декодер - как у топика в конфигурации; DLQ-топики
+	// там не перечислены и по умолчанию разбираются как заказы
+	name, decoding := "order", ""
+	for _, t := range kafkaTopics(cfg) {
+		if t.Topic == rng.Topic {
+			name, decoding = t.Handler, t.Decoding
+		}
+	}
+	if *handlerName != "" {
+		name = *handlerName
+	}
+
+	var handler kafka.Handler
+	if *dryRun {
+		if name != "order" {
+			return fmt.Errorf("-dry-run is only supported for the order handler, topic %s uses %s", rng.Topic, name)
+		}
+		handler = kafka.NewOrderValidationHandler(a.service)
+	} else {
+		decoder, err := kafka.NewDecoder(decoding)
+		if err != nil {
+			return err
+		}
+		if handler, err = a.kafkaHandlers().Build(name, decoder, log); err != nil {
+			return err
+		}
+	}
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+	report, err := replayer.Replay(ctx, rng, tracing.KafkaMiddleware(handler))
+
+	// отчет печатается и при ошибке, чтобы было видно, докуда дошли
+	printReplayReport(report)
+	if *reportPath != "" {
+		data, _ := json.MarshalIndent(report, "", "  ")
+		if werr := os.WriteFile(*reportPath, append(data, '\n'), 0o644); werr != nil {
+			return errors.Join(err, fmt.Errorf("failed to write report: %w", werr))
+		}
+	}
+	return err
+}
+
+func printReplayReport(r kafka.ReplayReport) {
+	mode := "replay"
+	if r.DryRun {
+		mode = "replay (dry-run)"
+	}
+	fmt.Fprintf(os.Stderr, "\n%s of %s finished in %s\n", mode, r.Topic, r.Duration)
+
+	tw := tabwriter.NewWriter(os.Stderr, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "partition\tfrom\tto\tread")
+	for _, p := range r.Partitions {
+		note := ""
+		if p.Idle {
+			note = "stopped: idle timeout"
+		}
+		fmt.Fprintf(tw, "%d\t%d\t%d\t%d\t%s\n", p.Partition, p.FromOffset, p.ToOffset, p.Read, note)
+	}
+	tw.Flush()
+
+	fmt.Fprintf(os.Stderr, "read %d: accepted %d, rejected %d, duplicate %d, failed %d\n",
+		r.Read, r.Accepted, r.Rejected, r.Duplicate, r.Failed)
+}
+
+func parsePartitions(s string) ([]int, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var ids []int
+	for _, part := range strings.Split(s, ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || id < 0 {
+			return nil, fmt.Errorf("invalid partition %q", part)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log/slog"
+	"net/http"
+	"sync"
+
+	"github.com/Sergi-Ch/WB_L0_2025/internal/auth"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/config"
+	prHttp "github.com/Sergi-Ch/WB_L0_2025/internal/delivery/http"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/health"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/kafka"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/logger"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/pii"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/service"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/tracing"
+	"github.com/go-chi/chi/v5"
+)
+
+type serveOptions struct {
+	http     bool
+	consumer bool
+	migrate  bool
+}
+
+func runServe(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	var opts serveOptions
+	fs.BoolVar(&opts.http, "http", true, "serve the HTTP API")
+	fs.BoolVar(&opts.consumer, "consumer", true, "consume orders from Kafka")
+	fs.BoolVar(&opts.migrate, "migrate", true, "apply pending migrations before start")
+
+	cfg, log, err := loadConfig(fs, args)
+	if err != nil {
+		return err
+	}
+	return serve(ctx, cfg, log, opts)
+}
+
+func runConsumeOnly(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("consume-only", flag.ContinueOnError)
+	opts := serveOptions{consumer: true}
+	fs.BoolVar(&opts.migrate, "migrate", false, "apply pending migrations before start")
+
+	cfg, log, err := loadConfig(fs, args)
+	if err != nil {
+		return err
+	}
+	return serve(ctx, cfg, log, opts)
+}
+
+func serve(ctx context.Context, cfg *config.Config, log *slog.Logger, opts serveOptions) error {
+	if !opts.http && !opts.consumer {
+		return errors.New("nothing to run: both -http and -consumer are disabled")
+	}
+
+	// спецификация встроена в бинарник, поэтому проверяется до подключений
+	var validate func(http.Handler) http.Handler
+	if opts.http && cfg.HTTP.ValidateRequests {
+		var err error
+		if validate, err = prHttp.ValidateRequests(log); err != nil {
+			return err
+		}
+	}
+	// JWKS тоже читается до подключений: с битым файлом сервис не стартует
+	var authenticator *auth.Authenticator
+	if opts.http {
+		var err error
+		if authenticator, err = newAuthenticator(cfg); err != nil {
+			return err
+		}
+		if authenticator == nil {
+			log.Warn("authentication disabled: order API is open to anyone who can reach the port (auth.enabled)")
+		}
+	}
+	var masking *pii.Policy
+	if opts.http && cfg.PII.Enabled {
+		var err error
+		if masking, err = piiPolicy(cfg); err != nil {
+			return err
+		}
+		log.Info("personal data masked for callers without the pii role", slog.Any("fields", masking.Rules()))
+	}
+
+	if opts.migrate {
+		if err := migrateUp(ctx, cfg, log); err != nil {
+			return err
+		}
+	}
+
+	a, err := newApp(ctx, cfg, log)
+	if err != nil {
+		return err
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		fatalErr error
+		once     sync.Once
+	)
+	// ошибка любого компонента останавливает весь процесс
+	fail := func(err error) {
+		once.Do(func() { fatalErr = err })
+		cancel()
+	}
+
+	checks := health.NewRegistry(cfg.Health.Timeout, cfg.Health.CacheTTL)
+	checks.Register(health.Check{Name: cfg.Storage.Driver, Checker: health.CheckerFunc(a.store.Ping), Critical: true})
+
+	if job := a.partitionJob(); job != nil {
+		checks.Register(health.Check{Name: "partitions", Checker: job})
+		go job.Run(runCtx)
+	}
+
+	var consumers *kafka.Manager
+	if opts.consumer {
+		//подключение kafka: по consumer'у на каждый топик из конфигурации
+		consumers, err = kafka.NewManager(kafka.ManagerConfig{
+			Brokers:  cfg.Kafka.Brokers,
+			GroupID:  cfg.Kafka.GroupID,
+			Security: kafkaSecurity(cfg),
+			Topics:   kafkaTopics(cfg),
+		}, a.kafkaHandlers(), log)
+		if err != nil {
+			a.Close(ctx)
+			return err
+		}
+		consumers.Use(tracing.KafkaMiddleware, a.metrics.KafkaMiddleware)
+		// лаг не мешает отдавать заказы из хранилища: degraded, а не down,
+		// иначе под с отстающим consumer'ом уходит из балансировки
+		checks.Register(health.Check{Name: "kafka", Checker: consumers.HealthChecker(cfg.Kafka.MaxLag)})
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := consumers.Start(runCtx); err != nil {
+				fail(err)
+			}
+		}()
+	}
+
+	var srv *http.Server
+	if opts.http {
+		// health-checks: падение только кэша переводит сервис в degraded
+		warmer := service.NewCacheWarmer(a.service, cfg.Cache.WarmupLimit, log)
+		checks.Register(health.Check{Name: "redis", Checker: health.CheckerFunc(a.redis.Ping)})
+		checks.Register(health.Check{Name: "cache_warmup", Checker: warmer})
+		go func() {
+			// ошибка уже записана в состояние прогрева и видна в /readyz
+			_ = warmer.Run(runCtx)
+		}()
+
+		handler := prHttp.NewOrderHandler(a.orders, prHttp.HandlerConfig{
+			CacheControl: prHttp.CacheControl{
+				Order:   cfg.HTTP.CacheControl.Order,
+				History: cfg.HTTP.CacheControl.History,
+				Static:  cfg.HTTP.CacheControl.Static,
+			},
+			LegacyRoutes: cfg.HTTP.LegacyRoutes,
+			V1Sunset:     cfg.HTTP.V1SunsetTime(),
+			Auth:         authenticator,
+			PII:          masking,
+		}, log)
+		r := chi.NewRouter()
+		r.Use(logger.RequestIDMiddleware, tracing.HTTPMiddleware, a.metrics.HTTPMiddleware, logger.AccessLog(log))
+		if validate != nil {
+			r.Use(validate)
+		}
+		r.Handle("/metrics", a.metrics.Handler())
+		r.Get("/livez", checks.LiveHandler)
+		r.Get("/readyz", checks.ReadyHandler)
+		r.Get("/health", checks.ReadyHandler)
+		handler.RegisterRoutes(r)
+
+		srv = &http.Server{
+			Addr:         cfg.HTTPAddr(),
+			Handler:      r,
+			ReadTimeout:  cfg.HTTP.ReadTimeout,
+			WriteTimeout: cfg.HTTP.WriteTimeout,
+		}
+
+		go func() {
+			//запуск сервера
+			log.Info("server started", slog.String("addr", srv.Addr))
+			if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				fail(err)
+			}
+		}()
+	}
+
+	// admin API на отдельном порту: пауза и drain consumer'а не трогают чтение по HTTP
+	var adminSrv *http.Server
+	if consumers != nil && cfg.Admin.Token != "" {
+		r := chi.NewRouter()
+		r.Use(logger.RequestIDMiddleware, logger.AccessLog(log))
+		prHttp.NewAdminHandler(consumers, cfg.Admin.Token, log).RegisterRoutes(r)
+
+		adminSrv = &http.Server{
+			Addr:         cfg.AdminAddr(),
+			Handler:      r,
+			ReadTimeout:  cfg.HTTP.ReadTimeout,
+			WriteTimeout: 0, // drain может ждать дольше обычного запроса
+		}
+		go func() {
+			log.Info("admin server started", slog.String("addr", adminSrv.Addr))
+			if err := adminSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				fail(err)
+			}
+		}()
+	} else if consumers != nil {
+		log.Warn("admin API disabled: admin.token is not set")
+	}
+
+	//graceful shutdown
+	<-runCtx.Done()
+	log.Info("shutting down")
+	cancel()
+
+	ctxTimeOut, cancelTimeOut := context.WithTimeout(context.Background(), cfg.HTTP.ShutdownTimeout)
+	defer cancelTimeOut()
+
+	// shutdown http server
+	if srv != nil {
+		if err := srv.Shutdown(ctxTimeOut); err != nil {
+			log.Error("http server shutdown error", logger.Err(err))
+		}
+	}
+
+	if adminSrv != nil {
+		if err := adminSrv.Shutdown(ctxTimeOut); err != nil {
+			log.Error("admin server shutdown error", logger.Err(err))
+		}
+	}
+
+	// kafka: дождаться обработки текущих сообщений, затем закрыть reader'ы и DLQ
+	if consumers != nil {
+		wg.Wait()
+		if err := consumers.Close(); err != nil {
+			log.Error("error closing kafka consumer", logger.Err(err))
+		}
+	}
+
+	a.Close(ctxTimeOut)
+
+	if fatalErr != nil {
+		return fatalErr
+	}
+	log.Info("server stopped gracefully")
+	return nil
+}
@@ -17,6 +17,29 @@ type Order struct {
 	SmId              int       `json:"sm_id"`
 	DateCreated       time.Time `json:"date_created"`
 	OofShard          string    `json:"oof_shard"`
+	// Deletion - отметка мягкого удаления (отмены), nil у действующего заказа
+	Deletion *Deletion `json:"deletion,omitempty"`
+	// Version растет при каждом изменении сохраненного заказа, начиная с 1
+	Version int64 `json:"version,omitempty"`
+	// UpdatedAt - время последнего изменения, нулевое у неизменявшегося заказа
+	UpdatedAt time.Time `json:"updated_at,omitzero"`
+}
+
+type Deletion struct {
+	At     time.Time `json:"at"`
+	Reason string    `json:"reason"`
+	// Actor - кто удалил: пользователь API или источник сообщения Kafka
+	Actor string `json:"actor"`
+}
+
+// DeliveryChange - запись истории изменений доставки
+type DeliveryChange struct {
+	// Version - версия заказа после изменения
+	Version   int64     `json:"version"`
+	ChangedAt time.Time `json:"changed_at"`
+	Actor     string    `json:"actor"`
+	Before    Delivery  `json:"before"`
+	After     Delivery  `json:"after"`
 }
 
 type Delivery struct {
@@ -34,24 +57,24 @@ type Payment struct {
 	RequestId    string `json:"request_id"`
 	Currency     string `json:"currency"`
 	Provider     string `json:"provider"`
-	Amount       int    `json:"amount"`
-	PaymentDt    int    `json:"payment_dt"`
+	Amount       int64  `json:"amount"`
+	PaymentDt    int64  `json:"payment_dt"`
 	Bank         string `json:"bank"`
-	DeliveryCost int    `json:"delivery_cost"`
-	GoodsTotal   int    `json:"goods_total"`
-	CustomFee    int    `json:"custom_fee"`
+	DeliveryCost int64  `json:"delivery_cost"`
+	GoodsTotal   int64  `json:"goods_total"`
+	CustomFee    int64  `json:"custom_fee"`
 }
 
 type Item struct {
-	ChrtId      int    `json:"chrt_id"`
+	ChrtId      int64  `json:"chrt_id"`
 	TrackNumber string `json:"track_number"`
-	Price       int    `json:"price"`
+	Price       int64  `json:"price"`
 	Rid         string `json:"rid"`
 	Name        string `json:"name"`
 	Sale        int    `json:"sale"`
 	Size        string `json:"size"`
-	TotalPrice  int    `json:"total_price"`
-	NmId        int    `json:"nm_id"`
+	TotalPrice  int64  `json:"total_price"`
+	NmId        int64  `json:"nm_id"`
 	Brand       string `json:"brand"`
 	Status      int    `json:"status"`
 }
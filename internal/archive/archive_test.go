@@ -0,0 +1,199 @@
+package archive_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Sergi-Ch/WB_L0_2025/internal/archive"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/repository"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/repository/repotest"
+)
+
+var cutoff = time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+
+// newStore - 5 заказов до cutoff (по дню) и 2 после
+func newStore(t *testing.T, opts repository.MemoryOptions) *repository.MemoryRepository {
+	t.Helper()
+	store := repository.NewMemoryRepository(opts)
+	for i := range 5 {
+		saveOrder(t, store, fmt.Sprintf("old-%d", i), cutoff.AddDate(0, 0, -5+i))
+	}
+	for i := range 2 {
+		saveOrder(t, store, fmt.Sprintf("new-%d", i), cutoff.Add(time.Duration(i)*time.Hour))
+	}
+	return store
+}
+
+func saveOrder(t *testing.T, store repository.PostgresRepInterface, uid string, created time.Time) {
+	t.Helper()
+	if err := store.SaveOrders(context.Background(), repotest.Order(uid, created)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newArchiver(store repository.PostgresRepInterface, dir, compression string) *archive.Archiver {
+	return archive.NewArchiver(store, archive.Options{
+		Dir:         dir,
+		Compression: compression,
+		ChunkSize:   2,
+		BatchSize:   2,
+	}, slog.New(slog.DiscardHandler))
+}
+
+func TestArchiveAndRestore(t *testing.T) {
+	for _, compression := range []string{archive.CompressionGzip, archive.CompressionZstd} {
+		t.Run(compression, func(t *testing.T) {
+			ctx := context.Background()
+			store := newStore(t, repository.MemoryOptions{})
+			a := newArchiver(store, t.TempDir(), compression)
+
+			res, err := a.Archive(ctx, cutoff, true)
+			if err != nil {
+				t.Fatalf("Archive: %v", err)
+			}
+			m := res.Manifest
+			if m.Orders != 5 || len(m.Chunks) != 3 || res.Deleted != 5 {
+				t.Fatalf("archived %d orders in %d chunks, deleted %d; want 5 in 3, 5", m.Orders, len(m.Chunks), res.Deleted)
+			}
+			if !m.From.Equal(cutoff.AddDate(0, 0, -5)) || !m.To.Equal(cutoff.AddDate(0, 0, -1)) {
+				t.Errorf("manifest range = %v - %v", m.From, m.To)
+			}
+			if store.Len() != 2 {
+				t.Errorf("%d orders left in storage, want 2", store.Len())
+			}
+
+			if _, err := archive.Read(res.Dir, nil); err != nil {
+				t.Fatalf("Read: %v", err)
+			}
+
+			restored, err := a.Restore(ctx, res.Dir)
+			if err != nil {
+				t.Fatalf("Restore: %v", err)
+			}
+			if restored.Restored != 5 || store.Len() != 7 {
+				t.Errorf("restored %d orders, storage has %d; want 5 and 7", restored.Restored, store.Len())
+			}
+			got, err := store.GetByID(ctx, "old-0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := repotest.Order("old-0", cutoff.AddDate(0, 0, -5)); !got.DateCreated.Equal(want.DateCreated) ||
+				len(got.Items) != len(want.Items) || got.Payment != want.Payment {
+				t.Errorf("restored order = %+v, want %+v", got, want)
+			}
+
+			// повторное восстановление ничего не дублирует
+			again, err := a.Restore(ctx, res.Dir)
+			if err != nil {
+				t.Fatalf("Restore again: %v", err)
+			}
+			if again.Restored != 0 || again.Skipped != 5 {
+				t.Errorf("second restore = %+v, want 5 skipped", again)
+			}
+		})
+	}
+}
+
+func TestArchiveWithoutDelete(t *testing.T) {
+	store := newStore(t, repository.MemoryOptions{})
+	res, err := newArchiver(store, t.TempDir(), archive.CompressionZstd).Archive(context.Background(), cutoff, false)
+	if err != nil {
+		t.Fatalf("Archive: %v", err)
+	}
+	if res.Manifest.Orders != 5 || res.Deleted != 0 || store.Len() != 7 {
+		t.Errorf("archived %d, deleted %d, left %d; want 5, 0, 7", res.Manifest.Orders, res.Deleted, store.Len())
+	}
+}
+
+func TestArchiveNothing(t *testing.T) {
+	dir := t.TempDir()
+	store := newStore(t, repository.MemoryOptions{})
+	res, err := newArchiver(store, dir, archive.CompressionGzip).Archive(context.Background(), cutoff.AddDate(-1, 0, 0), true)
+	if err != nil {
+		t.Fatalf("Archive: %v", err)
+	}
+	if res.Dir != "" || res.Manifest.Orders != 0 {
+		t.Errorf("result = %+v, want empty", res)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("empty archive left %d entries in %s", len(entries), dir)
+	}
+}
+
+func TestArchiveDeleteFailure(t *testing.T) {
+	errDown := errors.New("connection refused")
+	store := newStore(t, repository.MemoryOptions{Err: func(op string) error {
+		if op == repository.OpDeleteOrders {
+			return errDown
+		}
+		return nil
+	}})
+
+	res, err := newArchiver(store, t.TempDir(), archive.CompressionZstd).Archive(context.Background(), cutoff, true)
+	if !errors.Is(err, errDown) {
+		t.Fatalf("Archive = %v, want %v", err, errDown)
+	}
+	// архив остается: удаление можно повторить, данные не потеряны
+	if _, err := archive.Read(res.Dir, nil); err != nil {
+		t.Errorf("archive after failed delete: %v", err)
+	}
+	if store.Len() != 7 {
+		t.Errorf("%d orders left, want 7", store.Len())
+	}
+}
+
+func TestReadDetectsCorruption(t *testing.T) {
+	tests := []struct {
+		name    string
+		corrupt func(t *testing.T, dir string, m *archive.Manifest)
+	}{
+		{"flipped byte", func(t *testing.T, dir string, m *archive.Manifest) {
+			path := filepath.Join(dir, m.Chunks[1].File)
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data[len(data)/2] ^= 0xff
+			if err := os.WriteFile(path, data, 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"trailing data", func(t *testing.T, dir string, m *archive.Manifest) {
+			f, err := os.OpenFile(filepath.Join(dir, m.Chunks[0].File), os.O_APPEND|os.O_WRONLY, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			f.WriteString("garbage")
+		}},
+		{"missing chunk", func(t *testing.T, dir string, m *archive.Manifest) {
+			if err := os.Remove(filepath.Join(dir, m.Chunks[2].File)); err != nil {
+				t.Fatal(err)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newStore(t, repository.MemoryOptions{})
+			res, err := newArchiver(store, t.TempDir(), archive.CompressionGzip).Archive(context.Background(), cutoff, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.corrupt(t, res.Dir, res.Manifest)
+
+			if _, err := archive.Read(res.Dir, nil); err == nil {
+				t.Fatal("Read of a corrupted archive succeeded")
+			}
+			// восстановление проверяет архив до первой записи
+			if _, err := newArchiver(store, "", archive.CompressionGzip).Restore(context.Background(), res.Dir); err == nil {
+				t.Fatal("Restore of a corrupted archive succeeded")
+			}
+		})
+	}
+}
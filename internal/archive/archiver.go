@@ -0,0 +1,165 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"time"
+
+	"github.com/Sergi-Ch/WB_L0_2025/domain"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/logger"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/repository"
+)
+
+type Options struct {
+	// Dir - каталог, в котором создается подкаталог каждого архива
+	Dir         string
+	Compression string
+	// ChunkSize - заказов в одном файле
+	ChunkSize int
+	// BatchSize - заказов в одном запросе чтения и удаления
+	BatchSize int
+}
+
+// Result - итог архивации; Dir пустой, если архивировать было нечего
+type Result struct {
+	Dir      string
+	Manifest *Manifest
+	Deleted  int
+}
+
+type RestoreResult struct {
+	Restored int
+	// Skipped - заказы, которые уже есть в хранилище
+	Skipped int
+}
+
+// Archiver переносит старые заказы из хранилища в архив и обратно
+type Archiver struct {
+	store repository.PostgresRepInterface
+	opts  Options
+	log   *slog.Logger
+	now   func() time.Time
+}
+
+func NewArchiver(store repository.PostgresRepInterface, opts Options, log *slog.Logger) *Archiver {
+	return &Archiver{
+		store: store,
+		opts:  opts,
+		log:   log.With(slog.String("component", "archive")),
+		now:   time.Now,
+	}
+}
+
+// Archive пишет заказы, созданные до before, в новый архив, проверяет
+// записанные файлы и, если del, удаляет из хранилища ровно те заказы,
+// которые прочитаны из архива при проверке
+func (a *Archiver) Archive(ctx context.Context, before time.Time, del bool) (*Result, error) {
+	name := fmt.Sprintf("orders_before_%s_at_%s",
+		before.UTC().Format("20060102T150405Z"), a.now().UTC().Format("20060102T150405Z"))
+	w, err := Create(filepath.Join(a.opts.Dir, name), a.opts.Compression, a.opts.ChunkSize, before)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.write(ctx, w, before); err != nil {
+		if abortErr := w.Abort(); abortErr != nil {
+			a.log.ErrorContext(ctx, "failed to remove incomplete archive",
+				slog.String("dir", w.Dir()), logger.Err(abortErr))
+		}
+		return nil, err
+	}
+	m, err := w.Close()
+	if err != nil {
+		w.Abort()
+		return nil, fmt.Errorf("failed to finish archive: %w", err)
+	}
+	if m.Orders == 0 {
+		a.log.InfoContext(ctx, "nothing to archive", slog.Time("before", before))
+		return &Result{Manifest: m}, w.Abort()
+	}
+
+	var uids []string
+	if _, err := Read(w.Dir(), func(o domain.Order) error {
+		uids = append(uids, o.OrderUid)
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("archive verification failed, nothing deleted: %w", err)
+	}
+	a.log.InfoContext(ctx, "archive written",
+		slog.String("dir", w.Dir()),
+		slog.Int("orders", m.Orders),
+		slog.Int("chunks", len(m.Chunks)),
+	)
+
+	res := &Result{Dir: w.Dir(), Manifest: m}
+	if !del {
+		return res, nil
+	}
+	for start := 0; start < len(uids); start += a.opts.BatchSize {
+		batch := uids[start:min(start+a.opts.BatchSize, len(uids))]
+		n, err := a.store.DeleteOrders(ctx, batch)
+		res.Deleted += n
+		if err != nil {
+			return res, fmt.Errorf("deleted %d of %d archived orders: %w", res.Deleted, len(uids), err)
+		}
+	}
+	a.log.InfoContext(ctx, "archived orders deleted", slog.Int("orders", res.Deleted))
+	return res, nil
+}
+
+func (a *Archiver) write(ctx context.Context, w *Writer, before time.Time) error {
+	// удаленные заказы архивируются вместе с отметкой и восстанавливаются удаленными
+	filter := repository.OrderFilter{CreatedTo: before, IncludeDeleted: true, Limit: a.opts.BatchSize}
+	for {
+		orders, err := a.store.ListOrders(ctx, filter)
+		if err != nil {
+			return err
+		}
+		for _, order := range orders {
+			if err := w.Write(order); err != nil {
+				return err
+			}
+		}
+		if len(orders) < filter.Limit {
+			return nil
+		}
+		filter.Offset += filter.Limit
+	}
+}
+
+// Restore проверяет архив целиком и сохраняет его заказы через SaveOrders.
+// Уже существующие заказы пропускаются, поэтому восстановление можно повторять.
+func (a *Archiver) Restore(ctx context.Context, dir string) (*RestoreResult, error) {
+	if _, err := Read(dir, nil); err != nil {
+		return nil, err
+	}
+
+	res := &RestoreResult{}
+	_, err := Read(dir, func(o domain.Order) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := a.store.SaveOrders(ctx, &o)
+		switch {
+		case errors.Is(err, repository.ErrAlreadyExists):
+			res.Skipped++
+		case err != nil:
+			return fmt.Errorf("failed to restore order %s: %w", o.OrderUid, err)
+		default:
+			res.Restored++
+		}
+		return nil
+	})
+	if err != nil {
+		return res, err
+	}
+	a.log.InfoContext(ctx, "archive restored",
+		slog.String("dir", dir),
+		slog.Int("restored", res.Restored),
+		slog.Int("skipped", res.Skipped),
+	)
+	return res, nil
+}
@@ -0,0 +1,378 @@
+// Package archive - архивы заказов: сжатые NDJSON-файлы (чанки) и manifest.json
+// с количеством заказов, диапазоном дат и контрольными суммами каждого чанка.
+package archive
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Sergi-Ch/WB_L0_2025/domain"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+
+	// ManifestFile пишется последним: архив без него не дописан
+	ManifestFile = "manifest.json"
+
+	manifestVersion = 1
+	// maxLine - максимальный размер одного заказа в чанке
+	maxLine = 10 << 20
+)
+
+// ErrCorrupted - файлы архива не совпадают с manifest.json
+var ErrCorrupted = errors.New("archive is corrupted")
+
+type Manifest struct {
+	Version     int       `json:"version"`
+	CreatedAt   time.Time `json:"created_at"`
+	Before      time.Time `json:"before"`
+	Compression string    `json:"compression"`
+	Orders      int       `json:"orders"`
+	// From и To - самый старый и самый новый date_created в архиве
+	From   time.Time `json:"from"`
+	To     time.Time `json:"to"`
+	Chunks []Chunk   `json:"chunks"`
+}
+
+type Chunk struct {
+	File   string    `json:"file"`
+	Orders int       `json:"orders"`
+	Bytes  int64     `json:"bytes"`
+	SHA256 string    `json:"sha256"` // сжатого файла
+	From   time.Time `json:"from"`
+	To     time.Time `json:"to"`
+}
+
+// extend расширяет диапазон [from, to] датой t
+func extend(from, to *time.Time, t time.Time) {
+	if from.IsZero() || t.Before(*from) {
+		*from = t
+	}
+	if to.IsZero() || t.After(*to) {
+		*to = t
+	}
+}
+
+func extension(compression string) (string, error) {
+	switch compression {
+	case CompressionGzip:
+		return ".ndjson.gz", nil
+	case CompressionZstd:
+		return ".ndjson.zst", nil
+	}
+	return "", fmt.Errorf("unknown compression %q (expected gzip or zstd)", compression)
+}
+
+// Writer пишет заказы в каталог архива, начиная новый чанк каждые chunkSize заказов
+type Writer struct {
+	dir       string
+	chunkSize int
+	manifest  Manifest
+	ext       string
+	chunk     *chunkWriter
+}
+
+// Create создает каталог dir (его не должно быть) для нового архива
+// заказов, созданных до before
+func Create(dir, compression string, chunkSize int, before time.Time) (*Writer, error) {
+	ext, err := extension(compression)
+	if err != nil {
+		return nil, err
+	}
+	if chunkSize < 1 {
+		return nil, fmt.Errorf("chunk size must be positive, got %d", chunkSize)
+	}
+	if err := os.MkdirAll(filepath.Dir(dir), 0o755); err != nil {
+		return nil, err
+	}
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create archive directory: %w", err)
+	}
+	return &Writer{
+		dir:       dir,
+		chunkSize: chunkSize,
+		ext:       ext,
+		manifest: Manifest{
+			Version:     manifestVersion,
+			CreatedAt:   time.Now().UTC(),
+			Before:      before.UTC(),
+			Compression: compression,
+			Chunks:      []Chunk{},
+		},
+	}, nil
+}
+
+// Dir - каталог архива
+func (w *Writer) Dir() string {
+	return w.dir
+}
+
+func (w *Writer) Write(order domain.Order) error {
+	if w.chunk == nil {
+		name := fmt.Sprintf("orders-%04d%s", len(w.manifest.Chunks)+1, w.ext)
+		c, err := newChunkWriter(filepath.Join(w.dir, name), w.manifest.Compression)
+		if err != nil {
+			return err
+		}
+		w.chunk = c
+	}
+	if err := w.chunk.write(order); err != nil {
+		return err
+	}
+	w.manifest.Orders++
+	extend(&w.manifest.From, &w.manifest.To, order.DateCreated)
+
+	if w.chunk.meta.Orders >= w.chunkSize {
+		return w.closeChunk()
+	}
+	return nil
+}
+
+// Close дописывает последний чанк и manifest.json
+func (w *Writer) Close() (*Manifest, error) {
+	if err := w.closeChunk(); err != nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(w.manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	// через временный файл: manifest.json либо целый, либо его нет
+	tmp := filepath.Join(w.dir, ManifestFile+".tmp")
+	if err := writeFileSync(tmp, data); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp, filepath.Join(w.dir, ManifestFile)); err != nil {
+		return nil, err
+	}
+	return &w.manifest, nil
+}
+
+// Abort удаляет недописанный архив
+func (w *Writer) Abort() error {
+	if w.chunk != nil {
+		w.chunk.file.Close()
+		w.chunk = nil
+	}
+	return os.RemoveAll(w.dir)
+}
+
+func (w *Writer) closeChunk() error {
+	if w.chunk == nil {
+		return nil
+	}
+	meta, err := w.chunk.close()
+	w.chunk = nil
+	if err != nil {
+		return err
+	}
+	w.manifest.Chunks = append(w.manifest.Chunks, meta)
+	return nil
+}
+
+// chunkWriter: заказ -> JSON -> сжатие -> (файл + sha256)
+type chunkWriter struct {
+	file *os.File
+	hash hash.Hash
+	size *countingWriter
+	comp io.WriteCloser
+	buf  *bufio.Writer
+	enc  *json.Encoder
+	meta Chunk
+}
+
+func newChunkWriter(path, compression string) (*chunkWriter, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	c := &chunkWriter{file: f, hash: sha256.New(), meta: Chunk{File: filepath.Base(path)}}
+	c.size = &countingWriter{w: io.MultiWriter(f, c.hash)}
+
+	switch compression {
+	case CompressionGzip:
+		c.comp = gzip.NewWriter(c.size)
+	case CompressionZstd:
+		if c.comp, err = zstd.NewWriter(c.size); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	c.buf = bufio.NewWriter(c.comp)
+	c.enc = json.NewEncoder(c.buf)
+	return c, nil
+}
+
+func (c *chunkWriter) write(order domain.Order) error {
+	if err := c.enc.Encode(order); err != nil {
+		return fmt.Errorf("failed to write order %s: %w", order.OrderUid, err)
+	}
+	c.meta.Orders++
+	extend(&c.meta.From, &c.meta.To, order.DateCreated)
+	return nil
+}
+
+func (c *chunkWriter) close() (Chunk, error) {
+	defer c.file.Close()
+	if err := c.buf.Flush(); err != nil {
+		return Chunk{}, err
+	}
+	if err := c.comp.Close(); err != nil {
+		return Chunk{}, err
+	}
+	if err := c.file.Sync(); err != nil {
+		return Chunk{}, err
+	}
+	if err := c.file.Close(); err != nil {
+		return Chunk{}, err
+	}
+	c.meta.Bytes = c.size.n
+	c.meta.SHA256 = hex.EncodeToString(c.hash.Sum(nil))
+	return c.meta, nil
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func writeFileSync(path string, data []byte) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// ReadManifest читает manifest.json архива в каталоге dir
+func ReadManifest(dir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("%w: invalid %s: %v", ErrCorrupted, ManifestFile, err)
+	}
+	if m.Version != manifestVersion {
+		return nil, fmt.Errorf("unsupported archive version %d", m.Version)
+	}
+	if _, err := extension(m.Compression); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorrupted, err)
+	}
+	return &m, nil
+}
+
+// Read читает все чанки архива и передает заказы fn по порядку. Размер,
+// sha256 и число заказов каждого чанка сверяются с manifest.json;
+// расхождение - ErrCorrupted. Чанк проверяется после чтения, поэтому fn
+// с побочными эффектами стоит вызывать после отдельной проверки (fn = nil).
+func Read(dir string, fn func(domain.Order) error) (*Manifest, error) {
+	m, err := ReadManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	total := 0
+	for _, chunk := range m.Chunks {
+		n, err := readChunk(filepath.Join(dir, filepath.Base(chunk.File)), m.Compression, chunk, fn)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", chunk.File, err)
+		}
+		total += n
+	}
+	if total != m.Orders {
+		return nil, fmt.Errorf("%w: %d orders in chunks, manifest says %d", ErrCorrupted, total, m.Orders)
+	}
+	return m, nil
+}
+
+func readChunk(path, compression string, meta Chunk, fn func(domain.Order) error) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size := &countingWriter{w: h}
+	r := io.TeeReader(f, size)
+
+	var dec io.Reader
+	switch compression {
+	case CompressionGzip:
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %v", ErrCorrupted, err)
+		}
+		defer gz.Close()
+		dec = gz
+	case CompressionZstd:
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return 0, err
+		}
+		defer zr.Close()
+		dec = zr
+	}
+
+	scanner := bufio.NewScanner(dec)
+	scanner.Buffer(make([]byte, 64*1024), maxLine)
+	n := 0
+	for scanner.Scan() {
+		var order domain.Order
+		if err := json.Unmarshal(scanner.Bytes(), &order); err != nil {
+			return n, fmt.Errorf("%w: line %d: %v", ErrCorrupted, n+1, err)
+		}
+		n++
+		if fn != nil {
+			if err := fn(order); err != nil {
+				return n, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return n, fmt.Errorf("%w: %v", ErrCorrupted, err)
+	}
+	// остаток файла после конца сжатого потока тоже входит в контрольную сумму
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		return n, err
+	}
+
+	switch {
+	case n != meta.Orders:
+		return n, fmt.Errorf("%w: %d orders, manifest says %d", ErrCorrupted, n, meta.Orders)
+	case size.n != meta.Bytes:
+		return n, fmt.Errorf("%w: %d bytes, manifest says %d", ErrCorrupted, size.n, meta.Bytes)
+	case hex.EncodeToString(h.Sum(nil)) != meta.SHA256:
+		return n, fmt.Errorf("%w: sha256 mismatch", ErrCorrupted)
+	}
+	return n, nil
+}
@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// APIKey - статический ключ из конфигурации. Хранится только хэш, сам ключ
+// выдается клиенту один раз (order-service apikey).
+type APIKey struct {
+	Name  string
+	Hash  string // sha256:<hex>
+	Roles []Role
+}
+
+const hashPrefix = "sha256:"
+
+// HashAPIKey - значение для api_keys[].hash. Ключи случайные (256 бит),
+// поэтому медленный хэш вроде bcrypt не нужен.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hashPrefix + hex.EncodeToString(sum[:])
+}
+
+// NewAPIKey генерирует ключ и его хэш
+func NewAPIKey() (key, hash string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+	key = base64.RawURLEncoding.EncodeToString(buf)
+	return key, HashAPIKey(key), nil
+}
+
+func parseHash(hash string) ([]byte, error) {
+	hexSum, ok := strings.CutPrefix(hash, hashPrefix)
+	if !ok {
+		return nil, fmt.Errorf("hash must start with %q", hashPrefix)
+	}
+	sum, err := hex.DecodeString(hexSum)
+	if err != nil || len(sum) != sha256.Size {
+		return nil, fmt.Errorf("hash must be %s followed by 64 hex digits", hashPrefix)
+	}
+	return sum, nil
+}
+
+// APIKey проверяет ключ из заголовка X-API-Key
+func (a *Authenticator) APIKey(key string) (*Principal, error) {
+	sum := sha256.Sum256([]byte(key))
+	var found *APIKey
+	// сравниваются все ключи, чтобы время ответа не зависело от позиции ключа
+	for i := range a.keys {
+		want, _ := parseHash(a.keys[i].Hash)
+		if subtle.ConstantTimeCompare(sum[:], want) == 1 {
+			found = &a.keys[i]
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("%w: unknown api key", ErrInvalidCredentials)
+	}
+	return &Principal{Subject: found.Name, Roles: found.Roles, Method: MethodAPIKey}, nil
+}
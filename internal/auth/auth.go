@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Role - роль клиента API. Роли вложены: writer может все, что reader,
+// admin - все, что writer.
+type Role string
+
+const (
+	RoleReader Role = "reader"
+	RoleWriter Role = "writer"
+	RoleAdmin  Role = "admin"
+	// RolePII - право видеть персональные данные без маскирования. Вне
+	// иерархии: выдается отдельно, admin без pii тоже видит маску.
+	RolePII Role = "pii"
+)
+
+var roleRank = map[Role]int{RoleReader: 1, RoleWriter: 2, RoleAdmin: 3}
+
+// ParseRole проверяет имя роли из конфигурации
+func ParseRole(s string) (Role, error) {
+	if _, ok := roleRank[Role(s)]; !ok && Role(s) != RolePII {
+		return "", fmt.Errorf("unknown role %q (expected %s, %s, %s or %s)", s, RoleReader, RoleWriter, RoleAdmin, RolePII)
+	}
+	return Role(s), nil
+}
+
+// Способы аутентификации в Principal.Method
+const (
+	MethodAPIKey = "api_key"
+	MethodJWT    = "jwt"
+)
+
+// Principal - аутентифицированный клиент
+type Principal struct {
+	// Subject - имя ключа или sub токена
+	Subject string
+	Roles   []Role
+	Method  string
+}
+
+// Has - есть ли у клиента роль role или старшая
+func (p *Principal) Has(role Role) bool {
+	if p == nil {
+		return false
+	}
+	if role == RolePII {
+		return slices.Contains(p.Roles, RolePII)
+	}
+	return slices.ContainsFunc(p.Roles, func(r Role) bool { return roleRank[r] >= roleRank[role] })
+}
+
+// ErrInvalidCredentials - ключ не найден, токен не прошел проверку или истек
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// Authenticator проверяет API-ключи и JWT
+type Authenticator struct {
+	keys []APIKey
+	jwks *JWKS
+	jwt  JWTConfig
+}
+
+// NewAuthenticator - jwtCfg.JWKSFile пустой отключает JWT, пустой keys - API-ключи
+func NewAuthenticator(keys []APIKey, jwtCfg JWTConfig) (*Authenticator, error) {
+	for _, k := range keys {
+		if _, err := parseHash(k.Hash); err != nil {
+			return nil, fmt.Errorf("api key %s: %w", k.Name, err)
+		}
+	}
+	a := &Authenticator{keys: keys, jwt: jwtCfg}
+	if jwtCfg.JWKSFile != "" {
+		jwks, err := LoadJWKS(jwtCfg.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		a.jwks = jwks
+	}
+	if a.jwt.RolesClaim == "" {
+		a.jwt.RolesClaim = DefaultRolesClaim
+	}
+	return a, nil
+}
+
+type principalKey struct{}
+
+// WithPrincipal сохраняет клиента в контексте запроса
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFrom - клиент запроса, nil - запрос без учетных данных
+func PrincipalFrom(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
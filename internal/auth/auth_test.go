@@ -0,0 +1,288 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// testIssuer - издатель токенов с RSA и EC ключами в JWKS-файле
+type testIssuer struct {
+	t    *testing.T
+	path string
+	rsa  *rsa.PrivateKey
+	ec   *ecdsa.PrivateKey
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	iss := &testIssuer{t: t, path: filepath.Join(t.TempDir(), "jwks.json"), rsa: rsaKey, ec: ecKey}
+	iss.writeJWKS(map[string]any{"kid": "rsa-1", "kty": "RSA", "alg": "RS256", "use": "sig",
+		"n": b64(rsaKey.N.Bytes()), "e": b64([]byte{1, 0, 1})})
+	return iss
+}
+
+func (iss *testIssuer) writeJWKS(keys ...map[string]any) {
+	iss.t.Helper()
+	ecPub := iss.ec.PublicKey
+	keys = append(keys, map[string]any{"kid": "ec-1", "kty": "EC", "crv": "P-256",
+		"x": b64(ecPub.X.FillBytes(make([]byte, 32))), "y": b64(ecPub.Y.FillBytes(make([]byte, 32)))})
+	data, err := json.Marshal(map[string]any{"keys": keys})
+	if err != nil {
+		iss.t.Fatal(err)
+	}
+	if err := os.WriteFile(iss.path, data, 0o600); err != nil {
+		iss.t.Fatal(err)
+	}
+}
+
+func (iss *testIssuer) sign(method jwt.SigningMethod, kid string, claims jwt.MapClaims) string {
+	iss.t.Helper()
+	var key crypto.Signer = iss.rsa
+	if strings.HasPrefix(method.Alg(), "ES") {
+		key = iss.ec
+	}
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	s, err := token.SignedString(key)
+	if err != nil {
+		iss.t.Fatal(err)
+	}
+	return s
+}
+
+func b64(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+func claims(exp time.Duration, roles ...string) jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"sub":   "user-1",
+		"iss":   "https://issuer.test",
+		"aud":   "order-service",
+		"iat":   now.Unix(),
+		"exp":   now.Add(exp).Unix(),
+		"roles": roles,
+	}
+}
+
+func TestToken(t *testing.T) {
+	iss := newTestIssuer(t)
+	a, err := NewAuthenticator(nil, JWTConfig{JWKSFile: iss.path, Issuer: "https://issuer.test", Audience: "order-service"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	noExp := claims(time.Hour, "reader")
+	delete(noExp, "exp")
+	notYet := claims(time.Hour, "reader")
+	notYet["nbf"] = time.Now().Add(time.Minute).Unix()
+	wrongIss := claims(time.Hour, "reader")
+	wrongIss["iss"] = "https://other.test"
+	wrongAud := claims(time.Hour, "reader")
+	wrongAud["aud"] = "other-service"
+	noSub := claims(time.Hour, "reader")
+	delete(noSub, "sub")
+
+	valid := iss.sign(jwt.SigningMethodRS256, "rsa-1", claims(time.Hour, "reader"))
+	tampered := valid[:strings.LastIndex(valid, ".")+1] + b64([]byte("not a signature"))
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr string
+	}{
+		{"rsa", valid, ""},
+		{"ec", iss.sign(jwt.SigningMethodES256, "ec-1", claims(time.Hour, "writer")), ""},
+		{"expired", iss.sign(jwt.SigningMethodRS256, "rsa-1", claims(-time.Minute, "reader")), "token is expired"},
+		{"no exp", iss.sign(jwt.SigningMethodRS256, "rsa-1", noExp), "exp claim is required"},
+		{"not valid yet", iss.sign(jwt.SigningMethodRS256, "rsa-1", notYet), "token is not valid yet"},
+		{"wrong issuer", iss.sign(jwt.SigningMethodRS256, "rsa-1", wrongIss), "invalid issuer"},
+		{"wrong audience", iss.sign(jwt.SigningMethodRS256, "rsa-1", wrongAud), "invalid audience"},
+		{"no sub", iss.sign(jwt.SigningMethodRS256, "rsa-1", noSub), "no sub claim"},
+		{"unknown kid", iss.sign(jwt.SigningMethodRS256, "rsa-2", claims(time.Hour, "reader")), "unknown key id"},
+		{"kid of another alg", iss.sign(jwt.SigningMethodRS384, "rsa-1", claims(time.Hour, "reader")), "key \"rsa-1\" is for RS256"},
+		{"bad signature", tampered, "signature is invalid"},
+		{"hs256", hs256(t, claims(time.Hour, "admin")), "signing method HS256 is invalid"},
+		{"none", unsigned(t, claims(time.Hour, "admin")), "signing method none is invalid"},
+		{"garbage", "not-a-token", "token is malformed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := a.Token(tt.token)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Token() error = %v", err)
+				}
+				if p.Subject != "user-1" || p.Method != MethodJWT || len(p.Roles) != 1 {
+					t.Errorf("principal = %+v", p)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidCredentials) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Token() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTokenLeeway(t *testing.T) {
+	iss := newTestIssuer(t)
+	a, err := NewAuthenticator(nil, JWTConfig{JWKSFile: iss.path, Leeway: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Token(iss.sign(jwt.SigningMethodRS256, "rsa-1", claims(-30*time.Second))); err != nil {
+		t.Errorf("token expired within leeway: %v", err)
+	}
+	if _, err := a.Token(iss.sign(jwt.SigningMethodRS256, "rsa-1", claims(-2*time.Minute))); err == nil {
+		t.Error("token expired beyond leeway accepted")
+	}
+}
+
+func TestJWKSRotation(t *testing.T) {
+	iss := newTestIssuer(t)
+	a, err := NewAuthenticator(nil, JWTConfig{JWKSFile: iss.path})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims(time.Hour, "reader"))
+	token.Header["kid"] = "rsa-2"
+	signed, err := token.SignedString(rotated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Token(signed); err == nil {
+		t.Fatal("token with a key missing from jwks accepted")
+	}
+
+	iss.writeJWKS(map[string]any{"kid": "rsa-2", "kty": "RSA", "n": b64(rotated.N.Bytes()), "e": b64([]byte{1, 0, 1})})
+	// mtime может совпасть в пределах разрешения файловой системы
+	later := time.Now().Add(time.Second)
+	if err := os.Chtimes(iss.path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Token(signed); err != nil {
+		t.Errorf("token with a rotated key: %v", err)
+	}
+}
+
+func TestTokenRoles(t *testing.T) {
+	tests := []struct {
+		claim  string
+		claims jwt.MapClaims
+		want   []Role
+	}{
+		{"roles", jwt.MapClaims{"roles": []any{"writer", "unknown"}}, []Role{RoleWriter}},
+		{"scope", jwt.MapClaims{"scope": "openid reader admin"}, []Role{RoleReader, RoleAdmin}},
+		{"realm_access.roles", jwt.MapClaims{"realm_access": map[string]any{"roles": []any{"admin"}}}, []Role{RoleAdmin}},
+		{"roles", jwt.MapClaims{"roles": 42}, nil},
+		{"realm_access.roles", jwt.MapClaims{"realm_access": "admin"}, nil},
+	}
+	for _, tt := range tests {
+		got := tokenRoles(tt.claims, tt.claim)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("tokenRoles(%v, %s) = %v, want %v", tt.claims, tt.claim, got, tt.want)
+		}
+	}
+}
+
+func TestAPIKey(t *testing.T) {
+	key, hash, err := NewAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := NewAuthenticator([]APIKey{
+		{Name: "reporting", Hash: HashAPIKey("other-key"), Roles: []Role{RoleReader}},
+		{Name: "importer", Hash: hash, Roles: []Role{RoleWriter}},
+	}, JWTConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := a.APIKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Subject != "importer" || p.Method != MethodAPIKey || !p.Has(RoleReader) || p.Has(RoleAdmin) {
+		t.Errorf("principal = %+v", p)
+	}
+	if _, err := a.APIKey(key + "x"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("APIKey(wrong) error = %v", err)
+	}
+	if _, err := a.Token("any"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Token() without jwks error = %v", err)
+	}
+
+	if _, err := NewAuthenticator([]APIKey{{Name: "plain", Hash: key}}, JWTConfig{}); err == nil {
+		t.Error("NewAuthenticator accepted a key without sha256: hash")
+	}
+}
+
+func TestHas(t *testing.T) {
+	admin := &Principal{Roles: []Role{RoleAdmin}}
+	reader := &Principal{Roles: []Role{RoleReader}}
+	var anonymous *Principal
+
+	if !admin.Has(RoleWriter) || !admin.Has(RoleReader) {
+		t.Error("admin must include writer and reader")
+	}
+	if reader.Has(RoleWriter) || (&Principal{}).Has(RoleReader) || anonymous.Has(RoleReader) {
+		t.Error("role granted without a sufficient role")
+	}
+	// pii вне иерархии ролей
+	if admin.Has(RolePII) || !(&Principal{Roles: []Role{RoleReader, RolePII}}).Has(RolePII) {
+		t.Error("pii must be granted only explicitly")
+	}
+	if (&Principal{Roles: []Role{RolePII}}).Has(RoleReader) {
+		t.Error("pii must not grant reader")
+	}
+}
+
+func hs256(t *testing.T, c jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, c)
+	token.Header["kid"] = "rsa-1"
+	s, err := token.SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func unsigned(t *testing.T, c jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodNone, c)
+	s, err := token.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
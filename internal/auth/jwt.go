@@ -0,0 +1,297 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// DefaultRolesClaim - claim с ролями, если jwt.roles_claim не задан
+const DefaultRolesClaim = "roles"
+
+// JWTConfig - проверка bearer-токенов
+type JWTConfig struct {
+	// JWKSFile - локальный файл JWKS с открытыми ключами издателя
+	JWKSFile string
+	// Issuer и Audience - ожидаемые iss и aud, пустые не проверяются
+	Issuer   string
+	Audience string
+	// RolesClaim - claim с ролями: массив строк или строка через пробел;
+	// вложенный claim задается через точку (realm_access.roles)
+	RolesClaim string
+	// Leeway - допустимое расхождение часов для exp и nbf
+	Leeway time.Duration
+}
+
+// подписи с открытым ключом; HS* и none не принимаются
+var validMethods = []string{
+	"RS256", "RS384", "RS512", "PS256", "PS384", "PS512",
+	"ES256", "ES384", "ES512", "EdDSA",
+}
+
+// Token проверяет JWT из Authorization: Bearer. exp обязателен.
+func (a *Authenticator) Token(token string) (*Principal, error) {
+	if a.jwks == nil {
+		return nil, fmt.Errorf("%w: bearer tokens are not accepted", ErrInvalidCredentials)
+	}
+
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(validMethods),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(a.jwt.Leeway),
+	}
+	if a.jwt.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(a.jwt.Issuer))
+	}
+	if a.jwt.Audience != "" {
+		opts = append(opts, jwt.WithAudience(a.jwt.Audience))
+	}
+
+	claims := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(token, claims, a.keyFunc, opts...); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCredentials, err)
+	}
+
+	sub, _ := claims.GetSubject()
+	if sub == "" {
+		return nil, fmt.Errorf("%w: token has no sub claim", ErrInvalidCredentials)
+	}
+	return &Principal{Subject: sub, Roles: tokenRoles(claims, a.jwt.RolesClaim), Method: MethodJWT}, nil
+}
+
+func (a *Authenticator) keyFunc(t *jwt.Token) (any, error) {
+	kid, _ := t.Header["kid"].(string)
+	k, err := a.jwks.key(kid)
+	if err != nil {
+		return nil, err
+	}
+	if k.alg != "" && k.alg != t.Method.Alg() {
+		return nil, fmt.Errorf("key %q is for %s, token is signed with %s", kid, k.alg, t.Method.Alg())
+	}
+	return k.public, nil
+}
+
+// tokenRoles - известные роли из claim; чужие роли издателя пропускаются
+func tokenRoles(claims jwt.MapClaims, claim string) []Role {
+	var v any = map[string]any(claims)
+	for _, part := range strings.Split(claim, ".") {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = m[part]
+	}
+
+	var names []string
+	switch v := v.(type) {
+	case string:
+		names = strings.Fields(v)
+	case []any:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				names = append(names, s)
+			}
+		}
+	}
+	var roles []Role
+	for _, name := range names {
+		if role, err := ParseRole(name); err == nil {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+// JWKS - открытые ключи из файла. При неизвестном kid файл перечитывается,
+// если изменился, - так ротация ключей не требует перезапуска.
+type JWKS struct {
+	path string
+
+	mu      sync.Mutex
+	keys    map[string]jwk
+	modTime time.Time
+}
+
+type jwk struct {
+	alg    string
+	public crypto.PublicKey
+}
+
+// LoadJWKS читает файл JWKS (RFC 7517): RSA, EC P-256/384/521 и Ed25519
+func LoadJWKS(path string) (*JWKS, error) {
+	s := &JWKS{path: path}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *JWKS) load() error {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return fmt.Errorf("failed to read jwks: %w", err)
+	}
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("failed to read jwks: %w", err)
+	}
+
+	var set struct {
+		Keys []rawJWK `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return fmt.Errorf("failed to parse jwks %s: %w", s.path, err)
+	}
+	keys := make(map[string]jwk, len(set.Keys))
+	for i, raw := range set.Keys {
+		// ключи шифрования не подходят для проверки подписи
+		if raw.Use == "enc" {
+			continue
+		}
+		public, err := raw.publicKey()
+		if err != nil {
+			return fmt.Errorf("jwks %s: key %d (kid %q): %w", s.path, i, raw.Kid, err)
+		}
+		keys[raw.Kid] = jwk{alg: raw.Alg, public: public}
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("jwks %s has no signing keys", s.path)
+	}
+
+	s.keys, s.modTime = keys, info.ModTime()
+	return nil
+}
+
+// key ищет ключ по kid. Токен без kid принимается, только если ключ один.
+func (s *JWKS) key(kid string) (jwk, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if k, ok := s.lookup(kid); ok {
+		return k, nil
+	}
+	if info, err := os.Stat(s.path); err == nil && !info.ModTime().Equal(s.modTime) {
+		// при ошибке чтения остаются прежние ключи
+		if err := s.load(); err != nil {
+			return jwk{}, err
+		}
+		if k, ok := s.lookup(kid); ok {
+			return k, nil
+		}
+	}
+	return jwk{}, fmt.Errorf("unknown key id %q", kid)
+}
+
+func (s *JWKS) lookup(kid string) (jwk, bool) {
+	if kid == "" && len(s.keys) == 1 {
+		for _, k := range s.keys {
+			return k, true
+		}
+	}
+	k, ok := s.keys[kid]
+	return k, ok
+}
+
+type rawJWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC и OKP
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k rawJWK) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("n: %w", err)
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("e: %w", err)
+		}
+		if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+			return nil, errors.New("e is out of range")
+		}
+		if n.BitLen() < 2048 {
+			return nil, fmt.Errorf("rsa key is %d bits, at least 2048 required", n.BitLen())
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		curves := map[string]struct {
+			curve elliptic.Curve
+			check ecdh.Curve
+		}{
+			"P-256": {elliptic.P256(), ecdh.P256()},
+			"P-384": {elliptic.P384(), ecdh.P384()},
+			"P-521": {elliptic.P521(), ecdh.P521()},
+		}
+		c, ok := curves[k.Crv]
+		if !ok {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("x: %w", err)
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("y: %w", err)
+		}
+		// ecdsa.PublicKey не проверяет, что точка лежит на кривой, - это
+		// делает ecdh по несжатому представлению
+		size := (c.curve.Params().BitSize + 7) / 8
+		if x.BitLen() > size*8 || y.BitLen() > size*8 {
+			return nil, errors.New("coordinates do not match the curve")
+		}
+		point := append([]byte{4}, x.FillBytes(make([]byte, size))...)
+		point = append(point, y.FillBytes(make([]byte, size))...)
+		if _, err := c.check.NewPublicKey(point); err != nil {
+			return nil, errors.New("point is not on the curve")
+		}
+		return &ecdsa.PublicKey{Curve: c.curve, X: x, Y: y}, nil
+
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("x must be a base64url Ed25519 public key")
+		}
+		return ed25519.PublicKey(x), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, errors.New("must be a non-empty base64url value")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,589 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Config - вся конфигурация сервиса. Источники применяются по порядку:
+// значения по умолчанию, YAML-файл, переменные окружения, флаги.
+type Config struct {
+	HTTP       HTTPConfig       `yaml:"http"`
+	Admin      AdminConfig      `yaml:"admin"`
+	Auth       AuthConfig       `yaml:"auth"`
+	PII        PIIConfig        `yaml:"pii"`
+	Storage    StorageConfig    `yaml:"storage"`
+	Postgres   PostgresConfig   `yaml:"postgres"`
+	SQLite     SQLiteConfig     `yaml:"sqlite"`
+	Partitions PartitionsConfig `yaml:"partitions"`
+	Archive    ArchiveConfig    `yaml:"archive"`
+	Redis      RedisConfig      `yaml:"redis"`
+	Kafka      KafkaConfig      `yaml:"kafka"`
+	Cache      CacheConfig      `yaml:"cache"`
+	Health     HealthConfig     `yaml:"health"`
+	Log        LogConfig        `yaml:"log"`
+	Tracing    TracingConfig    `yaml:"tracing"`
+}
+
+type HTTPConfig struct {
+	Port            int           `yaml:"port"`
+	ReadTimeout     time.Duration `yaml:"read_timeout"`
+	WriteTimeout    time.Duration `yaml:"write_timeout"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	// CacheControl - заголовок Cache-Control по маршрутам, пустой не отправляется
+	CacheControl CacheControlConfig `yaml:"cache_control"`
+	// ValidateRequests - проверять запросы по спецификации OpenAPI
+	ValidateRequests bool `yaml:"validate_requests"`
+	// LegacyRoutes - дублировать /api/v1 маршрутами без префикса (/order/...)
+	LegacyRoutes bool `yaml:"legacy_routes"`
+	// V1Sunset - дата отключения /api/v1 (YYYY-MM-DD) для заголовка Sunset
+	V1Sunset string `yaml:"v1_sunset"`
+}
+
+// V1SunsetTime - http.v1_sunset как время (нулевое, если не задано)
+func (c HTTPConfig) V1SunsetTime() time.Time {
+	t, _ := time.Parse(time.DateOnly, c.V1Sunset)
+	return t
+}
+
+type CacheControlConfig struct {
+	Order   string `yaml:"order"`   // GET /order/{order_uid}
+	History string `yaml:"history"` // GET /order/{order_uid}/delivery/history
+	Static  string `yaml:"static"`  // веб-интерфейс
+}
+
+// AdminConfig - отдельный порт для управления consumer'ом.
+// Без токена admin API не запускается.
+type AdminConfig struct {
+	Port  int    `yaml:"port"`
+	Token string `yaml:"token"`
+}
+
+// AuthConfig - аутентификация API заказов. Выключенная оставляет API
+// открытым всем, кто достучится до порта.
+type AuthConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// APIKeys - статические ключи (только из YAML), хранятся их sha256
+	APIKeys []APIKeyConfig `yaml:"api_keys"`
+	JWT     JWTConfig      `yaml:"jwt"`
+}
+
+type APIKeyConfig struct {
+	Name string `yaml:"name"`
+	// Hash - sha256:<hex>, выдает order-service apikey
+	Hash  string   `yaml:"hash"`
+	Roles []string `yaml:"roles"`
+}
+
+// JWTConfig - bearer-токены, подписанные ключами из локального JWKS
+type JWTConfig struct {
+	JWKSFile   string        `yaml:"jwks_file"`
+	Issuer     string        `yaml:"issuer"`
+	Audience   string        `yaml:"audience"`
+	RolesClaim string        `yaml:"roles_claim"`
+	Leeway     time.Duration `yaml:"leeway"`
+}
+
+// PIIConfig - маскирование персональных данных в ответах API для клиентов
+// без роли pii
+type PIIConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Fields - поле заказа (delivery.phone, payment.bank...) -> show, mask
+	// или omit (только из YAML); заданные в файле поля дополняют значения
+	// по умолчанию
+	Fields map[string]string `yaml:"fields"`
+}
+
+// StorageConfig - где хранятся заказы: postgres или sqlite
+// (локальная разработка и демо без контейнера Postgres)
+type StorageConfig struct {
+	Driver string `yaml:"driver"`
+}
+
+type PostgresConfig struct {
+	// DSN, если задан, используется вместо отдельных полей ниже
+	DSN           string `yaml:"dsn"`
+	Host          string `yaml:"host"`
+	Port          int    `yaml:"port"`
+	User          string `yaml:"user"`
+	Password      string `yaml:"password"`
+	Database      string `yaml:"database"`
+	SSLMode       string `yaml:"sslmode"`
+	MigrationsDir string `yaml:"migrations_dir"`
+}
+
+type SQLiteConfig struct {
+	// Path - файл базы, создается при первом запуске
+	Path          string `yaml:"path"`
+	MigrationsDir string `yaml:"migrations_dir"`
+}
+
+// PartitionsConfig - обслуживание помесячных партиций (только Postgres)
+type PartitionsConfig struct {
+	Enabled  bool          `yaml:"enabled"`
+	Interval time.Duration `yaml:"interval"`
+	// Premake - сколько месяцев после текущего создавать заранее
+	Premake int `yaml:"premake"`
+	// RetentionMonths - сколько полных месяцев до текущего хранить, 0 - все
+	RetentionMonths int `yaml:"retention_months"`
+	// RetentionMode - drop (удалить) или detach (оставить отдельными таблицами)
+	RetentionMode string `yaml:"retention_mode"`
+}
+
+// ArchiveConfig - команды archive и restore
+type ArchiveConfig struct {
+	Dir         string `yaml:"dir"`
+	Compression string `yaml:"compression"`
+	ChunkSize   int    `yaml:"chunk_size"`
+	BatchSize   int    `yaml:"batch_size"`
+}
+
+type RedisConfig struct {
+	Addr     string        `yaml:"addr"`
+	Password string        `yaml:"password"`
+	DB       int           `yaml:"db"`
+	TTL      time.Duration `yaml:"ttl"`
+}
+
+type KafkaConfig struct {
+	Brokers []string `yaml:"brokers"`
+	Topic   string   `yaml:"topic"`
+	GroupID string   `yaml:"group_id"`
+	// MaxLag - порог лага для readiness, 0 - не проверять
+	MaxLag int64 `yaml:"max_lag"`
+	// Concurrency и DLQTopic относятся к топику Topic, если Topics не задан
+	Concurrency int    `yaml:"concurrency"`
+	DLQTopic    string `yaml:"dlq_topic"`
+	// Topics - полный список читаемых топиков (только из YAML);
+	// пустой - один топик Topic с обработчиком order
+	Topics []KafkaTopicConfig `yaml:"topics"`
+	TLS    KafkaTLSConfig     `yaml:"tls"`
+	SASL   KafkaSASLConfig    `yaml:"sasl"`
+}
+
+type KafkaTopicConfig struct {
+	Name        string `yaml:"name"`
+	Handler     string `yaml:"handler"`
+	Decoding    string `yaml:"decoding"`
+	Concurrency int    `yaml:"concurrency"`
+	DLQTopic    string `yaml:"dlq_topic"`
+}
+
+type KafkaTLSConfig struct {
+	Enabled            bool   `yaml:"enabled"`
+	CAFile             string `yaml:"ca_file"`
+	CertFile           string `yaml:"cert_file"`
+	KeyFile            string `yaml:"key_file"`
+	ServerName         string `yaml:"server_name"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+}
+
+// KafkaSASLConfig - пароль лучше задавать файлом (password_file),
+// например из docker/k8s secret
+type KafkaSASLConfig struct {
+	Mechanism    string `yaml:"mechanism"`
+	Username     string `yaml:"username"`
+	Password     string `yaml:"password"`
+	PasswordFile string `yaml:"password_file"`
+}
+
+type CacheConfig struct {
+	WarmupLimit int `yaml:"warmup_limit"`
+}
+
+type HealthConfig struct {
+	Timeout  time.Duration `yaml:"timeout"`
+	CacheTTL time.Duration `yaml:"cache_ttl"`
+}
+
+type LogConfig struct {
+	Level  string `yaml:"level"`
+	Format string `yaml:"format"`
+}
+
+type TracingConfig struct {
+	Exporter    string `yaml:"exporter"`
+	ServiceName string `yaml:"service_name"`
+}
+
+// Default возвращает значения, с которыми сервис работает в docker-compose
+func Default() *Config {
+	return &Config{
+		HTTP: HTTPConfig{
+			Port:            8081,
+			ReadTimeout:     10 * time.Second,
+			WriteTimeout:    10 * time.Second,
+			ShutdownTimeout: 5 * time.Second,
+			// заказы содержат персональные данные: кэширует только браузер и
+			// каждый раз перепроверяет по ETag
+			CacheControl: CacheControlConfig{
+				Order:   "private, no-cache",
+				History: "private, no-cache",
+				Static:  "public, max-age=300",
+			},
+			// старые клиенты ходят на /order без префикса
+			LegacyRoutes: true,
+		},
+		Admin: AdminConfig{
+			Port: 8082,
+		},
+		Auth: AuthConfig{
+			JWT: JWTConfig{
+				RolesClaim: "roles",
+				Leeway:     30 * time.Second,
+			},
+		},
+		PII: PIIConfig{
+			Fields: map[string]string{
+				"delivery.name":    "mask",
+				"delivery.phone":   "mask",
+				"delivery.email":   "mask",
+				"delivery.address": "mask",
+				"delivery.zip":     "mask",
+				"payment.bank":     "mask",
+			},
+		},
+		Storage: StorageConfig{
+			Driver: "postgres",
+		},
+		Postgres: PostgresConfig{
+			Host:          "postgres",
+			Port:          5432,
+			SSLMode:       "disable",
+			MigrationsDir: "migrations",
+		},
+		SQLite: SQLiteConfig{
+			Path:          "orders.db",
+			MigrationsDir: "migrations/sqlite",
+		},
+		Partitions: PartitionsConfig{
+			Enabled:       true,
+			Interval:      time.Hour,
+			Premake:       3,
+			RetentionMode: "detach",
+		},
+		Archive: ArchiveConfig{
+			Dir:         "archive",
+			Compression: "zstd",
+			ChunkSize:   10000,
+			BatchSize:   500,
+		},
+		Redis: RedisConfig{
+			Addr: "redis:6379",
+			TTL:  30 * time.Minute,
+		},
+		Kafka: KafkaConfig{
+			Brokers:     []string{"kafka:29092"},
+			Topic:       "orders",
+			GroupID:     "order-service",
+			MaxLag:      10000,
+			Concurrency: 1,
+			SASL:        KafkaSASLConfig{Mechanism: "none"},
+		},
+		Cache: CacheConfig{
+			WarmupLimit: 1000,
+		},
+		Health: HealthConfig{
+			Timeout:  2 * time.Second,
+			CacheTTL: 5 * time.Second,
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "json",
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			ServiceName: "order-service",
+		},
+	}
+}
+
+// HTTPAddr - адрес для http.Server
+func (c *Config) HTTPAddr() string {
+	return ":" + strconv.Itoa(c.HTTP.Port)
+}
+
+// AdminAddr - адрес admin API
+func (c *Config) AdminAddr() string {
+	return ":" + strconv.Itoa(c.Admin.Port)
+}
+
+// TopicList возвращает читаемые топики: Topics или, если он пуст,
+// единственный топик заказов из Topic/Concurrency/DLQTopic
+func (c KafkaConfig) TopicList() []KafkaTopicConfig {
+	if len(c.Topics) > 0 {
+		return c.Topics
+	}
+	return []KafkaTopicConfig{{
+		Name:        c.Topic,
+		Handler:     "order",
+		Decoding:    "json",
+		Concurrency: c.Concurrency,
+		DLQTopic:    c.DLQTopic,
+	}}
+}
+
+// ConnString возвращает DSN, если он задан, или собирает его из полей
+func (c PostgresConfig) ConnString() string {
+	if c.DSN != "" {
+		return c.DSN
+	}
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.User, c.Password),
+		Host:     net.JoinHostPort(c.Host, strconv.Itoa(c.Port)),
+		Path:     "/" + c.Database,
+		RawQuery: url.Values{"sslmode": {c.SSLMode}}.Encode(),
+	}
+	return u.String()
+}
+
+var apiKeyHash = regexp.MustCompile(`^sha256:[0-9a-f]{64}$`)
+
+// Validate проверяет конфигурацию и возвращает все найденные ошибки сразу
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(key, format string, args ...any) {
+		f := lookupField(c, key)
+		hint := fmt.Sprintf(" (set %s in config file", key)
+		if f.ptr == nil {
+			// только YAML, например элементы kafka.topics
+			hint += ")"
+		} else {
+			if f.env != "" {
+				hint += ", env " + f.env
+			}
+			hint += " or flag -" + key + ")"
+		}
+		errs = append(errs, fmt.Errorf("%s: %s%s", key, fmt.Sprintf(format, args...), hint))
+	}
+	positive := func(key string, d time.Duration) {
+		if d <= 0 {
+			fail(key, "must be a positive duration, got %s", d)
+		}
+	}
+	port := func(key string, p int) {
+		if p < 1 || p > 65535 {
+			fail(key, "must be a port number between 1 and 65535, got %d", p)
+		}
+	}
+
+	port("http.port", c.HTTP.Port)
+	positive("http.read_timeout", c.HTTP.ReadTimeout)
+	positive("http.write_timeout", c.HTTP.WriteTimeout)
+	positive("http.shutdown_timeout", c.HTTP.ShutdownTimeout)
+	if c.HTTP.V1Sunset != "" {
+		if _, err := time.Parse(time.DateOnly, c.HTTP.V1Sunset); err != nil {
+			fail("http.v1_sunset", "must be a date like 2027-01-31, got %q", c.HTTP.V1Sunset)
+		}
+	}
+
+	if c.Admin.Token != "" {
+		port("admin.port", c.Admin.Port)
+		if c.Admin.Port == c.HTTP.Port {
+			fail("admin.port", "must differ from http.port")
+		}
+	}
+
+	if c.Auth.Enabled && len(c.Auth.APIKeys) == 0 && c.Auth.JWT.JWKSFile == "" {
+		fail("auth.api_keys", "at least one key or auth.jwt.jwks_file is required when auth is enabled")
+	}
+	names := make(map[string]bool)
+	for i, k := range c.Auth.APIKeys {
+		key := fmt.Sprintf("auth.api_keys[%d]", i)
+		switch {
+		case k.Name == "":
+			fail(key, "name is required")
+		case names[k.Name]:
+			fail(key, "key %s is listed twice", k.Name)
+		}
+		names[k.Name] = true
+		if !apiKeyHash.MatchString(k.Hash) {
+			fail(key, "hash must be sha256: followed by 64 hex digits (see order-service apikey)")
+		}
+		if len(k.Roles) == 0 {
+			fail(key, "at least one role is required")
+		}
+		for _, role := range k.Roles {
+			switch role {
+			case "reader", "writer", "admin", "pii":
+			default:
+				fail(key, "role must be reader, writer, admin or pii, got %q", role)
+			}
+		}
+	}
+	if c.Auth.JWT.JWKSFile != "" && c.Auth.JWT.RolesClaim == "" {
+		fail("auth.jwt.roles_claim", "is required")
+	}
+	if c.Auth.JWT.Leeway < 0 {
+		fail("auth.jwt.leeway", "must not be negative")
+	}
+	for _, path := range slices.Sorted(maps.Keys(c.PII.Fields)) {
+		switch mode := c.PII.Fields[path]; mode {
+		case "show", "mask", "omit":
+		default:
+			fail("pii.fields."+path, "must be show, mask or omit, got %q", mode)
+		}
+	}
+
+	switch c.Storage.Driver {
+	case "postgres":
+		if c.Postgres.DSN == "" {
+			if c.Postgres.Host == "" {
+				fail("postgres.host", "is required")
+			}
+			port("postgres.port", c.Postgres.Port)
+			if c.Postgres.User == "" {
+				fail("postgres.user", "is required")
+			}
+			if c.Postgres.Database == "" {
+				fail("postgres.database", "is required")
+			}
+		} else if _, err := url.Parse(c.Postgres.DSN); err != nil {
+			fail("postgres.dsn", "is not a valid URL")
+		}
+		if c.Postgres.MigrationsDir == "" {
+			fail("postgres.migrations_dir", "is required")
+		}
+		if c.Partitions.Enabled {
+			positive("partitions.interval", c.Partitions.Interval)
+			if c.Partitions.Premake < 0 {
+				fail("partitions.premake", "must not be negative")
+			}
+			if c.Partitions.RetentionMonths < 0 {
+				fail("partitions.retention_months", "must not be negative")
+			}
+			switch c.Partitions.RetentionMode {
+			case "drop", "detach":
+			default:
+				fail("partitions.retention_mode", "must be drop or detach, got %q", c.Partitions.RetentionMode)
+			}
+		}
+	case "sqlite":
+		if c.SQLite.Path == "" {
+			fail("sqlite.path", "is required")
+		}
+		if c.SQLite.MigrationsDir == "" {
+			fail("sqlite.migrations_dir", "is required")
+		}
+	default:
+		fail("storage.driver", "must be postgres or sqlite, got %q", c.Storage.Driver)
+	}
+
+	if c.Archive.Dir == "" {
+		fail("archive.dir", "is required")
+	}
+	switch c.Archive.Compression {
+	case "gzip", "zstd":
+	default:
+		fail("archive.compression", "must be gzip or zstd, got %q", c.Archive.Compression)
+	}
+	if c.Archive.ChunkSize < 1 {
+		fail("archive.chunk_size", "must be at least 1")
+	}
+	if c.Archive.BatchSize < 1 {
+		fail("archive.batch_size", "must be at least 1")
+	}
+
+	if c.Redis.Addr == "" {
+		fail("redis.addr", "is required")
+	}
+	if c.Redis.DB < 0 {
+		fail("redis.db", "must not be negative")
+	}
+	positive("redis.ttl", c.Redis.TTL)
+
+	if len(c.Kafka.Brokers) == 0 {
+		fail("kafka.brokers", "at least one broker is required")
+	}
+	if c.Kafka.Topic == "" && len(c.Kafka.Topics) == 0 {
+		fail("kafka.topic", "is required")
+	}
+	if c.Kafka.Concurrency < 1 {
+		fail("kafka.concurrency", "must be at least 1")
+	}
+	seen := make(map[string]bool)
+	for i, t := range c.Kafka.Topics {
+		key := fmt.Sprintf("kafka.topics[%d]", i)
+		switch {
+		case t.Name == "":
+			fail(key, "name is required")
+		case seen[t.Name]:
+			fail(key, "topic %s is listed twice", t.Name)
+		}
+		seen[t.Name] = true
+		if t.Handler == "" {
+			fail(key, "handler is required")
+		}
+		switch t.Decoding {
+		case "", "json", "json_strict":
+		default:
+			fail(key, "decoding must be json or json_strict, got %q", t.Decoding)
+		}
+		if t.Concurrency < 0 {
+			fail(key, "concurrency must not be negative")
+		}
+		if t.DLQTopic != "" && t.DLQTopic == t.Name {
+			fail(key, "dlq_topic must differ from the topic itself")
+		}
+	}
+	if c.Kafka.DLQTopic != "" && c.Kafka.DLQTopic == c.Kafka.Topic {
+		fail("kafka.dlq_topic", "must differ from kafka.topic")
+	}
+	if c.Kafka.GroupID == "" {
+		fail("kafka.group_id", "is required")
+	}
+	if c.Kafka.MaxLag < 0 {
+		fail("kafka.max_lag", "must not be negative")
+	}
+	if tls := c.Kafka.TLS; tls.Enabled && (tls.CertFile == "") != (tls.KeyFile == "") {
+		fail("kafka.tls.cert_file", "cert_file and key_file must be set together")
+	}
+	switch sasl := c.Kafka.SASL; sasl.Mechanism {
+	case "none":
+	case "plain", "scram-sha-256", "scram-sha-512":
+		if sasl.Username == "" {
+			fail("kafka.sasl.username", "is required for SASL %s", sasl.Mechanism)
+		}
+		if (sasl.Password == "") == (sasl.PasswordFile == "") {
+			fail("kafka.sasl.password_file", "exactly one of password or password_file is required for SASL %s", sasl.Mechanism)
+		}
+	default:
+		fail("kafka.sasl.mechanism", "must be one of none, plain, scram-sha-256, scram-sha-512, got %q", sasl.Mechanism)
+	}
+
+	if c.Cache.WarmupLimit < 0 {
+		fail("cache.warmup_limit", "must not be negative")
+	}
+
+	positive("health.timeout", c.Health.Timeout)
+	if c.Health.CacheTTL < 0 {
+		fail("health.cache_ttl", "must not be negative")
+	}
+
+	switch c.Log.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		fail("log.level", "must be one of debug, info, warn, error, got %q", c.Log.Level)
+	}
+	switch c.Log.Format {
+	case "json", "text":
+	default:
+		fail("log.format", "must be json or text, got %q", c.Log.Format)
+	}
+
+	switch c.Tracing.Exporter {
+	case "none", "stdout", "otlp":
+	default:
+		fail("tracing.exporter", "must be one of none, stdout, otlp, got %q", c.Tracing.Exporter)
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,203 @@
+package config
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, yaml string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// load - Load с обязательными полями Postgres из окружения
+func load(t *testing.T, args ...string) (*Config, error) {
+	t.Helper()
+	t.Setenv("USER_NAME", "orderuser")
+	t.Setenv("DATABASE_NAME", "orderservice")
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return Load(fs, args)
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeConfig(t, `
+http:
+  port: 9000
+  read_timeout: 20s
+redis:
+  addr: yaml:6379
+log:
+  level: warn
+kafka:
+  topic: yaml-orders
+`)
+	t.Setenv("APP_PORT", "9100")
+	t.Setenv("HTTP_READ_TIMEOUT", "30s")
+	// пустая переменная считается незаданной и не затирает YAML
+	t.Setenv("REDIS_ADDR", "")
+	t.Setenv("LOG_LEVEL", "debug")
+
+	cfg, err := load(t, "-config", path, "-http.port", "9200")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		key  string
+		got  any
+		want any
+	}{
+		{"http.port (flag over env)", cfg.HTTP.Port, 9200},
+		{"http.read_timeout (env over yaml)", cfg.HTTP.ReadTimeout, 30 * time.Second},
+		{"log.level (env over yaml)", cfg.Log.Level, "debug"},
+		{"redis.addr (empty env)", cfg.Redis.Addr, "yaml:6379"},
+		{"kafka.topic (yaml over default)", cfg.Kafka.Topic, "yaml-orders"},
+		{"http.write_timeout (default)", cfg.HTTP.WriteTimeout, Default().HTTP.WriteTimeout},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.key, tt.got, tt.want)
+		}
+	}
+}
+
+func TestLoadConfigFileEnv(t *testing.T) {
+	t.Setenv(ConfigFileEnv, writeConfig(t, "http:\n  port: 9300\n"))
+	cfg, err := load(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.HTTP.Port != 9300 {
+		t.Errorf("http.port = %d, want 9300 from %s", cfg.HTTP.Port, ConfigFileEnv)
+	}
+}
+
+func TestLoadUnknownKey(t *testing.T) {
+	path := writeConfig(t, "http:\n  prot: 9000\n")
+	_, err := load(t, "-config", path)
+	if err == nil || !strings.Contains(err.Error(), "prot") {
+		t.Fatalf("Load() with a misspelled key error = %v", err)
+	}
+}
+
+func TestLoadBoolFlag(t *testing.T) {
+	// bool-флаг без =true не должен забирать следующий аргумент
+	cfg, err := load(t, "-http.validate_requests", "-http.port", "9200")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.HTTP.ValidateRequests || cfg.HTTP.Port != 9200 {
+		t.Errorf("validate_requests = %v, port = %d", cfg.HTTP.ValidateRequests, cfg.HTTP.Port)
+	}
+
+	cfg, err = load(t, "-http.legacy_routes=false")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.HTTP.LegacyRoutes {
+		t.Error("-http.legacy_routes=false ignored")
+	}
+}
+
+func TestLoadInvalidValues(t *testing.T) {
+	t.Setenv("APP_PORT", "eighty")
+	t.Setenv("REDIS_DB", "first")
+	_, err := load(t, "-health.timeout", "soon")
+	if err == nil {
+		t.Fatal("Load() accepted invalid values")
+	}
+	for _, want := range []string{"env APP_PORT", "env REDIS_DB", "flag -health.timeout"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error has no %q:\n%v", want, err)
+		}
+	}
+}
+
+func validDefault() *Config {
+	cfg := Default()
+	cfg.Postgres.User, cfg.Postgres.Database = "orderuser", "orderservice"
+	return cfg
+}
+
+func TestValidate(t *testing.T) {
+	cfg := validDefault()
+	cfg.HTTP.Port = 0
+	cfg.HTTP.ReadTimeout = 0
+	cfg.HTTP.V1Sunset = "soon"
+	cfg.Auth.APIKeys = []APIKeyConfig{{Name: "importer", Hash: "plain", Roles: []string{"root"}}}
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Validate() = nil")
+	}
+	// все ошибки сразу, с подсказкой, где задать значение
+	for _, want := range []string{
+		"http.port: must be a port number between 1 and 65535, got 0 (set http.port in config file, env APP_PORT or flag -http.port)",
+		"http.read_timeout: must be a positive duration",
+		"http.v1_sunset: must be a date",
+		"auth.api_keys[0]: hash must be sha256:",
+		`auth.api_keys[0]: role must be reader, writer, admin or pii, got "root" (set auth.api_keys[0] in config file)`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error has no %q:\n%v", want, err)
+		}
+	}
+	if n := strings.Count(err.Error(), "\n") + 1; n != 5 {
+		t.Errorf("Validate() returned %d errors, want 5:\n%v", n, err)
+	}
+
+	if err := validDefault().Validate(); err != nil {
+		t.Errorf("default config is invalid: %v", err)
+	}
+}
+
+func TestStringRedactsSecrets(t *testing.T) {
+	cfg := Default()
+	var secrets []string
+	for _, f := range cfg.fields() {
+		if f.secret {
+			value := "secret-" + strings.ReplaceAll(f.key, ".", "-")
+			if err := f.set(value); err != nil {
+				t.Fatal(err)
+			}
+			secrets = append(secrets, value)
+		}
+	}
+	if len(secrets) == 0 {
+		t.Fatal("no secret fields")
+	}
+
+	out := cfg.String()
+	for _, s := range secrets {
+		if strings.Contains(out, s) {
+			t.Errorf("String() leaks %s", s)
+		}
+	}
+	if n := strings.Count(out, redacted); n != len(secrets) {
+		t.Errorf("String() has %d redacted values, want %d", n, len(secrets))
+	}
+	if !strings.Contains(out, "host: postgres") {
+		t.Errorf("String() lost non-secret values:\n%s", out)
+	}
+
+	// маскируется копия, исходная конфигурация не меняется
+	i := 0
+	for _, f := range cfg.fields() {
+		if f.secret {
+			if f.String() != secrets[i] {
+				t.Errorf("%s = %q after String(), want %q", f.key, f.String(), secrets[i])
+			}
+			i++
+		}
+	}
+}
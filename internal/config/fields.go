@@ -0,0 +1,175 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// field связывает поле конфигурации с переменной окружения и флагом.
+// key совпадает с путем в YAML и именем флага.
+type field struct {
+	key    string
+	env    string
+	usage  string
+	ptr    any
+	secret bool
+}
+
+// fields - единая таблица всех настраиваемых полей
+func (c *Config) fields() []field {
+	return []field{
+		{key: "http.port", env: "APP_PORT", usage: "HTTP listen port", ptr: &c.HTTP.Port},
+		{key: "http.read_timeout", env: "HTTP_READ_TIMEOUT", usage: "HTTP server read timeout", ptr: &c.HTTP.ReadTimeout},
+		{key: "http.write_timeout", env: "HTTP_WRITE_TIMEOUT", usage: "HTTP server write timeout", ptr: &c.HTTP.WriteTimeout},
+		{key: "http.shutdown_timeout", env: "HTTP_SHUTDOWN_TIMEOUT", usage: "graceful shutdown timeout", ptr: &c.HTTP.ShutdownTimeout},
+		{key: "http.cache_control.order", env: "HTTP_CACHE_CONTROL_ORDER", usage: "Cache-Control of GET /order/{order_uid}, empty omits it", ptr: &c.HTTP.CacheControl.Order},
+		{key: "http.cache_control.history", env: "HTTP_CACHE_CONTROL_HISTORY", usage: "Cache-Control of delivery history, empty omits it", ptr: &c.HTTP.CacheControl.History},
+		{key: "http.cache_control.static", env: "HTTP_CACHE_CONTROL_STATIC", usage: "Cache-Control of the web UI files, empty omits it", ptr: &c.HTTP.CacheControl.Static},
+		{key: "http.validate_requests", env: "HTTP_VALIDATE_REQUESTS", usage: "reject requests that do not match the OpenAPI spec", ptr: &c.HTTP.ValidateRequests},
+		{key: "http.legacy_routes", env: "HTTP_LEGACY_ROUTES", usage: "serve /api/v1 also without the prefix (deprecated)", ptr: &c.HTTP.LegacyRoutes},
+		{key: "http.v1_sunset", env: "HTTP_V1_SUNSET", usage: "date (YYYY-MM-DD) /api/v1 is removed, sent in the Sunset header", ptr: &c.HTTP.V1Sunset},
+
+		{key: "admin.port", env: "ADMIN_PORT", usage: "admin API listen port", ptr: &c.Admin.Port},
+		{key: "admin.token", env: "ADMIN_TOKEN", usage: "bearer token for the admin API, empty disables it", ptr: &c.Admin.Token, secret: true},
+
+		{key: "auth.enabled", env: "AUTH_ENABLED", usage: "require an API key or JWT for the order API", ptr: &c.Auth.Enabled},
+		{key: "auth.jwt.jwks_file", env: "AUTH_JWT_JWKS_FILE", usage: "JWKS file with keys that sign bearer tokens, empty disables JWT", ptr: &c.Auth.JWT.JWKSFile},
+		{key: "auth.jwt.issuer", env: "AUTH_JWT_ISSUER", usage: "expected iss of bearer tokens, empty skips the check", ptr: &c.Auth.JWT.Issuer},
+		{key: "auth.jwt.audience", env: "AUTH_JWT_AUDIENCE", usage: "expected aud of bearer tokens, empty skips the check", ptr: &c.Auth.JWT.Audience},
+		{key: "auth.jwt.roles_claim", env: "AUTH_JWT_ROLES_CLAIM", usage: "token claim with roles, nested as realm_access.roles", ptr: &c.Auth.JWT.RolesClaim},
+		{key: "auth.jwt.leeway", env: "AUTH_JWT_LEEWAY", usage: "allowed clock skew for exp and nbf", ptr: &c.Auth.JWT.Leeway},
+
+		{key: "pii.enabled", env: "PII_ENABLED", usage: "mask personal data in API responses for callers without the pii role", ptr: &c.PII.Enabled},
+
+		{key: "storage.driver", env: "STORAGE_DRIVER", usage: "order storage: postgres or sqlite", ptr: &c.Storage.Driver},
+
+		{key: "postgres.dsn", env: "DATABASE_URL", usage: "full Postgres DSN, overrides host/port/user/password/database", ptr: &c.Postgres.DSN, secret: true},
+		{key: "postgres.host", env: "POSTGRES_HOST", usage: "Postgres host", ptr: &c.Postgres.Host},
+		{key: "postgres.port", env: "POSTGRES_PORT", usage: "Postgres port", ptr: &c.Postgres.Port},
+		{key: "postgres.user", env: "USER_NAME", usage: "Postgres user", ptr: &c.Postgres.User},
+		{key: "postgres.password", env: "DATABASE_PASSWORD", usage: "Postgres password", ptr: &c.Postgres.Password, secret: true},
+		{key: "postgres.database", env: "DATABASE_NAME", usage: "Postgres database name", ptr: &c.Postgres.Database},
+		{key: "postgres.sslmode", env: "POSTGRES_SSLMODE", usage: "Postgres sslmode", ptr: &c.Postgres.SSLMode},
+		{key: "postgres.migrations_dir", env: "MIGRATIONS_DIR", usage: "directory with *.up.sql migrations", ptr: &c.Postgres.MigrationsDir},
+
+		{key: "sqlite.path", env: "SQLITE_PATH", usage: "SQLite database file", ptr: &c.SQLite.Path},
+		{key: "sqlite.migrations_dir", env: "SQLITE_MIGRATIONS_DIR", usage: "directory with SQLite *.up.sql migrations", ptr: &c.SQLite.MigrationsDir},
+
+		{key: "partitions.enabled", env: "PARTITIONS_ENABLED", usage: "create monthly partitions ahead and apply retention (postgres only)", ptr: &c.Partitions.Enabled},
+		{key: "partitions.interval", env: "PARTITIONS_INTERVAL", usage: "how often partitions are maintained", ptr: &c.Partitions.Interval},
+		{key: "partitions.premake", env: "PARTITIONS_PREMAKE", usage: "months after the current one to create partitions for", ptr: &c.Partitions.Premake},
+		{key: "partitions.retention_months", env: "PARTITIONS_RETENTION_MONTHS", usage: "full months before the current one to keep, 0 keeps everything", ptr: &c.Partitions.RetentionMonths},
+		{key: "partitions.retention_mode", env: "PARTITIONS_RETENTION_MODE", usage: "what to do with expired partitions: drop or detach", ptr: &c.Partitions.RetentionMode},
+
+		{key: "archive.dir", env: "ARCHIVE_DIR", usage: "directory for order archives", ptr: &c.Archive.Dir},
+		{key: "archive.compression", env: "ARCHIVE_COMPRESSION", usage: "archive compression: gzip or zstd", ptr: &c.Archive.Compression},
+		{key: "archive.chunk_size", env: "ARCHIVE_CHUNK_SIZE", usage: "orders per archive file", ptr: &c.Archive.ChunkSize},
+		{key: "archive.batch_size", env: "ARCHIVE_BATCH_SIZE", usage: "orders read and deleted per query while archiving", ptr: &c.Archive.BatchSize},
+
+		{key: "redis.addr", env: "REDIS_ADDR", usage: "Redis address host:port", ptr: &c.Redis.Addr},
+		{key: "redis.password", env: "REDIS_PASSWORD", usage: "Redis password", ptr: &c.Redis.Password, secret: true},
+		{key: "redis.db", env: "REDIS_DB", usage: "Redis database number", ptr: &c.Redis.DB},
+		{key: "redis.ttl", env: "CACHE_TTL", usage: "TTL of cached orders", ptr: &c.Redis.TTL},
+
+		{key: "kafka.brokers", env: "KAFKA_BROKERS", usage: "comma-separated Kafka brokers", ptr: &c.Kafka.Brokers},
+		{key: "kafka.topic", env: "KAFKA_TOPIC", usage: "orders topic", ptr: &c.Kafka.Topic},
+		{key: "kafka.group_id", env: "KAFKA_GROUP_ID", usage: "consumer group id", ptr: &c.Kafka.GroupID},
+		{key: "kafka.max_lag", env: "KAFKA_MAX_LAG", usage: "lag above which the service is not ready, 0 disables", ptr: &c.Kafka.MaxLag},
+		{key: "kafka.concurrency", env: "KAFKA_CONCURRENCY", usage: "readers of kafka.topic in the group", ptr: &c.Kafka.Concurrency},
+		{key: "kafka.dlq_topic", env: "KAFKA_DLQ_TOPIC", usage: "dead letter topic for kafka.topic, empty only logs failures", ptr: &c.Kafka.DLQTopic},
+		{key: "kafka.tls.enabled", env: "KAFKA_TLS_ENABLED", usage: "connect to Kafka over TLS", ptr: &c.Kafka.TLS.Enabled},
+		{key: "kafka.tls.ca_file", env: "KAFKA_TLS_CA_FILE", usage: "PEM file with CA certificates, empty uses system roots", ptr: &c.Kafka.TLS.CAFile},
+		{key: "kafka.tls.cert_file", env: "KAFKA_TLS_CERT_FILE", usage: "PEM client certificate for mTLS", ptr: &c.Kafka.TLS.CertFile},
+		{key: "kafka.tls.key_file", env: "KAFKA_TLS_KEY_FILE", usage: "PEM client key for mTLS", ptr: &c.Kafka.TLS.KeyFile},
+		{key: "kafka.tls.server_name", env: "KAFKA_TLS_SERVER_NAME", usage: "expected broker certificate name", ptr: &c.Kafka.TLS.ServerName},
+		{key: "kafka.tls.insecure_skip_verify", env: "KAFKA_TLS_INSECURE_SKIP_VERIFY", usage: "do not verify broker certificates (testing only)", ptr: &c.Kafka.TLS.InsecureSkipVerify},
+		{key: "kafka.sasl.mechanism", env: "KAFKA_SASL_MECHANISM", usage: "SASL mechanism: none, plain, scram-sha-256, scram-sha-512", ptr: &c.Kafka.SASL.Mechanism},
+		{key: "kafka.sasl.username", env: "KAFKA_SASL_USERNAME", usage: "SASL username", ptr: &c.Kafka.SASL.Username},
+		{key: "kafka.sasl.password", env: "KAFKA_SASL_PASSWORD", usage: "SASL password, prefer password_file", ptr: &c.Kafka.SASL.Password, secret: true},
+		{key: "kafka.sasl.password_file", env: "KAFKA_SASL_PASSWORD_FILE", usage: "file containing the SASL password", ptr: &c.Kafka.SASL.PasswordFile},
+
+		{key: "cache.warmup_limit", env: "CACHE_WARMUP_LIMIT", usage: "number of newest orders loaded into cache at startup", ptr: &c.Cache.WarmupLimit},
+
+		{key: "health.timeout", env: "HEALTH_TIMEOUT", usage: "default timeout of a single health check", ptr: &c.Health.Timeout},
+		{key: "health.cache_ttl", env: "HEALTH_CACHE_TTL", usage: "how long health check results are reused", ptr: &c.Health.CacheTTL},
+
+		{key: "log.level", env: "LOG_LEVEL", usage: "log level: debug, info, warn, error", ptr: &c.Log.Level},
+		{key: "log.format", env: "LOG_FORMAT", usage: "log format: json or text", ptr: &c.Log.Format},
+
+		{key: "tracing.exporter", env: "OTEL_TRACES_EXPORTER", usage: "trace exporter: none, stdout, otlp", ptr: &c.Tracing.Exporter},
+		{key: "tracing.service_name", env: "OTEL_SERVICE_NAME", usage: "service name in traces", ptr: &c.Tracing.ServiceName},
+	}
+}
+
+func lookupField(c *Config, key string) field {
+	for _, f := range c.fields() {
+		if f.key == key {
+			return f
+		}
+	}
+	return field{key: key}
+}
+
+// set разбирает строковое значение в поле соответствующего типа
+func (f field) set(value string) error {
+	switch p := f.ptr.(type) {
+	case *string:
+		*p = value
+	case *int:
+		v, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%s: invalid integer %q", f.key, value)
+		}
+		*p = v
+	case *int64:
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%s: invalid integer %q", f.key, value)
+		}
+		*p = v
+	case *bool:
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%s: invalid boolean %q", f.key, value)
+		}
+		*p = v
+	case *time.Duration:
+		v, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%s: invalid duration %q (use e.g. 10s, 5m)", f.key, value)
+		}
+		*p = v
+	case *[]string:
+		var list []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		*p = list
+	default:
+		return fmt.Errorf("%s: unsupported field type %T", f.key, f.ptr)
+	}
+	return nil
+}
+
+// String возвращает текущее значение поля в виде строки
+func (f field) String() string {
+	switch p := f.ptr.(type) {
+	case *string:
+		return *p
+	case *int:
+		return strconv.Itoa(*p)
+	case *int64:
+		return strconv.FormatInt(*p, 10)
+	case *bool:
+		return strconv.FormatBool(*p)
+	case *time.Duration:
+		return p.String()
+	case *[]string:
+		return strings.Join(*p, ",")
+	}
+	return ""
+}
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFileEnv - переменная окружения с путем к YAML-файлу (альтернатива -config)
+const ConfigFileEnv = "CONFIG_FILE"
+
+const redacted = "******"
+
+// Load собирает конфигурацию: значения по умолчанию, затем YAML-файл
+// (-config или CONFIG_FILE), затем переменные окружения, затем флаги.
+// Флаги конфигурации регистрируются на fs, поэтому вызывающий код может
+// заранее добавить туда свои флаги. Результат проходит Validate.
+func Load(fs *flag.FlagSet, args []string) (*Config, error) {
+	cfg := Default()
+	fields := cfg.fields()
+
+	configPath := fs.String("config", os.Getenv(ConfigFileEnv), "path to YAML config file (env "+ConfigFileEnv+")")
+	pending := make(map[string]*flagValue, len(fields))
+	for _, f := range fields {
+		_, isBool := f.ptr.(*bool)
+		v := &flagValue{def: f.String(), isBool: isBool}
+		if f.secret {
+			v.def = ""
+		}
+		pending[f.key] = v
+		fs.Var(v, f.key, f.usage+" (env "+f.env+")")
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *configPath != "" {
+		if err := cfg.loadFile(*configPath); err != nil {
+			return nil, err
+		}
+	}
+
+	var errs []error
+	for _, f := range fields {
+		// пустая переменная (например, из .env) считается незаданной
+		if value := os.Getenv(f.env); f.env != "" && value != "" {
+			if err := f.set(value); err != nil {
+				errs = append(errs, fmt.Errorf("env %s: %w", f.env, err))
+			}
+		}
+	}
+	fs.Visit(func(fl *flag.Flag) {
+		v, ok := pending[fl.Name]
+		if !ok {
+			return
+		}
+		if err := lookupField(cfg, fl.Name).set(v.value); err != nil {
+			errs = append(errs, fmt.Errorf("flag -%s: %w", fl.Name, err))
+		}
+	})
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return cfg, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	// опечатки в ключах должны падать при старте, а не молча игнорироваться
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// String возвращает конфигурацию в YAML с замаскированными секретами
+func (c *Config) String() string {
+	// все секреты - строки во вложенных структурах (не в срезах и картах),
+	// поэтому маскирование копии не трогает c
+	cp := *c
+	for _, f := range cp.fields() {
+		if f.secret && f.String() != "" {
+			_ = f.set(redacted)
+		}
+	}
+
+	out, err := yaml.Marshal(&cp)
+	if err != nil {
+		return fmt.Sprintf("<config: %v>", err)
+	}
+	return string(out)
+}
+
+// flagValue запоминает значение флага, чтобы применить его после env
+type flagValue struct {
+	def    string
+	value  string
+	isBool bool
+}
+
+func (v *flagValue) String() string {
+	if v == nil {
+		return ""
+	}
+	return v.def
+}
+
+func (v *flagValue) Set(s string) error {
+	v.value = s
+	return nil
+}
+
+// IsBoolFlag позволяет писать -kafka.tls.enabled без =true
+func (v *flagValue) IsBoolFlag() bool {
+	return v.isBool
+}
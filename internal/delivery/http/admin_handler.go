@@ -0,0 +1,152 @@
+package http
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Sergi-Ch/WB_L0_2025/internal/kafka"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/logger"
+	"github.com/go-chi/chi/v5"
+)
+
+// defaultDrainTimeout - сколько POST /admin/consumer/drain ждет завершения
+const defaultDrainTimeout = 30 * time.Second
+
+// ConsumerAdminInterface - управление consumer'ами (реализует *kafka.Manager).
+// Пустой topic - все топики.
+type ConsumerAdminInterface interface {
+	Pause(topic string) error
+	Resume(topic string) error
+	Drain(ctx context.Context, topic string) error
+	Status(ctx context.Context) []kafka.ConsumerStatus
+}
+
+// AdminHandler - admin API, монтируется на отдельный роутер и порт
+type AdminHandler struct {
+	consumer ConsumerAdminInterface
+	token    string
+	log      *slog.Logger
+}
+
+func NewAdminHandler(consumer ConsumerAdminInterface, token string, log *slog.Logger) *AdminHandler {
+	return &AdminHandler{consumer: consumer, token: token, log: log.With(slog.String("component", "admin"))}
+}
+
+func (h *AdminHandler) RegisterRoutes(r chi.Router) {
+	r.Route("/admin", func(r chi.Router) {
+		r.Use(h.authenticate)
+		r.Get("/consumer", h.ConsumerStatus)
+		r.Post("/consumer/pause", h.PauseConsumer)
+		r.Post("/consumer/resume", h.ResumeConsumer)
+		r.Post("/consumer/drain", h.DrainConsumer)
+	})
+}
+
+// authenticate пропускает только запросы с Authorization: Bearer <token>
+func (h *AdminHandler) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) != 1 {
+			h.log.WarnContext(r.Context(), "unauthorized admin request", slog.String("path", r.URL.Path))
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeProblem(w, r, http.StatusUnauthorized, "bearer token is missing or invalid")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// GET /admin/consumer?topic=orders
+func (h *AdminHandler) ConsumerStatus(w http.ResponseWriter, r *http.Request) {
+	h.writeStatus(w, r, http.StatusOK)
+}
+
+// POST /admin/consumer/pause?topic=orders
+func (h *AdminHandler) PauseConsumer(w http.ResponseWriter, r *http.Request) {
+	if err := h.consumer.Pause(r.URL.Query().Get("topic")); err != nil {
+		h.stateError(w, r, err)
+		return
+	}
+	h.log.InfoContext(r.Context(), "consumer paused via admin API", slog.String("topic", r.URL.Query().Get("topic")))
+	h.writeStatus(w, r, http.StatusOK)
+}
+
+// POST /admin/consumer/resume?topic=orders
+func (h *AdminHandler) ResumeConsumer(w http.ResponseWriter, r *http.Request) {
+	if err := h.consumer.Resume(r.URL.Query().Get("topic")); err != nil {
+		h.stateError(w, r, err)
+		return
+	}
+	h.log.InfoContext(r.Context(), "consumer resumed via admin API", slog.String("topic", r.URL.Query().Get("topic")))
+	h.writeStatus(w, r, http.StatusOK)
+}
+
+// POST /admin/consumer/drain?topic=orders&timeout=30s
+// 200 - consumer остановлен, 202 - таймаут истек, drain продолжается
+func (h *AdminHandler) DrainConsumer(w http.ResponseWriter, r *http.Request) {
+	timeout := defaultDrainTimeout
+	if v := r.URL.Query().Get("timeout"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			writeProblem(w, r, http.StatusBadRequest, "timeout must be a positive duration")
+			return
+		}
+		timeout = d
+	}
+
+	topic := r.URL.Query().Get("topic")
+	h.log.InfoContext(r.Context(), "consumer drain requested via admin API",
+		slog.String("topic", topic), slog.Duration("timeout", timeout))
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
+	code := http.StatusOK
+	if err := h.consumer.Drain(ctx, topic); err != nil {
+		if !errors.Is(err, context.DeadlineExceeded) {
+			h.stateError(w, r, err)
+			return
+		}
+		code = http.StatusAccepted
+	}
+	h.writeStatus(w, r, code)
+}
+
+func (h *AdminHandler) stateError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, kafka.ErrUnknownTopic):
+		writeProblem(w, r, http.StatusNotFound, err.Error())
+		return
+	case errors.Is(err, kafka.ErrConsumerState):
+		writeProblem(w, r, http.StatusConflict, err.Error())
+		return
+	}
+	h.log.ErrorContext(r.Context(), "admin action failed", logger.Err(err))
+	writeProblem(w, r, http.StatusInternalServerError, "admin action failed")
+}
+
+// writeStatus отвечает состоянием consumer'ов (только топика из ?topic=, если он задан)
+func (h *AdminHandler) writeStatus(w http.ResponseWriter, r *http.Request, code int) {
+	topic := r.URL.Query().Get("topic")
+	consumers := []kafka.ConsumerStatus{}
+	for _, st := range h.consumer.Status(r.Context()) {
+		if topic == "" || st.Topic == topic {
+			consumers = append(consumers, st)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	resp := struct {
+		Consumers []kafka.ConsumerStatus `json:"consumers"`
+	}{consumers}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.log.ErrorContext(r.Context(), "json encoding error", logger.Err(err))
+	}
+}
@@ -0,0 +1,167 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Sergi-Ch/WB_L0_2025/internal/kafka"
+	"github.com/go-chi/chi/v5"
+)
+
+// fakeConsumers - consumer'ы топиков с переходами состояний как у kafka.Consumer
+type fakeConsumers struct {
+	states map[string]kafka.ConsumerState
+	// slowDrain - drain не успевает до таймаута запроса
+	slowDrain bool
+}
+
+func newFakeConsumers() *fakeConsumers {
+	return &fakeConsumers{states: map[string]kafka.ConsumerState{
+		"orders":              kafka.ConsumerRunning,
+		"order-cancellations": kafka.ConsumerRunning,
+	}}
+}
+
+func (f *fakeConsumers) each(topic string, fn func(topic string) error) error {
+	if topic != "" {
+		if _, ok := f.states[topic]; !ok {
+			return fmt.Errorf("%w: %s", kafka.ErrUnknownTopic, topic)
+		}
+		return fn(topic)
+	}
+	for t := range f.states {
+		if err := fn(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fakeConsumers) transition(topic string, from, to kafka.ConsumerState) error {
+	return f.each(topic, func(t string) error {
+		if f.states[t] != from {
+			return fmt.Errorf("%w: %s is %s", kafka.ErrConsumerState, t, f.states[t])
+		}
+		f.states[t] = to
+		return nil
+	})
+}
+
+func (f *fakeConsumers) Pause(topic string) error {
+	return f.transition(topic, kafka.ConsumerRunning, kafka.ConsumerPaused)
+}
+
+func (f *fakeConsumers) Resume(topic string) error {
+	return f.transition(topic, kafka.ConsumerPaused, kafka.ConsumerRunning)
+}
+
+func (f *fakeConsumers) Drain(ctx context.Context, topic string) error {
+	if err := f.each(topic, func(t string) error { f.states[t] = kafka.ConsumerDraining; return nil }); err != nil {
+		return err
+	}
+	if f.slowDrain {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return f.each(topic, func(t string) error { f.states[t] = kafka.ConsumerDrained; return nil })
+}
+
+func (f *fakeConsumers) Status(context.Context) []kafka.ConsumerStatus {
+	var statuses []kafka.ConsumerStatus
+	for topic, state := range f.states {
+		statuses = append(statuses, kafka.ConsumerStatus{Topic: topic, State: state})
+	}
+	return statuses
+}
+
+func TestAdminHandler(t *testing.T) {
+	tests := []struct {
+		name      string
+		method    string
+		path      string
+		token     string
+		paused    bool // orders на паузе до запроса
+		slowDrain bool
+		want      int
+		// состояния в ответе по топикам
+		states map[string]kafka.ConsumerState
+	}{
+		{"no token", http.MethodGet, "/admin/consumer", "", false, false, http.StatusUnauthorized, nil},
+		{"wrong token", http.MethodGet, "/admin/consumer", "guess", false, false, http.StatusUnauthorized, nil},
+		{"status", http.MethodGet, "/admin/consumer", "admin-token", false, false, http.StatusOK,
+			map[string]kafka.ConsumerState{"orders": kafka.ConsumerRunning, "order-cancellations": kafka.ConsumerRunning}},
+		{"status of topic", http.MethodGet, "/admin/consumer?topic=orders", "admin-token", false, false, http.StatusOK,
+			map[string]kafka.ConsumerState{"orders": kafka.ConsumerRunning}},
+		{"pause topic", http.MethodPost, "/admin/consumer/pause?topic=orders", "admin-token", false, false, http.StatusOK,
+			map[string]kafka.ConsumerState{"orders": kafka.ConsumerPaused}},
+		{"pause while paused", http.MethodPost, "/admin/consumer/pause?topic=orders", "admin-token", true, false, http.StatusConflict, nil},
+		{"pause unknown topic", http.MethodPost, "/admin/consumer/pause?topic=payments", "admin-token", false, false, http.StatusNotFound, nil},
+		{"resume", http.MethodPost, "/admin/consumer/resume?topic=orders", "admin-token", true, false, http.StatusOK,
+			map[string]kafka.ConsumerState{"orders": kafka.ConsumerRunning}},
+		{"resume running", http.MethodPost, "/admin/consumer/resume?topic=orders", "admin-token", false, false, http.StatusConflict, nil},
+		{"drain", http.MethodPost, "/admin/consumer/drain?topic=orders", "admin-token", false, false, http.StatusOK,
+			map[string]kafka.ConsumerState{"orders": kafka.ConsumerDrained}},
+		{"drain all", http.MethodPost, "/admin/consumer/drain", "admin-token", false, false, http.StatusOK,
+			map[string]kafka.ConsumerState{"orders": kafka.ConsumerDrained, "order-cancellations": kafka.ConsumerDrained}},
+		{"drain timeout", http.MethodPost, "/admin/consumer/drain?topic=orders&timeout=10ms", "admin-token", false, true, http.StatusAccepted,
+			map[string]kafka.ConsumerState{"orders": kafka.ConsumerDraining}},
+		{"drain bad timeout", http.MethodPost, "/admin/consumer/drain?timeout=soon", "admin-token", false, false, http.StatusBadRequest, nil},
+		{"drain negative timeout", http.MethodPost, "/admin/consumer/drain?timeout=-1s", "admin-token", false, false, http.StatusBadRequest, nil},
+		{"drain unknown topic", http.MethodPost, "/admin/consumer/drain?topic=payments", "admin-token", false, false, http.StatusNotFound, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			consumers := newFakeConsumers()
+			consumers.slowDrain = tt.slowDrain
+			if tt.paused {
+				consumers.states["orders"] = kafka.ConsumerPaused
+			}
+			r := chi.NewRouter()
+			NewAdminHandler(consumers, "admin-token", slog.New(slog.DiscardHandler)).RegisterRoutes(r)
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Fatalf("%s %s = %d, want %d: %s", tt.method, tt.path, w.Code, tt.want, w.Body)
+			}
+			if tt.want == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 without WWW-Authenticate")
+			}
+			if tt.want >= http.StatusBadRequest {
+				if ct := w.Header().Get("Content-Type"); ct != problemContentType {
+					t.Errorf("Content-Type = %q, want %q", ct, problemContentType)
+				}
+				return
+			}
+
+			var resp struct {
+				Consumers []kafka.ConsumerStatus `json:"consumers"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			got := make(map[string]kafka.ConsumerState, len(resp.Consumers))
+			for _, st := range resp.Consumers {
+				got[st.Topic] = st.State
+			}
+			if len(got) != len(tt.states) {
+				t.Errorf("consumers = %v, want %v", got, tt.states)
+			}
+			for topic, state := range tt.states {
+				if got[topic] != state {
+					t.Errorf("%s state = %q, want %q", topic, got[topic], state)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Sergi-Ch/WB_L0_2025/domain"
+)
+
+// apiVersion - формат заказа в одной версии API. Маршруты у версий общие,
+// различаются тела с заказом.
+type apiVersion struct {
+	name string
+	// encode - тело ответа с заказом
+	encode func(order *domain.Order) any
+	// decode - заказ из тела POST /order
+	decode func(body io.Reader) (*domain.Order, error)
+}
+
+var (
+	apiV1 = apiVersion{
+		name:   "v1",
+		encode: func(order *domain.Order) any { return order },
+		decode: func(body io.Reader) (*domain.Order, error) {
+			var order domain.Order
+			if err := json.NewDecoder(body).Decode(&order); err != nil {
+				return nil, err
+			}
+			return &order, nil
+		},
+	}
+	apiV2 = apiVersion{
+		name:   "v2",
+		encode: func(order *domain.Order) any { return newOrderV2(order) },
+		decode: decodeOrderV2,
+	}
+)
+
+// v1DeprecatedAt - дата, с которой /api/v1 и маршруты без префикса устарели
+// (вышла /api/v2)
+var v1DeprecatedAt = time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+
+type apiVersionKey struct{}
+
+// withAPIVersion задает версию, в формате которой обработчики читают и отдают заказы
+func withAPIVersion(v apiVersion) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiVersionKey{}, v)))
+		})
+	}
+}
+
+// versionOf - версия API запроса, без withAPIVersion - v1
+func versionOf(r *http.Request) apiVersion {
+	if v, ok := r.Context().Value(apiVersionKey{}).(apiVersion); ok {
+		return v
+	}
+	return apiV1
+}
+
+// deprecated добавляет к ответам заголовки устаревшей версии: Deprecation
+// (RFC 9745), Sunset (RFC 8594), если дата отключения известна, и Link на
+// тот же путь с префиксом successor вместо prefix
+func deprecated(at, sunset time.Time, prefix, successor string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", "@"+strconv.FormatInt(at.Unix(), 10))
+			if !sunset.IsZero() {
+				w.Header().Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+			}
+			link := successor + strings.TrimPrefix(r.URL.Path, prefix)
+			w.Header().Add("Link", "<"+link+`>; rel="successor-version"`)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
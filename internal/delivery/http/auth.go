@@ -0,0 +1,110 @@
+package http
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/Sergi-Ch/WB_L0_2025/internal/auth"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/logger"
+)
+
+// apiKeyHeader - заголовок со статическим API-ключом
+const apiKeyHeader = "X-API-Key"
+
+// authenticate проверяет X-API-Key или Authorization: Bearer и кладет клиента
+// в контекст. Запрос без учетных данных проходит дальше: роль проверяет
+// require у маршрута, открытые маршруты (openapi.json) доступны всем.
+// С аутентификацией или маскированием добавляет Vary по учетным данным.
+func (h *OrderHandler) authenticate(next http.Handler) http.Handler {
+	if h.cfg.Auth == nil && h.cfg.PII == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// ответ зависит от учетных данных (401, маскирование персональных данных),
+		// кэш не должен отдавать его другому клиенту
+		w.Header().Add("Vary", "Authorization, "+apiKeyHeader)
+		if h.cfg.Auth == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var (
+			p   *auth.Principal
+			err error
+		)
+		if key := r.Header.Get(apiKeyHeader); key != "" {
+			p, err = h.cfg.Auth.APIKey(key)
+		} else if header := r.Header.Get("Authorization"); header != "" {
+			token, ok := cutBearer(header)
+			if !ok {
+				h.unauthorized(w, r, "authorization header must be Bearer <token>")
+				return
+			}
+			p, err = h.cfg.Auth.Token(token)
+		} else {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if err != nil {
+			// причина (подпись, kid, срок) только в логе: клиенту она не нужна,
+			// а подбирающему ключи подсказывает, что исправить
+			h.log.WarnContext(r.Context(), "authentication failed", slog.String("path", r.URL.Path), logger.Err(err))
+			h.unauthorized(w, r, "invalid or expired token")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), p)))
+	})
+}
+
+// require пропускает клиентов с ролью role или старшей: без учетных данных - 401,
+// с недостаточной ролью - 403. Без настроенной аутентификации пропускает всех.
+func (h *OrderHandler) require(role auth.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if h.cfg.Auth == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p := auth.PrincipalFrom(r.Context())
+			switch {
+			case p == nil:
+				h.unauthorized(w, r, "credentials are required")
+			case !p.Has(role):
+				h.log.WarnContext(r.Context(), "access denied",
+					slog.String("subject", p.Subject), slog.String("role", string(role)), slog.String("path", r.URL.Path))
+				writeProblem(w, r, http.StatusForbidden, "role "+string(role)+" is required")
+			default:
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+func (h *OrderHandler) unauthorized(w http.ResponseWriter, r *http.Request, detail string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="order-service"`)
+	writeProblem(w, r, http.StatusUnauthorized, detail)
+}
+
+// cutBearer - токен из Authorization, схема без учета регистра (RFC 9110)
+func cutBearer(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// actorOf - автор изменения. С аутентификацией это всегда клиент запроса:
+// явно переданный actor допустим, только если совпадает с ним, иначе false.
+// Без аутентификации - переданный actor как есть.
+func actorOf(r *http.Request, actor string) (string, bool) {
+	p := auth.PrincipalFrom(r.Context())
+	if p == nil {
+		return actor, true
+	}
+	if actor != "" && actor != p.Subject {
+		return "", false
+	}
+	return p.Subject, true
+}
@@ -0,0 +1,195 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Sergi-Ch/WB_L0_2025/domain"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/auth"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/repository"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/service"
+	"github.com/go-chi/chi/v5"
+)
+
+// stubService отдает один заказ и запоминает аргументы изменений. err
+// возвращают DeleteOrder и UpdateDelivery, deleted - заказ отменен.
+type stubService struct {
+	actor   string
+	reason  string
+	opts    service.GetOptions
+	patch   []byte
+	version int64
+	deleted bool
+	err     error
+}
+
+func (s *stubService) SaveOrder(context.Context, *domain.Order) error { return nil }
+
+func (s *stubService) GetOrderByID(_ context.Context, id string, opts service.GetOptions) (*domain.Order, error) {
+	s.opts = opts
+	order := &domain.Order{
+		OrderUid:    id,
+		Delivery:    domain.Delivery{Name: "Test Testov", Phone: "+79991112233", Email: "test@gmail.com"},
+		Payment:     domain.Payment{Currency: "USD", Amount: 1817, Bank: "alpha"},
+		DateCreated: time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC),
+		Version:     1,
+	}
+	if s.deleted {
+		if !opts.IncludeDeleted {
+			return nil, fmt.Errorf("%w: %s", repository.ErrNotFound, id)
+		}
+		order.Deletion = &domain.Deletion{At: order.DateCreated.Add(time.Hour), Reason: "customer request", Actor: "support"}
+	}
+	return order, nil
+}
+
+func (s *stubService) DeleteOrder(_ context.Context, _, reason, actor string) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.reason, s.actor = reason, actor
+	return nil
+}
+
+func (s *stubService) UpdateDelivery(ctx context.Context, id string, patch []byte, version int64, actor string) (*domain.Order, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	s.patch, s.version, s.actor = patch, version, actor
+	order, _ := s.GetOrderByID(ctx, id, service.GetOptions{})
+	order.Version = 2
+	return order, nil
+}
+
+func (s *stubService) DeliveryHistory(context.Context, string) ([]domain.DeliveryChange, error) {
+	return []domain.DeliveryChange{{Version: 2, Before: domain.Delivery{Phone: "+79991112233"}, After: domain.Delivery{Phone: "+79994445566"}}}, nil
+}
+
+func TestRouteRoles(t *testing.T) {
+	authenticator, err := auth.NewAuthenticator([]auth.APIKey{
+		{Name: "viewer", Hash: auth.HashAPIKey("reader-key"), Roles: []auth.Role{auth.RoleReader}},
+		{Name: "importer", Hash: auth.HashAPIKey("writer-key"), Roles: []auth.Role{auth.RoleWriter}},
+		{Name: "ops", Hash: auth.HashAPIKey("admin-key"), Roles: []auth.Role{auth.RoleAdmin}},
+	}, auth.JWTConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := &stubService{}
+	r := chi.NewRouter()
+	NewOrderHandler(svc, HandlerConfig{LegacyRoutes: true, Auth: authenticator}, slog.New(slog.DiscardHandler)).RegisterRoutes(r)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		header string
+		value  string
+		want   int
+	}{
+		{"no credentials", http.MethodGet, "/api/v2/order/test-1", "", "", http.StatusUnauthorized},
+		{"unknown key", http.MethodGet, "/api/v2/order/test-1", apiKeyHeader, "guess", http.StatusUnauthorized},
+		{"reader reads", http.MethodGet, "/api/v2/order/test-1", apiKeyHeader, "reader-key", http.StatusOK},
+		{"reader reads history", http.MethodGet, "/api/v1/order/test-1/delivery/history", apiKeyHeader, "reader-key", http.StatusOK},
+		{"reader creates", http.MethodPost, "/api/v2/order", apiKeyHeader, "reader-key", http.StatusForbidden},
+		{"reader patches", http.MethodPatch, "/api/v2/order/test-1/delivery", apiKeyHeader, "reader-key", http.StatusForbidden},
+		{"writer reads", http.MethodGet, "/api/v2/order/test-1", apiKeyHeader, "writer-key", http.StatusOK},
+		{"writer deletes", http.MethodDelete, "/api/v2/order/test-1", apiKeyHeader, "writer-key", http.StatusForbidden},
+		{"admin deletes", http.MethodDelete, "/api/v2/order/test-1", apiKeyHeader, "admin-key", http.StatusNoContent},
+		{"admin deletes as itself", http.MethodDelete, "/api/v2/order/test-1?actor=ops", apiKeyHeader, "admin-key", http.StatusNoContent},
+		{"forged actor", http.MethodDelete, "/api/v2/order/test-1?actor=someone-else", apiKeyHeader, "admin-key", http.StatusForbidden},
+		{"forged patch actor", http.MethodPatch, "/api/v2/order/test-1/delivery?actor=someone-else", apiKeyHeader, "writer-key", http.StatusForbidden},
+		{"legacy route", http.MethodDelete, "/order/test-1", apiKeyHeader, "reader-key", http.StatusForbidden},
+		{"basic auth", http.MethodGet, "/api/v2/order/test-1", "Authorization", "Basic dXNlcjpwYXNz", http.StatusUnauthorized},
+		{"bearer without jwks", http.MethodGet, "/api/v2/order/test-1", "Authorization", "Bearer abc.def.ghi", http.StatusUnauthorized},
+		{"public spec", http.MethodGet, "/api/v2/openapi.json", "", "", http.StatusOK},
+		{"bad key on public route", http.MethodGet, "/api/v2/openapi.json", apiKeyHeader, "guess", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Fatalf("%s %s = %d, want %d: %s", tt.method, tt.path, w.Code, tt.want, w.Body)
+			}
+			if tt.want == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 without WWW-Authenticate")
+			}
+			if (tt.want == http.StatusUnauthorized || tt.want == http.StatusForbidden) && w.Header().Get("Content-Type") != problemContentType {
+				t.Errorf("Content-Type = %q, want %q", w.Header().Get("Content-Type"), problemContentType)
+			}
+		})
+	}
+
+	// автор отмены без actor - имя ключа
+	if svc.actor != "ops" {
+		t.Errorf("delete actor = %q, want ops", svc.actor)
+	}
+
+	// подмена автора в теле DELETE
+	svc.actor = ""
+	req := httptest.NewRequest(http.MethodDelete, "/api/v2/order/test-1", strings.NewReader(`{"reason":"test","actor":"someone-else"}`))
+	req.Header.Set(apiKeyHeader, "admin-key")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden || svc.actor != "" {
+		t.Errorf("DELETE with a forged actor in body = %d, recorded actor %q", w.Code, svc.actor)
+	}
+}
+
+func TestAuthFailureDetail(t *testing.T) {
+	authenticator, err := auth.NewAuthenticator(nil, auth.JWTConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := chi.NewRouter()
+	NewOrderHandler(&stubService{}, HandlerConfig{Auth: authenticator}, slog.New(slog.DiscardHandler)).RegisterRoutes(r)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v2/order/test-1", nil)
+	req.Header.Set("Authorization", "Bearer abc.def.ghi")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var p Problem
+	if err := json.NewDecoder(w.Body).Decode(&p); err != nil {
+		t.Fatal(err)
+	}
+	// причина отказа (нет JWKS, подпись, kid) клиенту не раскрывается
+	if w.Code != http.StatusUnauthorized || p.Detail != "invalid or expired token" {
+		t.Errorf("401 detail = %q", p.Detail)
+	}
+}
+
+func TestActorWithoutAuth(t *testing.T) {
+	svc := &stubService{}
+	r := chi.NewRouter()
+	NewOrderHandler(svc, HandlerConfig{}, slog.New(slog.DiscardHandler)).RegisterRoutes(r)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/v2/order/test-1?actor=support", nil))
+	if w.Code != http.StatusNoContent || svc.actor != "support" {
+		t.Errorf("DELETE ?actor=support without auth = %d, recorded actor %q", w.Code, svc.actor)
+	}
+}
+
+func TestRouteRolesDisabled(t *testing.T) {
+	r := chi.NewRouter()
+	NewOrderHandler(&stubService{}, HandlerConfig{}, slog.New(slog.DiscardHandler)).RegisterRoutes(r)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/v2/order/test-1", nil))
+	if w.Code != http.StatusNoContent {
+		t.Errorf("DELETE without auth configured = %d, want %d", w.Code, http.StatusNoContent)
+	}
+}
@@ -0,0 +1,89 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Sergi-Ch/WB_L0_2025/domain"
+)
+
+// etag - сильный ETag представления заказа: версия заказа и вариант тела
+// (версия API, маска персональных данных), например "3-v2" или "3-v2-masked".
+// Одна версия заказа в разных представлениях дает разные ETag, поэтому кэш
+// не отдаст маскированное тело клиенту с ролью pii и наоборот.
+func etag(version int64, variant string) string {
+	return `"` + strconv.FormatInt(version, 10) + "-" + variant + `"`
+}
+
+// variant - вариант представления заказа для ETag
+func (h *OrderHandler) variant(r *http.Request) string {
+	v := versionOf(r).name
+	if !h.showPII(r) {
+		v += "-masked"
+	}
+	return v
+}
+
+// parseIfMatch разбирает If-Match с одним ETag заказа и возвращает версию
+// заказа из него: If-Match проверяет изменение заказа, а не представление,
+// поэтому подходит ETag любого варианта, а также "N" без варианта. Возвращает
+// 0 для "*" (любая версия) и ok=false для значения, которое не может совпасть
+// ни с одной версией: слабого ETag, списка или мусора.
+func parseIfMatch(header string) (version int64, ok bool) {
+	header = strings.TrimSpace(header)
+	if header == "*" {
+		return 0, true
+	}
+	unquoted, found := strings.CutPrefix(header, `"`)
+	if !found {
+		return 0, false
+	}
+	unquoted, found = strings.CutSuffix(unquoted, `"`)
+	if !found || strings.Contains(unquoted, `"`) {
+		return 0, false
+	}
+	unquoted, _, _ = strings.Cut(unquoted, "-")
+	version, err := strconv.ParseInt(unquoted, 10, 64)
+	if err != nil || version < 1 {
+		return 0, false
+	}
+	return version, true
+}
+
+// lastModified - время последнего изменения заказа с точностью
+// Last-Modified (секунды)
+func lastModified(order *domain.Order) time.Time {
+	t := order.DateCreated
+	if !order.UpdatedAt.IsZero() {
+		t = order.UpdatedAt
+	}
+	return t.UTC().Truncate(time.Second)
+}
+
+// setValidators выставляет ETag и Last-Modified заказа в представлении variant
+func setValidators(w http.ResponseWriter, order *domain.Order, variant string) {
+	w.Header().Set("ETag", etag(order.Version, variant))
+	w.Header().Set("Last-Modified", lastModified(order).Format(http.TimeFormat))
+}
+
+// notModified - условный GET (RFC 9110, 13.2.2): If-None-Match со слабым
+// сравнением ETag, а если его нет - If-Modified-Since
+func notModified(r *http.Request, order *domain.Order, variant string) bool {
+	if header := r.Header.Get("If-None-Match"); header != "" {
+		tag := etag(order.Version, variant)
+		for _, candidate := range strings.Split(header, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == tag {
+				return true
+			}
+		}
+		return false
+	}
+	if header := r.Header.Get("If-Modified-Since"); header != "" {
+		since, err := http.ParseTime(header)
+		return err == nil && !lastModified(order).After(since)
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Sergi-Ch/WB_L0_2025/domain"
+)
+
+func TestNotModified(t *testing.T) {
+	created := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	order := &domain.Order{Version: 3, DateCreated: created, UpdatedAt: created.Add(time.Hour + 500*time.Millisecond)}
+	modified := created.Add(time.Hour).Format(http.TimeFormat)
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    bool
+	}{
+		{"no conditions", nil, false},
+		{"same etag", map[string]string{"If-None-Match": `"3-v2"`}, true},
+		{"weak etag", map[string]string{"If-None-Match": `W/"3-v2"`}, true},
+		{"etag in list", map[string]string{"If-None-Match": `"1-v2", "3-v2"`}, true},
+		{"any etag", map[string]string{"If-None-Match": `*`}, true},
+		{"old etag", map[string]string{"If-None-Match": `"2-v2"`}, false},
+		{"other variant", map[string]string{"If-None-Match": `"3-v2-masked"`}, false},
+		{"version only", map[string]string{"If-None-Match": `"3"`}, false},
+		{"not modified since", map[string]string{"If-Modified-Since": modified}, true},
+		{"modified since", map[string]string{"If-Modified-Since": created.Format(http.TimeFormat)}, false},
+		{"invalid date", map[string]string{"If-Modified-Since": "yesterday"}, false},
+		// If-None-Match важнее If-Modified-Since
+		{"etag wins", map[string]string{"If-None-Match": `"2-v2"`, "If-Modified-Since": modified}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/order/o1", nil)
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			if got := notModified(r, order, "v2"); got != tt.want {
+				t.Errorf("notModified(%v) = %v, want %v", tt.headers, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		header  string
+		version int64
+		ok      bool
+	}{
+		{`"7"`, 7, true},
+		{` "7" `, 7, true},
+		{`"7-v2"`, 7, true},
+		{`"7-v1-masked"`, 7, true},
+		{`*`, 0, true},
+		{`W/"7"`, 0, false},
+		{`"1", "7"`, 0, false},
+		{`7`, 0, false},
+		{`"0"`, 0, false},
+		{`"abc"`, 0, false},
+		{`"-v2"`, 0, false},
+	}
+	for _, tt := range tests {
+		version, ok := parseIfMatch(tt.header)
+		if version != tt.version || ok != tt.ok {
+			t.Errorf("parseIfMatch(%q) = %d, %v, want %d, %v", tt.header, version, ok, tt.version, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package http
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/Sergi-Ch/WB_L0_2025/internal/logger"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/legacy"
+)
+
+// openAPISpecs - спецификации по версиям API (openapi/v1.json...), отдаются на
+// /api/{version}/openapi.json
+//
+//go:embed openapi/*.json
+var openAPISpecs embed.FS
+
+// apiVersions - версии API, для которых есть спецификация
+var apiVersions = []string{apiV1.name, apiV2.name}
+
+// OpenAPI разбирает и проверяет встроенную спецификацию версии API
+func OpenAPI(ctx context.Context, version string) (*openapi3.T, error) {
+	data, err := openAPISpecs.ReadFile("openapi/" + version + ".json")
+	if err != nil {
+		return nil, fmt.Errorf("load openapi spec %s: %w", version, err)
+	}
+	doc, err := openapi3.NewLoader().LoadFromData(data)
+	if err != nil {
+		return nil, fmt.Errorf("load openapi spec %s: %w", version, err)
+	}
+	if err := doc.Validate(ctx); err != nil {
+		return nil, fmt.Errorf("invalid openapi spec %s: %w", version, err)
+	}
+	return doc, nil
+}
+
+// GET /api/{version}/openapi.json
+func (h *OrderHandler) OpenAPISpec(w http.ResponseWriter, r *http.Request) {
+	data, err := openAPISpecs.ReadFile("openapi/" + versionOf(r).name + ".json")
+	if err != nil {
+		h.log.ErrorContext(r.Context(), "openapi spec is missing", logger.Err(err))
+		writeProblem(w, r, http.StatusInternalServerError, "openapi spec is missing")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// ValidateRequests - middleware, проверяющий запросы к описанным в спецификации
+// операциям (параметры, заголовки, тело): ошибка - 400, неописанный тип
+// тела - 415. Запросы вне спецификации (статика, метрики) пропускаются.
+func ValidateRequests(log *slog.Logger) (func(http.Handler) http.Handler, error) {
+	var specRouters []routers.Router
+	for _, version := range apiVersions {
+		doc, err := OpenAPI(context.Background(), version)
+		if err != nil {
+			return nil, err
+		}
+		router, err := legacy.NewRouter(doc)
+		if err != nil {
+			return nil, fmt.Errorf("build openapi router %s: %w", version, err)
+		}
+		specRouters = append(specRouters, router)
+	}
+	log = log.With(slog.String("component", "openapi"))
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route, params := findRoute(specRouters, r)
+			if route == nil {
+				// маршрут или метод не описан - решает роутер
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// ValidateRequest не отличает чужой Content-Type от прочих ошибок, 415 определяем сами
+			if body := route.Operation.RequestBody; body != nil && r.ContentLength != 0 &&
+				body.Value.Content.Get(mediaType(r)) == nil {
+				writeProblem(w, r, http.StatusUnsupportedMediaType, "unsupported content type "+mediaType(r))
+				return
+			}
+
+			input := &openapi3filter.RequestValidationInput{
+				Request:    r,
+				PathParams: params,
+				Route:      route,
+				Options:    &openapi3filter.Options{AuthenticationFunc: openapi3filter.NoopAuthenticationFunc},
+			}
+			if err := openapi3filter.ValidateRequest(r.Context(), input); err != nil {
+				log.DebugContext(r.Context(), "request does not match openapi spec",
+					slog.String("path", r.URL.Path), slog.String("error", err.Error()))
+				writeProblem(w, r, http.StatusBadRequest, err.Error())
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}, nil
+}
+
+// findRoute - операция первой спецификации, описывающей запрос
+func findRoute(specRouters []routers.Router, r *http.Request) (*routers.Route, map[string]string) {
+	for _, router := range specRouters {
+		if route, params, err := router.FindRoute(r); err == nil {
+			return route, params
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,182 @@
+package http
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/Sergi-Ch/WB_L0_2025/domain"
+	"github.com/go-chi/chi/v5"
+)
+
+// TestOpenAPIRoutes падает, если маршрут есть в RegisterRoutes, но не описан
+// в спецификации своей версии, или наоборот
+func TestOpenAPIRoutes(t *testing.T) {
+	var spec []string
+	for _, version := range apiVersions {
+		doc, err := OpenAPI(context.Background(), version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, server := range doc.Servers {
+			for path, item := range doc.Paths.Map() {
+				for method := range item.Operations() {
+					spec = append(spec, method+" "+strings.TrimSuffix(server.URL, "/")+path)
+				}
+			}
+		}
+	}
+
+	r := chi.NewRouter()
+	NewOrderHandler(nil, HandlerConfig{LegacyRoutes: true}, slog.New(slog.DiscardHandler)).RegisterRoutes(r)
+	var routes []string
+	err := chi.Walk(r, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		// веб-интерфейс не часть API
+		if route != "/" && route != "/ui" && route != "/ui/*" {
+			routes = append(routes, method+" "+route)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sort.Strings(spec)
+	sort.Strings(routes)
+	if !slices.Equal(spec, routes) {
+		t.Errorf("openapi operations differ from handler routes:\nspec:   %v\nroutes: %v", spec, routes)
+	}
+}
+
+// TestOpenAPISchemas сверяет поля схем с JSON-тегами типов, которые отдают и
+// принимают обработчики каждой версии
+func TestOpenAPISchemas(t *testing.T) {
+	common := map[string]any{
+		"Delivery":       domain.Delivery{},
+		"DeliveryPatch":  domain.Delivery{},
+		"Deletion":       domain.Deletion{},
+		"DeliveryChange": domain.DeliveryChange{},
+		"DeleteRequest":  deleteRequest{},
+		"Problem":        Problem{},
+	}
+	types := map[string]map[string]any{
+		"v1": {
+			"Order":   domain.Order{},
+			"Payment": domain.Payment{},
+			"Item":    domain.Item{},
+		},
+		"v2": {
+			"Order":       OrderV2{},
+			"OrderStatus": OrderStatusV2{},
+			"Money":       Money{},
+			"Payment":     PaymentV2{},
+			"Item":        ItemV2{},
+		},
+	}
+
+	for _, version := range apiVersions {
+		doc, err := OpenAPI(context.Background(), version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		schemas := maps.Clone(common)
+		maps.Copy(schemas, types[version])
+		for name := range doc.Components.Schemas {
+			if _, ok := schemas[name]; !ok {
+				t.Errorf("%s: schema %s has no Go type in the test", version, name)
+			}
+		}
+
+		for name, v := range schemas {
+			schema, ok := doc.Components.Schemas[name]
+			if !ok {
+				t.Errorf("%s: schema %s is missing", version, name)
+				continue
+			}
+			var props []string
+			for prop := range schema.Value.Properties {
+				props = append(props, prop)
+			}
+			fields := jsonFields(reflect.TypeOf(v))
+
+			sort.Strings(props)
+			sort.Strings(fields)
+			if !slices.Equal(props, fields) {
+				t.Errorf("%s: schema %s properties %v, %T fields %v", version, name, props, v, fields)
+			}
+		}
+	}
+}
+
+func jsonFields(typ reflect.Type) []string {
+	var fields []string
+	for i := range typ.NumField() {
+		name, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
+
+func TestValidateRequests(t *testing.T) {
+	validate, err := ValidateRequests(slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := validate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	order := `{"order_uid": "test-1", "track_number": "T1", "date_created": "2025-03-01T12:00:00Z",
+		"delivery": {}, "payment": {"amount": 1817}, "items": [{}]}`
+	orderV2 := `{"order_uid": "test-1", "track_number": "T1", "date_created": "2025-03-01T12:00:00Z",
+		"delivery": {}, "payment": {"amount": {"amount": "18.17", "currency": "USD"}}, "items": [{}]}`
+	tests := []struct {
+		name   string
+		method string
+		target string
+		ct     string
+		body   string
+		want   int
+	}{
+		{"valid get", http.MethodGet, "/api/v1/order/test-1?include_deleted=true", "", "", http.StatusTeapot},
+		{"invalid order uid", http.MethodGet, "/api/v2/order/test%201", "", "", http.StatusBadRequest},
+		{"invalid query", http.MethodGet, "/api/v2/order/test-1?include_deleted=maybe", "", "", http.StatusBadRequest},
+		{"valid order", http.MethodPost, "/api/v1/order", "application/json", order, http.StatusTeapot},
+		{"missing field", http.MethodPost, "/api/v1/order", "application/json", `{"order_uid": "test-1"}`, http.StatusBadRequest},
+		{"wrong type", http.MethodPost, "/api/v1/order", "application/json", strings.Replace(order, `"T1"`, `1`, 1), http.StatusBadRequest},
+		{"valid v2 order", http.MethodPost, "/api/v2/order", "application/json", orderV2, http.StatusTeapot},
+		{"v1 order in v2", http.MethodPost, "/api/v2/order", "application/json", order, http.StatusBadRequest},
+		{"legacy route", http.MethodPost, "/order", "application/json", order, http.StatusTeapot},
+		{"valid patch", http.MethodPatch, "/api/v2/order/test-1/delivery", "application/merge-patch+json", `{"city": null}`, http.StatusTeapot},
+		{"unknown patch field", http.MethodPatch, "/api/v2/order/test-1/delivery", "application/merge-patch+json", `{"country": "IL"}`, http.StatusBadRequest},
+		{"unsupported content type", http.MethodPatch, "/api/v2/order/test-1/delivery", "text/plain", `city`, http.StatusUnsupportedMediaType},
+		{"delete without body", http.MethodDelete, "/api/v2/order/test-1?reason=test", "", "", http.StatusTeapot},
+		{"not in spec", http.MethodGet, "/ui/index.html", "", "", http.StatusTeapot},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			if tt.ct != "" {
+				r.Header.Set("Content-Type", tt.ct)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+			if tt.want != http.StatusTeapot && w.Header().Get("Content-Type") != problemContentType {
+				t.Errorf("Content-Type = %q, want %q", w.Header().Get("Content-Type"), problemContentType)
+			}
+		})
+	}
+}
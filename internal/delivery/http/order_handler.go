@@ -3,85 +3,290 @@ package http
 import (
 	"embed"
 	"encoding/json"
+	"errors"
+	"io"
 	"io/fs"
-	"log"
+	"log/slog"
+	"mime"
 	"net/http"
+	"strconv"
+	"time"
 
-	"github.com/Sergi-Ch/WB_L0_2025/domain"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/auth"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/logger"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/pii"
 	"github.com/Sergi-Ch/WB_L0_2025/internal/service"
 	"github.com/go-chi/chi/v5"
 )
 
+// CacheControl - значения Cache-Control по маршрутам; пустое не отправляется
+type CacheControl struct {
+	Order   string
+	History string
+	Static  string
+}
+
+type HandlerConfig struct {
+	CacheControl CacheControl
+	// LegacyRoutes - дублировать /api/v1 маршрутами без префикса (/order/...)
+	LegacyRoutes bool
+	// V1Sunset - дата отключения /api/v1 для заголовка Sunset, нулевая - не объявлена
+	V1Sunset time.Time
+	// Auth - проверка API-ключей и JWT, nil - API открыт всем
+	Auth *auth.Authenticator
+	// PII - маскирование персональных данных для клиентов без роли pii,
+	// nil - данные отдаются полностью
+	PII *pii.Policy
+}
+
 type OrderHandler struct {
-	service *service.OrderService
+	service service.OrderServiceInterface
+	cfg     HandlerConfig
+	log     *slog.Logger
 }
 
-func NewOrderHandler(s *service.OrderService) *OrderHandler {
-	return &OrderHandler{service: s}
+func NewOrderHandler(s service.OrderServiceInterface, cfg HandlerConfig, log *slog.Logger) *OrderHandler {
+	return &OrderHandler{
+		service: s,
+		cfg:     cfg,
+		log:     log.With(slog.String("component", "http")),
+	}
 }
 
 //go:embed web/*
 var content embed.FS
 
 func (h *OrderHandler) RegisterRoutes(r chi.Router) {
-	// Фронт
+	// Фронт: только под /ui/, чтобы не перекрывать маршруты API
+	var static http.Handler
 	webFS, err := fs.Sub(content, "web")
 	if err != nil {
-		log.Printf("Failed to create sub filesystem: %v", err)
+		h.log.Warn("failed to create sub filesystem", logger.Err(err))
 
-		fs := http.FileServer(http.Dir("./internal/delivery/http/web"))
-		r.Handle("/*", fs)
-		log.Println("Serving static files with FileServer")
+		static = http.FileServer(http.Dir("./internal/delivery/http/web"))
+		h.log.Info("serving static files with FileServer")
 	} else {
-		fs := http.FileServer(http.FS(webFS))
-		r.Handle("/*", fs)
-		log.Println("Serving static files with embed")
+		static = http.FileServer(http.FS(webFS))
+		h.log.Info("serving static files with embed")
 	}
+	r.With(h.withCacheControl(h.cfg.CacheControl.Static)).Handle("/ui/*", http.StripPrefix("/ui", static))
+	r.Get("/ui", http.RedirectHandler("/ui/", http.StatusMovedPermanently).ServeHTTP)
+	r.Get("/", http.RedirectHandler("/ui/", http.StatusFound).ServeHTTP)
 
-	// endpoints
-	r.Get("/order/{order_uid}", h.GetOrderByID)
-	r.Post("/order", h.CreateOrder)
-
-	// Health check
-	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.Write([]byte("OK"))
+	// endpoints: версии отличаются форматом заказа, устаревшие отдают Deprecation
+	r.Route("/api/v1", func(r chi.Router) {
+		r.Use(withAPIVersion(apiV1), deprecated(v1DeprecatedAt, h.cfg.V1Sunset, "/api/v1", "/api/v2"), h.authenticate)
+		h.orderRoutes(r)
+	})
+	r.Route("/api/v2", func(r chi.Router) {
+		r.Use(withAPIVersion(apiV2), h.authenticate)
+		h.orderRoutes(r)
 	})
+	if h.cfg.LegacyRoutes {
+		r.Group(func(r chi.Router) {
+			r.Use(withAPIVersion(apiV1), deprecated(v1DeprecatedAt, h.cfg.V1Sunset, "", "/api/v1"), h.authenticate)
+			h.orderRoutes(r)
+		})
+	}
+}
+
+// orderRoutes - маршруты одной версии API с ролями, которые они требуют
+func (h *OrderHandler) orderRoutes(r chi.Router) {
+	reader, writer, admin := h.require(auth.RoleReader), h.require(auth.RoleWriter), h.require(auth.RoleAdmin)
+
+	r.With(reader, h.withCacheControl(h.cfg.CacheControl.Order)).Get("/order/{order_uid}", h.GetOrderByID)
+	r.With(writer).Post("/order", h.CreateOrder)
+	r.With(admin).Delete("/order/{order_uid}", h.DeleteOrder)
+	r.With(writer).Patch("/order/{order_uid}/delivery", h.UpdateDelivery)
+	r.With(reader, h.withCacheControl(h.cfg.CacheControl.History)).Get("/order/{order_uid}/delivery/history", h.DeliveryHistory)
+	r.Get("/openapi.json", h.OpenAPISpec)
 }
 
-// GET /order/{order_uid}
+// withCacheControl добавляет Cache-Control ко всем ответам маршрута
+func (h *OrderHandler) withCacheControl(value string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if value == "" {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", value)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// maxPatchBody - максимальный размер тела PATCH
+const maxPatchBody = 64 << 10
+
+// GET /order/{order_uid}[?include_deleted=true]. С If-None-Match или
+// If-Modified-Since, которым заказ соответствует, отвечает 304 без тела.
 func (h *OrderHandler) GetOrderByID(w http.ResponseWriter, r *http.Request) {
 	orderID := chi.URLParam(r, "order_uid")
-	log.Printf("Fetching order: %s", orderID)
+	h.log.DebugContext(r.Context(), "fetching order", slog.String("order_uid", orderID))
+
+	var opts service.GetOptions
+	if v := r.URL.Query().Get("include_deleted"); v != "" {
+		include, err := strconv.ParseBool(v)
+		if err != nil {
+			writeProblem(w, r, http.StatusBadRequest, "include_deleted must be a boolean")
+			return
+		}
+		opts.IncludeDeleted = include
+	}
+
+	order, err := h.service.GetOrderByID(r.Context(), orderID, opts)
+	if err != nil {
+		h.writeError(w, r, err, orderID, "failed to get order")
+		return
+	}
+
+	variant := h.variant(r)
+	setValidators(w, order, variant)
+	if notModified(r, order, variant) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	body, err := h.orderBody(r, order)
+	if err != nil {
+		h.writeError(w, r, err, orderID, "failed to get order")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		// заголовки уже отправлены, остается только записать в лог
+		h.log.ErrorContext(r.Context(), "json encoding error", slog.String("order_uid", orderID), logger.Err(err))
+	}
+}
+
+// deleteRequest - необязательное тело DELETE /order/{order_uid}; вместо него
+// можно передать параметры запроса reason и actor
+type deleteRequest struct {
+	Reason string `json:"reason"`
+	Actor  string `json:"actor"`
+}
+
+// DELETE /order/{order_uid} - мягкое удаление (отмена) заказа
+func (h *OrderHandler) DeleteOrder(w http.ResponseWriter, r *http.Request) {
+	orderID := chi.URLParam(r, "order_uid")
+
+	req := deleteRequest{Reason: r.URL.Query().Get("reason"), Actor: r.URL.Query().Get("actor")}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			writeProblem(w, r, http.StatusBadRequest, "invalid body: "+err.Error())
+			return
+		}
+	}
+
+	actor, ok := actorOf(r, req.Actor)
+	if !ok {
+		writeProblem(w, r, http.StatusForbidden, "actor must match the authenticated client")
+		return
+	}
+	if err := h.service.DeleteOrder(r.Context(), orderID, req.Reason, actor); err != nil {
+		h.writeError(w, r, err, orderID, "failed to delete order")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PATCH /order/{order_uid}/delivery - JSON merge patch доставки. If-Match с
+// ETag из GET обязателен: изменение, сделанное после чтения, дает 412.
+func (h *OrderHandler) UpdateDelivery(w http.ResponseWriter, r *http.Request) {
+	orderID := chi.URLParam(r, "order_uid")
 
-	order, err := h.service.GetOrderByID(r.Context(), orderID)
+	actor, ok := actorOf(r, r.URL.Query().Get("actor"))
+	if !ok {
+		writeProblem(w, r, http.StatusForbidden, "actor must match the authenticated client")
+		return
+	}
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		writeProblem(w, r, http.StatusPreconditionRequired, "If-Match header is required")
+		return
+	}
+	version, ok := parseIfMatch(ifMatch)
+	if !ok {
+		writeProblem(w, r, http.StatusPreconditionFailed, "If-Match must be a single strong ETag or *")
+		return
+	}
+	if ct := mediaType(r); ct != "application/merge-patch+json" && ct != "application/json" {
+		writeProblem(w, r, http.StatusUnsupportedMediaType, "content type must be application/merge-patch+json")
+		return
+	}
+	patch, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPatchBody))
 	if err != nil {
-		log.Printf("Order not found: %s, error: %v", orderID, err)
-		http.Error(w, "order not found", http.StatusNotFound)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeProblem(w, r, http.StatusRequestEntityTooLarge, err.Error())
+			return
+		}
+		writeProblem(w, r, http.StatusBadRequest, "invalid body: "+err.Error())
+		return
+	}
+
+	order, err := h.service.UpdateDelivery(r.Context(), orderID, patch, version, actor)
+	if err != nil {
+		h.writeError(w, r, err, orderID, "failed to update delivery")
+		return
+	}
+	body, err := h.orderBody(r, order)
+	if err != nil {
+		h.writeError(w, r, err, orderID, "failed to update delivery")
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	err = json.NewEncoder(w).Encode(order)
+	setValidators(w, order, h.variant(r))
+	json.NewEncoder(w).Encode(body)
+}
+
+// GET /order/{order_uid}/delivery/history
+func (h *OrderHandler) DeliveryHistory(w http.ResponseWriter, r *http.Request) {
+	orderID := chi.URLParam(r, "order_uid")
+
+	history, err := h.service.DeliveryHistory(r.Context(), orderID)
 	if err != nil {
-		log.Printf("JSON encoding error: %v", err)
-		http.Error(w, "error of encoding json", http.StatusInternalServerError)
+		h.writeError(w, r, err, orderID, "failed to get delivery history")
+		return
+	}
+	body, err := h.historyBody(r, history)
+	if err != nil {
+		h.writeError(w, r, err, orderID, "failed to get delivery history")
+		return
 	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(body)
+}
+
+// mediaType - Content-Type запроса без параметров
+func mediaType(r *http.Request) string {
+	ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return ct
 }
 
 // POST /order (для теста напрямую)
 func (h *OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
-	var order domain.Order
-	if err := json.NewDecoder(r.Body).Decode(&order); err != nil {
-		http.Error(w, "invalid body", http.StatusBadRequest)
+	version := versionOf(r)
+	order, err := version.decode(r.Body)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "invalid body: "+err.Error())
 		return
 	}
 
-	if err := h.service.SaveOrder(r.Context(), &order); err != nil {
-		log.Printf("failed to save order: %v", err)
-		http.Error(w, "failed to save order", http.StatusInternalServerError)
+	if err := h.service.SaveOrder(r.Context(), order); err != nil {
+		h.writeError(w, r, err, order.OrderUid, "failed to save order")
+		return
+	}
+	body, err := h.orderBody(r, order)
+	if err != nil {
+		h.writeError(w, r, err, order.OrderUid, "failed to save order")
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(order)
+	json.NewEncoder(w).Encode(body)
 }
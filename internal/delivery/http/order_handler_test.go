@@ -0,0 +1,169 @@
+package http
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Sergi-Ch/WB_L0_2025/internal/repository"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/service"
+	"github.com/go-chi/chi/v5"
+)
+
+func newTestRouter(svc *stubService) http.Handler {
+	r := chi.NewRouter()
+	NewOrderHandler(svc, HandlerConfig{}, slog.New(slog.DiscardHandler)).RegisterRoutes(r)
+	return r
+}
+
+func TestDeleteOrder(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		body   string
+		err    error
+		want   int
+		reason string
+		actor  string
+	}{
+		{"no reason", "/api/v2/order/test-1", "", nil, http.StatusNoContent, "", ""},
+		{"query", "/api/v2/order/test-1?reason=duplicate&actor=support", "", nil, http.StatusNoContent, "duplicate", "support"},
+		{"body", "/api/v2/order/test-1", `{"reason":"customer request","actor":"support"}`, nil, http.StatusNoContent, "customer request", "support"},
+		// тело важнее параметров запроса
+		{"body over query", "/api/v2/order/test-1?reason=duplicate", `{"reason":"customer request"}`, nil, http.StatusNoContent, "customer request", ""},
+		{"empty body keeps query", "/api/v2/order/test-1?reason=duplicate", `{}`, nil, http.StatusNoContent, "duplicate", ""},
+		{"invalid body", "/api/v2/order/test-1", `{"reason":`, nil, http.StatusBadRequest, "", ""},
+		{"not found", "/api/v2/order/test-1", "", fmt.Errorf("%w: test-1", repository.ErrNotFound), http.StatusNotFound, "", ""},
+		{"already deleted", "/api/v2/order/test-1", "", fmt.Errorf("%w: test-1", repository.ErrAlreadyDeleted), http.StatusConflict, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &stubService{err: tt.err}
+			var body io.Reader
+			if tt.body != "" {
+				body = strings.NewReader(tt.body)
+			}
+			req := httptest.NewRequest(http.MethodDelete, tt.path, body)
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			newTestRouter(svc).ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Fatalf("DELETE %s = %d, want %d: %s", tt.path, w.Code, tt.want, w.Body)
+			}
+			if tt.want != http.StatusNoContent {
+				if ct := w.Header().Get("Content-Type"); ct != problemContentType {
+					t.Errorf("Content-Type = %q, want %q", ct, problemContentType)
+				}
+				return
+			}
+			if w.Body.Len() != 0 {
+				t.Errorf("204 with body %q", w.Body)
+			}
+			if svc.reason != tt.reason || svc.actor != tt.actor {
+				t.Errorf("reason, actor = %q, %q, want %q, %q", svc.reason, svc.actor, tt.reason, tt.actor)
+			}
+		})
+	}
+}
+
+func TestGetDeletedOrder(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    int
+		include bool
+		state   string
+	}{
+		{"/api/v2/order/test-1", http.StatusNotFound, false, ""},
+		{"/api/v2/order/test-1?include_deleted=false", http.StatusNotFound, false, ""},
+		{"/api/v2/order/test-1?include_deleted=true", http.StatusOK, true, `"state":"cancelled"`},
+		{"/api/v1/order/test-1?include_deleted=1", http.StatusOK, true, `"deletion":{`},
+		{"/api/v2/order/test-1?include_deleted=maybe", http.StatusBadRequest, false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			svc := &stubService{deleted: true}
+			w := httptest.NewRecorder()
+			newTestRouter(svc).ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if w.Code != tt.want {
+				t.Fatalf("GET %s = %d, want %d: %s", tt.path, w.Code, tt.want, w.Body)
+			}
+			if svc.opts.IncludeDeleted != tt.include {
+				t.Errorf("IncludeDeleted = %v, want %v", svc.opts.IncludeDeleted, tt.include)
+			}
+			if tt.state != "" && !strings.Contains(w.Body.String(), tt.state) {
+				t.Errorf("body has no %s: %s", tt.state, w.Body)
+			}
+		})
+	}
+}
+
+func TestUpdateDelivery(t *testing.T) {
+	const patch = `{"city":"Tel Aviv","region":null}`
+	tests := []struct {
+		name        string
+		ifMatch     string
+		contentType string
+		body        string
+		err         error
+		want        int
+		version     int64
+	}{
+		{"ok", `"1-v2"`, "application/merge-patch+json", patch, nil, http.StatusOK, 1},
+		{"version only etag", `"1"`, "application/merge-patch+json", patch, nil, http.StatusOK, 1},
+		{"any version", `*`, "application/merge-patch+json; charset=utf-8", patch, nil, http.StatusOK, 0},
+		{"plain json", `"1-v2"`, "application/json", patch, nil, http.StatusOK, 1},
+		{"no if-match", "", "application/merge-patch+json", patch, nil, http.StatusPreconditionRequired, 0},
+		{"weak etag", `W/"1-v2"`, "application/merge-patch+json", patch, nil, http.StatusPreconditionFailed, 0},
+		{"etag list", `"1-v2", "2-v2"`, "application/merge-patch+json", patch, nil, http.StatusPreconditionFailed, 0},
+		{"stale version", `"1-v2"`, "application/merge-patch+json", patch,
+			fmt.Errorf("%w: test-1 has version 2, not 1", repository.ErrVersionConflict), http.StatusPreconditionFailed, 0},
+		{"wrong content type", `"1-v2"`, "text/plain", patch, nil, http.StatusUnsupportedMediaType, 0},
+		{"no content type", `"1-v2"`, "", patch, nil, http.StatusUnsupportedMediaType, 0},
+		{"too large", `"1-v2"`, "application/merge-patch+json", `{"city":"` + strings.Repeat("a", maxPatchBody) + `"}`, nil, http.StatusRequestEntityTooLarge, 0},
+		{"invalid patch", `"1-v2"`, "application/merge-patch+json", `{"order_uid":"other"}`,
+			fmt.Errorf("%w: unknown field", service.ErrInvalidPatch), http.StatusBadRequest, 0},
+		{"invalid delivery", `"1-v2"`, "application/merge-patch+json", `{"phone":null}`,
+			fmt.Errorf("%w: phone is required", service.ErrInvalidOrder), http.StatusUnprocessableEntity, 0},
+		{"not found", `"1-v2"`, "application/merge-patch+json", patch,
+			fmt.Errorf("%w: test-1", repository.ErrNotFound), http.StatusNotFound, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &stubService{err: tt.err}
+			req := httptest.NewRequest(http.MethodPatch, "/api/v2/order/test-1/delivery", strings.NewReader(tt.body))
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			w := httptest.NewRecorder()
+			newTestRouter(svc).ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Fatalf("PATCH = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+			if tt.want != http.StatusOK {
+				if ct := w.Header().Get("Content-Type"); ct != problemContentType {
+					t.Errorf("Content-Type = %q, want %q", ct, problemContentType)
+				}
+				if w.Header().Get("ETag") != "" {
+					t.Errorf("ETag %s on error response", w.Header().Get("ETag"))
+				}
+				return
+			}
+			if svc.version != tt.version || string(svc.patch) != tt.body {
+				t.Errorf("service got version %d, patch %s, want %d, %s", svc.version, svc.patch, tt.version, tt.body)
+			}
+			if etag := w.Header().Get("ETag"); etag != `"2-v2"` {
+				t.Errorf("ETag = %s, want the new version", etag)
+			}
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Sergi-Ch/WB_L0_2025/domain"
+)
+
+// Money - сумма в /api/v2: десятичная строка в основных единицах валюты
+// ("18.17" USD), в заказе хранится целым числом минимальных единиц (1817)
+type Money struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// OrderV2 - заказ в формате /api/v2: суммы - Money, состояние заказа
+// (версия, изменение, отмена) - в Status
+type OrderV2 struct {
+	OrderUid          string          `json:"order_uid"`
+	TrackNumber       string          `json:"track_number"`
+	Entry             string          `json:"entry"`
+	Delivery          domain.Delivery `json:"delivery"`
+	Payment           PaymentV2       `json:"payment"`
+	Items             []ItemV2        `json:"items"`
+	Locale            string          `json:"locale"`
+	InternalSignature string          `json:"internal_signature"`
+	CustomerId        string          `json:"customer_id"`
+	DeliveryService   string          `json:"delivery_service"`
+	Shardkey          string          `json:"shardkey"`
+	SmId              int             `json:"sm_id"`
+	DateCreated       time.Time       `json:"date_created"`
+	OofShard          string          `json:"oof_shard"`
+	// Status только в ответах, в POST игнорируется
+	Status OrderStatusV2 `json:"status"`
+}
+
+type OrderStatusV2 struct {
+	// State - active или cancelled
+	State        string           `json:"state"`
+	Version      int64            `json:"version"`
+	UpdatedAt    time.Time        `json:"updated_at,omitzero"`
+	Cancellation *domain.Deletion `json:"cancellation,omitempty"`
+}
+
+type PaymentV2 struct {
+	Transaction  string `json:"transaction"`
+	RequestId    string `json:"request_id"`
+	Provider     string `json:"provider"`
+	Amount       Money  `json:"amount"`
+	PaymentDt    int64  `json:"payment_dt"`
+	Bank         string `json:"bank"`
+	DeliveryCost Money  `json:"delivery_cost"`
+	GoodsTotal   Money  `json:"goods_total"`
+	CustomFee    Money  `json:"custom_fee"`
+}
+
+type ItemV2 struct {
+	ChrtId      int64  `json:"chrt_id"`
+	TrackNumber string `json:"track_number"`
+	Price       Money  `json:"price"`
+	Rid         string `json:"rid"`
+	Name        string `json:"name"`
+	Sale        int    `json:"sale"`
+	Size        string `json:"size"`
+	TotalPrice  Money  `json:"total_price"`
+	NmId        int64  `json:"nm_id"`
+	Brand       string `json:"brand"`
+	Status      int    `json:"status"`
+}
+
+const (
+	stateActive    = "active"
+	stateCancelled = "cancelled"
+)
+
+// newOrderV2 переводит заказ в формат /api/v2; все суммы - в валюте платежа
+func newOrderV2(order *domain.Order) OrderV2 {
+	currency := order.Payment.Currency
+	money := func(minor int64) Money { return Money{Amount: formatMoney(minor, currency), Currency: currency} }
+
+	status := OrderStatusV2{State: stateActive, Version: order.Version, UpdatedAt: order.UpdatedAt}
+	if order.Deletion != nil {
+		status.State = stateCancelled
+		status.Cancellation = order.Deletion
+	}
+
+	items := make([]ItemV2, 0, len(order.Items))
+	for _, item := range order.Items {
+		items = append(items, ItemV2{
+			ChrtId:      item.ChrtId,
+			TrackNumber: item.TrackNumber,
+			Price:       money(item.Price),
+			Rid:         item.Rid,
+			Name:        item.Name,
+			Sale:        item.Sale,
+			Size:        item.Size,
+			TotalPrice:  money(item.TotalPrice),
+			NmId:        item.NmId,
+			Brand:       item.Brand,
+			Status:      item.Status,
+		})
+	}
+
+	p := order.Payment
+	return OrderV2{
+		OrderUid:    order.OrderUid,
+		TrackNumber: order.TrackNumber,
+		Entry:       order.Entry,
+		Delivery:    order.Delivery,
+		Payment: PaymentV2{
+			Transaction:  p.Transaction,
+			RequestId:    p.RequestId,
+			Provider:     p.Provider,
+			Amount:       money(p.Amount),
+			PaymentDt:    p.PaymentDt,
+			Bank:         p.Bank,
+			DeliveryCost: money(p.DeliveryCost),
+			GoodsTotal:   money(p.GoodsTotal),
+			CustomFee:    money(p.CustomFee),
+		},
+		Items:             items,
+		Locale:            order.Locale,
+		InternalSignature: order.InternalSignature,
+		CustomerId:        order.CustomerId,
+		DeliveryService:   order.DeliveryService,
+		Shardkey:          order.Shardkey,
+		SmId:              order.SmId,
+		DateCreated:       order.DateCreated,
+		OofShard:          order.OofShard,
+		Status:            status,
+	}
+}
+
+// decodeOrderV2 читает заказ в формате /api/v2. Валюта заказа - валюта
+// payment.amount, остальные суммы должны быть в ней же.
+func decodeOrderV2(body io.Reader) (*domain.Order, error) {
+	var in OrderV2
+	if err := json.NewDecoder(body).Decode(&in); err != nil {
+		return nil, err
+	}
+
+	currency := in.Payment.Amount.Currency
+	var errs []error
+	minor := func(field string, m Money) int64 {
+		if m == (Money{}) {
+			return 0
+		}
+		if m.Currency != currency {
+			errs = append(errs, fmt.Errorf("%s: currency %q differs from payment currency %q", field, m.Currency, currency))
+			return 0
+		}
+		v, err := parseMoney(m.Amount, currency)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", field, err))
+		}
+		return v
+	}
+
+	p := in.Payment
+	order := &domain.Order{
+		OrderUid:    in.OrderUid,
+		TrackNumber: in.TrackNumber,
+		Entry:       in.Entry,
+		Delivery:    in.Delivery,
+		Payment: domain.Payment{
+			Transaction:  p.Transaction,
+			RequestId:    p.RequestId,
+			Currency:     currency,
+			Provider:     p.Provider,
+			Amount:       minor("payment.amount", p.Amount),
+			PaymentDt:    p.PaymentDt,
+			Bank:         p.Bank,
+			DeliveryCost: minor("payment.delivery_cost", p.DeliveryCost),
+			GoodsTotal:   minor("payment.goods_total", p.GoodsTotal),
+			CustomFee:    minor("payment.custom_fee", p.CustomFee),
+		},
+		Locale:            in.Locale,
+		InternalSignature: in.InternalSignature,
+		CustomerId:        in.CustomerId,
+		DeliveryService:   in.DeliveryService,
+		Shardkey:          in.Shardkey,
+		SmId:              in.SmId,
+		DateCreated:       in.DateCreated,
+		OofShard:          in.OofShard,
+	}
+	for i, item := range in.Items {
+		order.Items = append(order.Items, domain.Item{
+			ChrtId:      item.ChrtId,
+			TrackNumber: item.TrackNumber,
+			Price:       minor(fmt.Sprintf("items[%d].price", i), item.Price),
+			Rid:         item.Rid,
+			Name:        item.Name,
+			Sale:        item.Sale,
+			Size:        item.Size,
+			TotalPrice:  minor(fmt.Sprintf("items[%d].total_price", i), item.TotalPrice),
+			NmId:        item.NmId,
+			Brand:       item.Brand,
+			Status:      item.Status,
+		})
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// currencyExponent - число знаков после запятой у валют, где их не два (ISO 4217)
+var currencyExponent = map[string]int{
+	"BHD": 3, "CLP": 0, "IQD": 3, "ISK": 0, "JOD": 3, "JPY": 0,
+	"KRW": 0, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3, "VND": 0,
+}
+
+func exponent(currency string) int {
+	if e, ok := currencyExponent[strings.ToUpper(currency)]; ok {
+		return e
+	}
+	return 2
+}
+
+// formatMoney переводит минимальные единицы в десятичную строку: 1817 USD - "18.17"
+func formatMoney(minor int64, currency string) string {
+	e := exponent(currency)
+	s := strconv.FormatInt(minor, 10)
+	if e == 0 {
+		return s
+	}
+	sign := ""
+	if minor < 0 {
+		sign, s = "-", s[1:]
+	}
+	if len(s) <= e {
+		s = strings.Repeat("0", e-len(s)+1) + s
+	}
+	return sign + s[:len(s)-e] + "." + s[len(s)-e:]
+}
+
+var moneyAmount = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+// parseMoney - обратное к formatMoney; знаков после запятой не больше, чем у валюты
+func parseMoney(amount, currency string) (int64, error) {
+	if !moneyAmount.MatchString(amount) {
+		return 0, fmt.Errorf("invalid amount %q", amount)
+	}
+	e := exponent(currency)
+	whole, frac, _ := strings.Cut(amount, ".")
+	if len(frac) > e {
+		return 0, fmt.Errorf("amount %q has more than %d decimal places for %s", amount, e, currency)
+	}
+	v, err := strconv.ParseInt(whole+frac+strings.Repeat("0", e-len(frac)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("amount %q is out of range", amount)
+	}
+	return v, nil
+}
@@ -0,0 +1,137 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Sergi-Ch/WB_L0_2025/domain"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestMoney(t *testing.T) {
+	tests := []struct {
+		minor    int64
+		currency string
+		amount   string
+	}{
+		{1817, "USD", "18.17"},
+		{5, "RUB", "0.05"},
+		{0, "EUR", "0.00"},
+		{-250, "USD", "-2.50"},
+		{1500, "JPY", "1500"},
+		{1234, "KWD", "1.234"},
+	}
+	for _, tt := range tests {
+		if got := formatMoney(tt.minor, tt.currency); got != tt.amount {
+			t.Errorf("formatMoney(%d, %s) = %q, want %q", tt.minor, tt.currency, got, tt.amount)
+		}
+		if got, err := parseMoney(tt.amount, tt.currency); err != nil || got != tt.minor {
+			t.Errorf("parseMoney(%q, %s) = %d, %v, want %d", tt.amount, tt.currency, got, err, tt.minor)
+		}
+	}
+
+	if got, err := parseMoney("18.1", "USD"); err != nil || got != 1810 {
+		t.Errorf("parseMoney(18.1) = %d, %v, want 1810", got, err)
+	}
+	for _, amount := range []string{"", "18.", ".5", "1.234", "1e3", "+-1", "18,17", "99999999999999999999"} {
+		if _, err := parseMoney(amount, "USD"); err == nil {
+			t.Errorf("parseMoney(%q) succeeded", amount)
+		}
+	}
+}
+
+func TestOrderV2RoundTrip(t *testing.T) {
+	order := &domain.Order{
+		OrderUid:    "test-1",
+		TrackNumber: "T1",
+		Delivery:    domain.Delivery{Name: "Test", City: "Haifa"},
+		Payment: domain.Payment{
+			Transaction: "test-1", Currency: "USD", Amount: 1817,
+			DeliveryCost: 1500, GoodsTotal: 317,
+		},
+		Items:       []domain.Item{{ChrtId: 1, Name: "Mascaras", Price: 453, Sale: 30, TotalPrice: 317, Status: 202}},
+		DateCreated: time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC),
+		Version:     2,
+		UpdatedAt:   time.Date(2025, 3, 2, 12, 0, 0, 0, time.UTC),
+	}
+
+	v2 := newOrderV2(order)
+	if v2.Payment.Amount != (Money{Amount: "18.17", Currency: "USD"}) || v2.Items[0].Price.Amount != "4.53" {
+		t.Errorf("money = %+v, items[0].price = %+v", v2.Payment.Amount, v2.Items[0].Price)
+	}
+	if v2.Status.State != stateActive || v2.Status.Version != 2 || !v2.Status.UpdatedAt.Equal(order.UpdatedAt) {
+		t.Errorf("status = %+v", v2.Status)
+	}
+
+	body, err := json.Marshal(v2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := decodeOrderV2(bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// состояние в POST не принимается
+	want := *order
+	want.Version, want.UpdatedAt = 0, time.Time{}
+	if !reflect.DeepEqual(*got, want) {
+		t.Errorf("decodeOrderV2 = %+v, want %+v", *got, want)
+	}
+
+	cancelled := *order
+	cancelled.Deletion = &domain.Deletion{At: order.UpdatedAt, Reason: "test"}
+	if st := newOrderV2(&cancelled).Status; st.State != stateCancelled || st.Cancellation == nil {
+		t.Errorf("cancelled status = %+v", st)
+	}
+
+	mixed := strings.Replace(string(body), `"amount":"4.53","currency":"USD"`, `"amount":"4.53","currency":"EUR"`, 1)
+	if _, err := decodeOrderV2(strings.NewReader(mixed)); err == nil {
+		t.Error("decodeOrderV2 accepted an item price in another currency")
+	}
+}
+
+func TestAPIVersionRoutes(t *testing.T) {
+	sunset := time.Date(2027, 1, 31, 0, 0, 0, 0, time.UTC)
+	r := chi.NewRouter()
+	NewOrderHandler(nil, HandlerConfig{LegacyRoutes: true, V1Sunset: sunset}, slog.New(slog.DiscardHandler)).RegisterRoutes(r)
+
+	tests := []struct {
+		path      string
+		code      int
+		successor string
+	}{
+		{"/api/v2/openapi.json", http.StatusOK, ""},
+		{"/api/v1/openapi.json", http.StatusOK, "</api/v2/openapi.json>"},
+		{"/openapi.json", http.StatusOK, "</api/v1/openapi.json>"},
+		{"/", http.StatusFound, ""},
+		{"/ui/", http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+		if w.Code != tt.code {
+			t.Errorf("GET %s = %d, want %d", tt.path, w.Code, tt.code)
+		}
+		link := w.Header().Get("Link")
+		if tt.successor == "" {
+			if d := w.Header().Get("Deprecation"); d != "" || link != "" {
+				t.Errorf("GET %s: Deprecation %q, Link %q on a current route", tt.path, d, link)
+			}
+			continue
+		}
+		if w.Header().Get("Deprecation") != "@1792368000" || w.Header().Get("Sunset") != "Sun, 31 Jan 2027 00:00:00 GMT" {
+			t.Errorf("GET %s: Deprecation %q, Sunset %q", tt.path, w.Header().Get("Deprecation"), w.Header().Get("Sunset"))
+		}
+		if link != tt.successor+`; rel="successor-version"` {
+			t.Errorf("GET %s: Link %q, want successor %s", tt.path, link, tt.successor)
+		}
+	}
+}
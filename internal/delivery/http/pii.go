@@ -0,0 +1,32 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/Sergi-Ch/WB_L0_2025/domain"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/auth"
+)
+
+// showPII - отдавать ли персональные данные полностью: маскирование
+// выключено или у клиента есть роль pii
+func (h *OrderHandler) showPII(r *http.Request) bool {
+	return h.cfg.PII == nil || auth.PrincipalFrom(r.Context()).Has(auth.RolePII)
+}
+
+// orderBody - заказ в формате версии запроса, без персональных данных для
+// клиентов без роли pii
+func (h *OrderHandler) orderBody(r *http.Request, order *domain.Order) (any, error) {
+	body := versionOf(r).encode(order)
+	if h.showPII(r) {
+		return body, nil
+	}
+	return h.cfg.PII.Order(body)
+}
+
+// historyBody - то же для истории доставки
+func (h *OrderHandler) historyBody(r *http.Request, history []domain.DeliveryChange) (any, error) {
+	if h.showPII(r) {
+		return history, nil
+	}
+	return h.cfg.PII.History(history)
+}
@@ -0,0 +1,133 @@
+package http
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Sergi-Ch/WB_L0_2025/internal/auth"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/pii"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestPIIMasking(t *testing.T) {
+	authenticator, err := auth.NewAuthenticator([]auth.APIKey{
+		{Name: "support", Hash: auth.HashAPIKey("admin-key"), Roles: []auth.Role{auth.RoleAdmin}},
+		{Name: "billing", Hash: auth.HashAPIKey("pii-key"), Roles: []auth.Role{auth.RoleReader, auth.RolePII}},
+	}, auth.JWTConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy, err := pii.NewPolicy(map[string]string{
+		"delivery.phone": pii.ModeMask,
+		"delivery.email": pii.ModeMask,
+		"payment.bank":   pii.ModeOmit,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	withAuth := chi.NewRouter()
+	NewOrderHandler(&stubService{}, HandlerConfig{Auth: authenticator, PII: policy}, slog.New(slog.DiscardHandler)).RegisterRoutes(withAuth)
+	noAuth := chi.NewRouter()
+	NewOrderHandler(&stubService{}, HandlerConfig{PII: policy}, slog.New(slog.DiscardHandler)).RegisterRoutes(noAuth)
+
+	tests := []struct {
+		name   string
+		router http.Handler
+		path   string
+		key    string
+		want   []string
+		absent []string
+	}{
+		{"admin without pii, v2", withAuth, "/api/v2/order/test-1", "admin-key",
+			[]string{`"phone":"+7999***2233"`, `"email":"t***@gmail.com"`, `"name":"Test Testov"`, `"amount":"18.17"`}, []string{`"bank"`}},
+		{"admin without pii, v1", withAuth, "/api/v1/order/test-1", "admin-key",
+			[]string{`"phone":"+7999***2233"`, `"amount":1817`}, []string{`"bank"`}},
+		{"pii role", withAuth, "/api/v2/order/test-1", "pii-key",
+			[]string{`"phone":"+79991112233"`, `"email":"test@gmail.com"`, `"bank":"alpha"`}, nil},
+		{"history", withAuth, "/api/v2/order/test-1/delivery/history", "admin-key",
+			[]string{`"phone":"+7999***2233"`, `"phone":"+7999***5566"`}, []string{"+79991112233"}},
+		{"auth disabled", noAuth, "/api/v2/order/test-1", "",
+			[]string{`"phone":"+7999***2233"`}, []string{`"bank"`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.key != "" {
+				req.Header.Set(apiKeyHeader, tt.key)
+			}
+			w := httptest.NewRecorder()
+			tt.router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("GET %s = %d: %s", tt.path, w.Code, w.Body)
+			}
+			if !json.Valid(w.Body.Bytes()) {
+				t.Fatalf("invalid JSON: %s", w.Body)
+			}
+			body := w.Body.String()
+			for _, s := range tt.want {
+				if !strings.Contains(body, s) {
+					t.Errorf("body has no %s: %s", s, body)
+				}
+			}
+			for _, s := range tt.absent {
+				if strings.Contains(body, s) {
+					t.Errorf("body has %s: %s", s, body)
+				}
+			}
+			if !strings.Contains(w.Header().Get("Vary"), apiKeyHeader) {
+				t.Errorf("Vary = %q, want credentials headers", w.Header().Get("Vary"))
+			}
+		})
+	}
+}
+
+func TestPIIETag(t *testing.T) {
+	authenticator, err := auth.NewAuthenticator([]auth.APIKey{
+		{Name: "support", Hash: auth.HashAPIKey("admin-key"), Roles: []auth.Role{auth.RoleAdmin}},
+		{Name: "billing", Hash: auth.HashAPIKey("pii-key"), Roles: []auth.Role{auth.RoleReader, auth.RolePII}},
+	}, auth.JWTConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy, err := pii.NewPolicy(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := chi.NewRouter()
+	NewOrderHandler(&stubService{}, HandlerConfig{Auth: authenticator, PII: policy}, slog.New(slog.DiscardHandler)).RegisterRoutes(r)
+
+	get := func(path, key, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set(apiKeyHeader, key)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	masked := get("/api/v2/order/test-1", "admin-key", "").Header().Get("ETag")
+	full := get("/api/v2/order/test-1", "pii-key", "").Header().Get("ETag")
+	v1 := get("/api/v1/order/test-1", "pii-key", "").Header().Get("ETag")
+	if masked == full || full == v1 || masked == "" {
+		t.Fatalf("ETag masked = %s, full = %s, v1 = %s, want distinct", masked, full, v1)
+	}
+
+	// маскированная копия не подтверждается клиенту с ролью pii
+	if w := get("/api/v2/order/test-1", "pii-key", masked); w.Code != http.StatusOK {
+		t.Errorf("If-None-Match with masked ETag for pii client = %d, want %d", w.Code, http.StatusOK)
+	}
+	if w := get("/api/v2/order/test-1", "admin-key", masked); w.Code != http.StatusNotModified {
+		t.Errorf("If-None-Match with own ETag = %d, want %d", w.Code, http.StatusNotModified)
+	}
+	// If-Match проверяет версию заказа, подходит ETag любого представления
+	if version, ok := parseIfMatch(masked); !ok || version != 1 {
+		t.Errorf("parseIfMatch(%s) = %d, %v", masked, version, ok)
+	}
+}
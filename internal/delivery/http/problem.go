@@ -0,0 +1,83 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/Sergi-Ch/WB_L0_2025/internal/logger"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/repository"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/service"
+)
+
+// problemContentType - тип ответа с ошибкой (RFC 7807)
+const problemContentType = "application/problem+json"
+
+// retryAfter - через сколько секунд повторять запрос при 503
+const retryAfter = "5"
+
+// Problem - тело ответа с ошибкой (RFC 7807). RequestID совпадает с
+// X-Request-ID и логами запроса.
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// writeProblem отвечает ошибкой status с пояснением detail
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	w.Header().Set("Content-Type", problemContentType)
+	// ошибки не кэшируются, даже если маршрут разрешает кэш
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Del("ETag")
+	w.Header().Del("Last-Modified")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  r.URL.Path,
+		RequestID: logger.RequestID(r.Context()),
+	})
+}
+
+// errorStatus - HTTP-статус для ошибки сервиса или репозитория
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrInvalidOrderID), errors.Is(err, service.ErrInvalidPatch):
+		return http.StatusBadRequest
+	case errors.Is(err, repository.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, repository.ErrAlreadyExists), errors.Is(err, repository.ErrAlreadyDeleted):
+		return http.StatusConflict
+	case errors.Is(err, repository.ErrVersionConflict):
+		// версия приходит в If-Match, поэтому конфликт - невыполненное условие
+		return http.StatusPreconditionFailed
+	case errors.Is(err, service.ErrInvalidOrder):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, service.ErrUnavailable):
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// writeError отвечает на ошибку сервиса по заказу orderID. Для 5xx текст
+// ошибки остается в логе, клиент получает только msg.
+func (h *OrderHandler) writeError(w http.ResponseWriter, r *http.Request, err error, orderID, msg string) {
+	status := errorStatus(err)
+	if status < http.StatusInternalServerError {
+		writeProblem(w, r, status, err.Error())
+		return
+	}
+
+	h.log.ErrorContext(r.Context(), msg, slog.String("order_uid", orderID), logger.Err(err))
+	if status == http.StatusServiceUnavailable {
+		w.Header().Set("Retry-After", retryAfter)
+	}
+	writeProblem(w, r, status, msg)
+}
@@ -0,0 +1,68 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Sergi-Ch/WB_L0_2025/internal/logger"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/repository"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/service"
+)
+
+func TestErrorStatus(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{fmt.Errorf("%w: order id is required", service.ErrInvalidOrderID), http.StatusBadRequest},
+		{fmt.Errorf("%w: unexpected EOF", service.ErrInvalidPatch), http.StatusBadRequest},
+		{fmt.Errorf("%w: test-1", repository.ErrNotFound), http.StatusNotFound},
+		{fmt.Errorf("%w: test-1", repository.ErrAlreadyExists), http.StatusConflict},
+		{fmt.Errorf("%w: test-1", repository.ErrAlreadyDeleted), http.StatusConflict},
+		{fmt.Errorf("%w: test-1", repository.ErrVersionConflict), http.StatusPreconditionFailed},
+		{fmt.Errorf("%w: phone is required", service.ErrInvalidOrder), http.StatusUnprocessableEntity},
+		{fmt.Errorf("%w: %w", service.ErrUnavailable, context.DeadlineExceeded), http.StatusServiceUnavailable},
+		{errors.New("boom"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		if got := errorStatus(tt.err); got != tt.want {
+			t.Errorf("errorStatus(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestWriteProblem(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/order/test-1", nil)
+	r = r.WithContext(logger.WithRequestID(r.Context(), "req-1"))
+	w := httptest.NewRecorder()
+	w.Header().Set("ETag", `"1"`)
+
+	writeProblem(w, r, http.StatusNotFound, "order not found: test-1")
+
+	if ct := w.Header().Get("Content-Type"); ct != problemContentType {
+		t.Errorf("Content-Type = %q, want %q", ct, problemContentType)
+	}
+	if etag := w.Header().Get("ETag"); etag != "" {
+		t.Errorf("ETag = %q on error response", etag)
+	}
+	var got Problem
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	want := Problem{
+		Type:      "about:blank",
+		Title:     "Not Found",
+		Status:    http.StatusNotFound,
+		Detail:    "order not found: test-1",
+		Instance:  "/order/test-1",
+		RequestID: "req-1",
+	}
+	if w.Code != http.StatusNotFound || got != want {
+		t.Errorf("problem = %d %+v, want %+v", w.Code, got, want)
+	}
+}
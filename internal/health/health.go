@@ -0,0 +1,181 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusUp       Status = "up"
+	StatusDegraded Status = "degraded"
+	StatusDown     Status = "down"
+)
+
+// Checker проверяет состояние одной зависимости
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc позволяет использовать функцию как Checker
+type CheckerFunc func(ctx context.Context) error
+
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Check описывает зарегистрированную проверку
+type Check struct {
+	Name    string
+	Checker Checker
+	// Timeout на одну проверку, 0 - таймаут реестра по умолчанию
+	Timeout time.Duration
+	// Critical - при падении сервис не готов (down). Падение некритичной
+	// проверки (например, кэша) переводит сервис только в degraded.
+	Critical bool
+}
+
+// Result - результат одной проверки
+type Result struct {
+	Status    Status    `json:"status"`
+	Critical  bool      `json:"critical"`
+	Error     string    `json:"error,omitempty"`
+	Duration  string    `json:"duration"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Report - сводный ответ /readyz
+type Report struct {
+	Status Status            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// Registry хранит проверки и кэширует их результаты на cacheTTL,
+// чтобы частые пробы не нагружали зависимости.
+type Registry struct {
+	timeout  time.Duration
+	cacheTTL time.Duration
+
+	mu      sync.Mutex
+	checks  []Check
+	results map[string]Result
+}
+
+func NewRegistry(timeout, cacheTTL time.Duration) *Registry {
+	return &Registry{
+		timeout:  timeout,
+		cacheTTL: cacheTTL,
+		results:  make(map[string]Result),
+	}
+}
+
+func (r *Registry) Register(c Check) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks = append(r.checks, c)
+}
+
+// Run выполняет все проверки параллельно (или берет свежие из кэша)
+func (r *Registry) Run(ctx context.Context) Report {
+	r.mu.Lock()
+	checks := append([]Check(nil), r.checks...)
+	r.mu.Unlock()
+
+	report := Report{Status: StatusUp, Checks: make(map[string]Result, len(checks))}
+
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+	for _, c := range checks {
+		wg.Add(1)
+		go func(c Check) {
+			defer wg.Done()
+			res := r.result(ctx, c)
+
+			mu.Lock()
+			report.Checks[c.Name] = res
+			mu.Unlock()
+		}(c)
+	}
+	wg.Wait()
+
+	for _, res := range report.Checks {
+		if res.Status == StatusUp {
+			continue
+		}
+		if res.Critical {
+			report.Status = StatusDown
+		} else if report.Status == StatusUp {
+			report.Status = StatusDegraded
+		}
+	}
+	return report
+}
+
+func (r *Registry) result(ctx context.Context, c Check) Result {
+	r.mu.Lock()
+	cached, ok := r.results[c.Name]
+	r.mu.Unlock()
+	if ok && time.Since(cached.CheckedAt) < r.cacheTTL {
+		return cached
+	}
+
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = r.timeout
+	}
+	checkCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := c.Checker.Check(checkCtx)
+	if err == nil && errors.Is(checkCtx.Err(), context.DeadlineExceeded) {
+		err = checkCtx.Err()
+	}
+
+	res := Result{
+		Status:    StatusUp,
+		Critical:  c.Critical,
+		Duration:  time.Since(start).String(),
+		CheckedAt: start,
+	}
+	if err != nil {
+		res.Status = StatusDown
+		res.Error = err.Error()
+	}
+
+	r.mu.Lock()
+	r.results[c.Name] = res
+	r.mu.Unlock()
+	return res
+}
+
+// LiveHandler - GET /livez: процесс жив и обслуживает запросы,
+// зависимости не проверяются, чтобы их падение не приводило к рестартам
+func (r *Registry) LiveHandler(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]Status{"status": StatusUp})
+}
+
+// ReadyHandler - GET /readyz: 200 для up/degraded, 503 для down
+func (r *Registry) ReadyHandler(w http.ResponseWriter, req *http.Request) {
+	report := r.Run(req.Context())
+
+	code := http.StatusOK
+	if report.Status == StatusDown {
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, report)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
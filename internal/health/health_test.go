@@ -0,0 +1,126 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var (
+	ok   = CheckerFunc(func(context.Context) error { return nil })
+	fail = CheckerFunc(func(context.Context) error { return errors.New("connection refused") })
+	// hang ждет отмены контекста, но возвращает nil: таймаут должен
+	// засчитаться и без ошибки от самой проверки
+	hang = CheckerFunc(func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+)
+
+func TestReadyHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		checks []Check
+		status Status
+		code   int
+	}{
+		{"no checks", nil, StatusUp, http.StatusOK},
+		{"all up", []Check{{Name: "db", Checker: ok, Critical: true}, {Name: "cache", Checker: ok}}, StatusUp, http.StatusOK},
+		{"non-critical down", []Check{{Name: "db", Checker: ok, Critical: true}, {Name: "cache", Checker: fail}}, StatusDegraded, http.StatusOK},
+		{"critical down", []Check{{Name: "db", Checker: fail, Critical: true}, {Name: "cache", Checker: ok}}, StatusDown, http.StatusServiceUnavailable},
+		{"critical wins", []Check{{Name: "db", Checker: fail, Critical: true}, {Name: "cache", Checker: fail}}, StatusDown, http.StatusServiceUnavailable},
+		{"critical timeout", []Check{{Name: "db", Checker: hang, Critical: true, Timeout: 10 * time.Millisecond}}, StatusDown, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg := NewRegistry(time.Second, 0)
+			for _, c := range tt.checks {
+				reg.Register(c)
+			}
+			w := httptest.NewRecorder()
+			reg.ReadyHandler(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			var report Report
+			if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+				t.Fatal(err)
+			}
+			if w.Code != tt.code || report.Status != tt.status {
+				t.Errorf("/readyz = %d %s, want %d %s", w.Code, report.Status, tt.code, tt.status)
+			}
+			if len(report.Checks) != len(tt.checks) {
+				t.Errorf("report has %d checks, want %d", len(report.Checks), len(tt.checks))
+			}
+		})
+	}
+}
+
+func TestCheckTimeout(t *testing.T) {
+	reg := NewRegistry(time.Hour, 0)
+	reg.Register(Check{Name: "slow", Checker: hang, Timeout: 10 * time.Millisecond})
+
+	start := time.Now()
+	report := reg.Run(context.Background())
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Run took %s, per-check timeout ignored", elapsed)
+	}
+	res := report.Checks["slow"]
+	if res.Status != StatusDown || res.Error != context.DeadlineExceeded.Error() {
+		t.Errorf("slow check = %+v, want down with deadline exceeded", res)
+	}
+	if report.Status != StatusDegraded {
+		t.Errorf("status = %s, want %s", report.Status, StatusDegraded)
+	}
+
+	// без своего таймаута - таймаут реестра
+	reg = NewRegistry(10*time.Millisecond, 0)
+	reg.Register(Check{Name: "slow", Checker: hang})
+	if res := reg.Run(context.Background()).Checks["slow"]; res.Status != StatusDown {
+		t.Errorf("slow check with registry timeout = %+v", res)
+	}
+}
+
+func TestResultCache(t *testing.T) {
+	var calls atomic.Int32
+	var failing atomic.Bool
+	counted := CheckerFunc(func(context.Context) error {
+		calls.Add(1)
+		if failing.Load() {
+			return errors.New("down")
+		}
+		return nil
+	})
+
+	reg := NewRegistry(time.Second, time.Hour)
+	reg.Register(Check{Name: "db", Checker: counted, Critical: true})
+	reg.Run(context.Background())
+	failing.Store(true)
+	// в пределах cacheTTL - прежний результат без вызова проверки
+	if report := reg.Run(context.Background()); report.Status != StatusUp || calls.Load() != 1 {
+		t.Errorf("cached run: status %s, %d calls, want up and 1 call", report.Status, calls.Load())
+	}
+
+	reg = NewRegistry(time.Second, 0)
+	reg.Register(Check{Name: "db", Checker: counted, Critical: true})
+	calls.Store(0)
+	reg.Run(context.Background())
+	if report := reg.Run(context.Background()); report.Status != StatusDown || calls.Load() != 2 {
+		t.Errorf("run without cache: status %s, %d calls, want down and 2 calls", report.Status, calls.Load())
+	}
+}
+
+func TestLiveHandler(t *testing.T) {
+	reg := NewRegistry(time.Second, 0)
+	reg.Register(Check{Name: "db", Checker: fail, Critical: true})
+
+	w := httptest.NewRecorder()
+	reg.LiveHandler(w, httptest.NewRequest(http.MethodGet, "/livez", nil))
+	// зависимости на /livez не влияют
+	if w.Code != http.StatusOK {
+		t.Errorf("/livez = %d, want %d", w.Code, http.StatusOK)
+	}
+}
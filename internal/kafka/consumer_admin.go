@@ -0,0 +1,336 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Sergi-Ch/WB_L0_2025/internal/logger"
+	"github.com/segmentio/kafka-go"
+)
+
+// ConsumerState - состояние consumer'а, управляется через admin API
+type ConsumerState string
+
+const (
+	ConsumerRunning  ConsumerState = "running"
+	ConsumerPaused   ConsumerState = "paused"   // не читает, но остается в группе
+	ConsumerDraining ConsumerState = "draining" // дорабатывает текущее сообщение
+	ConsumerDrained  ConsumerState = "drained"  // reader закрыт, партиции отданы группе
+)
+
+// ErrConsumerState - операция недопустима в текущем состоянии
+var ErrConsumerState = errors.New("invalid consumer state")
+
+// ConsumerStatus - ответ admin API о состоянии consumer'а
+type ConsumerStatus struct {
+	State      ConsumerState     `json:"state"`
+	Since      time.Time         `json:"since"`
+	Topic      string            `json:"topic"`
+	GroupID    string            `json:"group_id"`
+	ClientID   string            `json:"client_id"`
+	Handled    int64             `json:"handled"`
+	Failed     int64             `json:"failed"`
+	LastError  *ConsumerError    `json:"last_error,omitempty"`
+	Lag        int64             `json:"lag"`
+	Partitions []PartitionStatus `json:"partitions"`
+	// BrokerError - не удалось получить офсеты от брокера,
+	// локальная часть статуса при этом актуальна
+	BrokerError string `json:"broker_error,omitempty"`
+}
+
+type ConsumerError struct {
+	Message string    `json:"message"`
+	At      time.Time `json:"at"`
+}
+
+// PartitionStatus - партиция топика с точки зрения группы
+type PartitionStatus struct {
+	Partition int  `json:"partition"`
+	Assigned  bool `json:"assigned"` // назначена этому экземпляру
+	// CommittedOffset - следующий офсет для группы, -1 если коммитов не было
+	CommittedOffset int64 `json:"committed_offset"`
+	HighWatermark   int64 `json:"high_watermark"`
+	Lag             int64 `json:"lag"`
+}
+
+// Pause останавливает чтение новых сообщений. Текущее сообщение
+// дообрабатывается и коммитится, consumer остается в группе.
+func (c *Consumer) Pause() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch c.state {
+	case ConsumerPaused:
+		return nil
+	case ConsumerRunning:
+		c.setState(ConsumerPaused)
+		c.interruptFetch()
+		c.log.Info("kafka consumer paused")
+		return nil
+	default:
+		return fmt.Errorf("%w: cannot pause %s consumer", ErrConsumerState, c.state)
+	}
+}
+
+// Resume возобновляет чтение. После drain создается новый reader,
+// который заново вступает в группу.
+func (c *Consumer) Resume() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch c.state {
+	case ConsumerRunning:
+		return nil
+	case ConsumerDrained:
+		c.reader = c.newReader(c.readerConfig)
+		fallthrough
+	case ConsumerPaused:
+		c.setState(ConsumerRunning)
+		c.log.Info("kafka consumer resumed")
+		return nil
+	default:
+		return fmt.Errorf("%w: cannot resume %s consumer", ErrConsumerState, c.state)
+	}
+}
+
+// Drain дожидается обработки текущего сообщения, коммитит его и закрывает
+// reader, отдавая партиции другим экземплярам группы. Ждет до отмены ctx;
+// если ctx истек раньше, drain продолжается в фоне.
+func (c *Consumer) Drain(ctx context.Context) error {
+	c.mu.Lock()
+	switch c.state {
+	case ConsumerRunning, ConsumerPaused:
+		c.setState(ConsumerDraining)
+		c.interruptFetch()
+		c.log.Info("kafka consumer draining")
+	}
+
+	for c.state != ConsumerDrained {
+		changed := c.changed
+		c.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+		c.mu.Lock()
+	}
+	c.mu.Unlock()
+	return nil
+}
+
+// waitRunning блокируется, пока consumer не в состоянии running, и
+// выполняет drain, когда его запросили. Возвращает reader и контекст
+// чтения, который отменяется при pause/drain.
+func (c *Consumer) waitRunning(ctx context.Context) (MessageReader, context.Context, context.CancelFunc, error) {
+	c.mu.Lock()
+	for {
+		switch c.state {
+		case ConsumerRunning:
+			fetchCtx, cancel := context.WithCancel(ctx)
+			c.stopFetch = cancel
+			reader := c.reader
+			c.mu.Unlock()
+			return reader, fetchCtx, cancel, nil
+		case ConsumerDraining:
+			reader := c.reader
+			c.reader = nil
+			c.mu.Unlock()
+
+			// офсеты уже закоммичены после обработки, Close только выходит из группы
+			err := reader.Close()
+
+			c.mu.Lock()
+			if err != nil {
+				c.setErrorLocked(err)
+				c.log.Error("failed to close kafka reader on drain", logger.Err(err))
+			}
+			c.setState(ConsumerDrained)
+			c.log.Info("kafka consumer drained")
+			continue
+		}
+
+		changed := c.changed
+		c.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return nil, nil, nil, ctx.Err()
+		case <-changed:
+		}
+		c.mu.Lock()
+	}
+}
+
+// interruptFetch прерывает ожидание FetchMessage в цикле Start. Вызывать под c.mu.
+func (c *Consumer) interruptFetch() {
+	if c.stopFetch != nil {
+		c.stopFetch()
+	}
+}
+
+// setState меняет состояние и будит всех, кто его ждет. Вызывать под c.mu.
+func (c *Consumer) setState(s ConsumerState) {
+	c.state = s
+	c.since = time.Now()
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+func (c *Consumer) setError(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.setErrorLocked(err)
+}
+
+func (c *Consumer) setErrorLocked(err error) {
+	c.lastErr = err
+	c.lastErrAt = time.Now()
+}
+
+func (c *Consumer) recordHandled(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.handled++
+	if err != nil {
+		c.failed++
+		c.setErrorLocked(err)
+	}
+}
+
+// Status возвращает состояние consumer'а, назначенные партиции, закоммиченные
+// офсеты и лаг группы. Ошибка брокера не прерывает ответ, а попадает в BrokerError.
+func (c *Consumer) Status(ctx context.Context) ConsumerStatus {
+	c.mu.Lock()
+	st := ConsumerStatus{
+		State:      c.state,
+		Since:      c.since,
+		Topic:      c.readerConfig.Topic,
+		GroupID:    c.readerConfig.GroupID,
+		ClientID:   c.readerConfig.Dialer.ClientID,
+		Handled:    c.handled,
+		Failed:     c.failed,
+		Partitions: []PartitionStatus{},
+	}
+	if c.lastErr != nil {
+		st.LastError = &ConsumerError{Message: c.lastErr.Error(), At: c.lastErrAt}
+	}
+	c.mu.Unlock()
+
+	partitions, err := c.partitionStatus(ctx)
+	if err != nil {
+		st.BrokerError = err.Error()
+		return st
+	}
+	st.Partitions = partitions
+	for _, p := range partitions {
+		st.Lag += p.Lag
+	}
+	return st
+}
+
+func (c *Consumer) partitionStatus(ctx context.Context) ([]PartitionStatus, error) {
+	topic, groupID := c.readerConfig.Topic, c.readerConfig.GroupID
+
+	meta, err := c.client.Metadata(ctx, &kafka.MetadataRequest{Topics: []string{topic}})
+	if err != nil {
+		return nil, fmt.Errorf("metadata: %w", err)
+	}
+	if len(meta.Topics) == 0 {
+		return nil, fmt.Errorf("topic %s not found", topic)
+	}
+	if meta.Topics[0].Error != nil {
+		return nil, fmt.Errorf("metadata: %w", meta.Topics[0].Error)
+	}
+
+	ids := make([]int, 0, len(meta.Topics[0].Partitions))
+	requests := make([]kafka.OffsetRequest, 0, len(ids))
+	for _, p := range meta.Topics[0].Partitions {
+		ids = append(ids, p.ID)
+		requests = append(requests, kafka.FirstOffsetOf(p.ID), kafka.LastOffsetOf(p.ID))
+	}
+	sort.Ints(ids)
+
+	committed, err := c.client.OffsetFetch(ctx, &kafka.OffsetFetchRequest{
+		GroupID: groupID,
+		Topics:  map[string][]int{topic: ids},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("offset fetch: %w", err)
+	}
+	if committed.Error != nil {
+		return nil, fmt.Errorf("offset fetch: %w", committed.Error)
+	}
+
+	offsets, err := c.client.ListOffsets(ctx, &kafka.ListOffsetsRequest{
+		Topics: map[string][]kafka.OffsetRequest{topic: requests},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list offsets: %w", err)
+	}
+
+	assigned, err := c.assignedPartitions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int]*PartitionStatus, len(ids))
+	result := make([]PartitionStatus, len(ids))
+	for i, id := range ids {
+		result[i] = PartitionStatus{Partition: id, Assigned: assigned[id], CommittedOffset: -1}
+		byID[id] = &result[i]
+	}
+	for _, p := range committed.Topics[topic] {
+		if ps, ok := byID[p.Partition]; ok && p.Error == nil {
+			ps.CommittedOffset = p.CommittedOffset
+		}
+	}
+	for _, p := range offsets.Topics[topic] {
+		ps, ok := byID[p.Partition]
+		if !ok || p.Error != nil {
+			continue
+		}
+		ps.HighWatermark = p.LastOffset
+		// без коммитов группа начнет с начала партиции
+		from := ps.CommittedOffset
+		if from < 0 {
+			from = p.FirstOffset
+		}
+		ps.Lag = max(ps.HighWatermark-from, 0)
+	}
+	return result, nil
+}
+
+// assignedPartitions - партиции топика, которые координатор группы
+// назначил этому экземпляру (ищем участника по client id)
+func (c *Consumer) assignedPartitions(ctx context.Context) (map[int]bool, error) {
+	resp, err := c.client.DescribeGroups(ctx, &kafka.DescribeGroupsRequest{
+		GroupIDs: []string{c.readerConfig.GroupID},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("describe group: %w", err)
+	}
+
+	assigned := make(map[int]bool)
+	for _, g := range resp.Groups {
+		if g.Error != nil {
+			return nil, fmt.Errorf("describe group: %w", g.Error)
+		}
+		for _, m := range g.Members {
+			if m.ClientID != c.readerConfig.Dialer.ClientID {
+				continue
+			}
+			for _, t := range m.MemberAssignments.Topics {
+				if t.Topic != c.readerConfig.Topic {
+					continue
+				}
+				for _, p := range t.Partitions {
+					assigned[p] = true
+				}
+			}
+		}
+	}
+	return assigned, nil
+}
@@ -0,0 +1,86 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Заголовки, которые DeadLetterWriter добавляет к исходному сообщению
+const (
+	HeaderDLQError     = "x-dlq-error"
+	HeaderDLQTopic     = "x-dlq-source-topic"
+	HeaderDLQPartition = "x-dlq-source-partition"
+	HeaderDLQOffset    = "x-dlq-source-offset"
+	HeaderDLQFailedAt  = "x-dlq-failed-at"
+)
+
+// dlqTimeout ограничивает запись в DLQ, чтобы consumer не завис на недоступном брокере
+const dlqTimeout = 10 * time.Second
+
+// MessageWriter - то, что нужно для записи в DLQ. Реализуется *kafka.Writer,
+// в тестах - kafkatest.Broker.
+type MessageWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// DeadLetterWriter пишет необработанные сообщения в отдельный топик:
+// ключ, значение и заголовки сохраняются, причина и источник - в x-dlq-* заголовках.
+// Такой топик можно разобрать и перечитать командой replay.
+type DeadLetterWriter struct {
+	topic  string
+	writer MessageWriter
+}
+
+// NewDeadLetterWriter создает DLQ поверх настоящего брокера
+func NewDeadLetterWriter(brokers []string, topic string, sec SecurityConfig) (*DeadLetterWriter, error) {
+	transport, err := sec.Transport("order-service-dlq")
+	if err != nil {
+		return nil, err
+	}
+	return NewDeadLetterWriterFrom(topic, &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{}, // тот же ключ - та же партиция, порядок по заказу сохраняется
+		RequiredAcks: kafka.RequireAll,
+		Transport:    transport,
+	}), nil
+}
+
+// NewDeadLetterWriterFrom - DLQ поверх готового writer'а, уже настроенного на topic
+func NewDeadLetterWriterFrom(topic string, w MessageWriter) *DeadLetterWriter {
+	return &DeadLetterWriter{topic: topic, writer: w}
+}
+
+func (w *DeadLetterWriter) Topic() string {
+	return w.topic
+}
+
+func (w *DeadLetterWriter) Write(ctx context.Context, m kafka.Message, cause error) error {
+	ctx, cancel := context.WithTimeout(ctx, dlqTimeout)
+	defer cancel()
+
+	headers := make([]kafka.Header, 0, len(m.Headers)+5)
+	headers = append(headers, m.Headers...)
+	headers = append(headers,
+		kafka.Header{Key: HeaderDLQError, Value: []byte(cause.Error())},
+		kafka.Header{Key: HeaderDLQTopic, Value: []byte(m.Topic)},
+		kafka.Header{Key: HeaderDLQPartition, Value: []byte(strconv.Itoa(m.Partition))},
+		kafka.Header{Key: HeaderDLQOffset, Value: []byte(strconv.FormatInt(m.Offset, 10))},
+		kafka.Header{Key: HeaderDLQFailedAt, Value: []byte(time.Now().UTC().Format(time.RFC3339))},
+	)
+
+	err := w.writer.WriteMessages(ctx, kafka.Message{Key: m.Key, Value: m.Value, Headers: headers})
+	if err != nil {
+		return fmt.Errorf("failed to write message %d of partition %d to DLQ %s: %w", m.Offset, m.Partition, w.topic, err)
+	}
+	return nil
+}
+
+func (w *DeadLetterWriter) Close() error {
+	return w.writer.Close()
+}
@@ -0,0 +1,177 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"github.com/Sergi-Ch/WB_L0_2025/domain"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/repository"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/service"
+	"github.com/segmentio/kafka-go"
+)
+
+// Decoder разбирает значение сообщения; выбирается для каждого топика
+type Decoder interface {
+	Decode(data []byte, v any) error
+}
+
+// JSONDecoder - декодер по умолчанию. Strict отклоняет неизвестные поля.
+type JSONDecoder struct {
+	Strict bool
+}
+
+func (d JSONDecoder) Decode(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if d.Strict {
+		dec.DisallowUnknownFields()
+	}
+	return dec.Decode(v)
+}
+
+// NewDecoder возвращает декодер по имени из конфигурации: json, json_strict
+func NewDecoder(name string) (Decoder, error) {
+	switch name {
+	case "", "json":
+		return JSONDecoder{}, nil
+	case "json_strict":
+		return JSONDecoder{Strict: true}, nil
+	default:
+		return nil, fmt.Errorf("unknown decoding %q", name)
+	}
+}
+
+// HandlerFactory создает handler для топика с его декодером
+type HandlerFactory func(d Decoder, log *slog.Logger) Handler
+
+// HandlerRegistry - типы обработчиков, на которые в конфигурации ссылаются
+// топики (orders -> order, ...). Заполняется при старте, до NewManager.
+type HandlerRegistry struct {
+	factories map[string]HandlerFactory
+}
+
+func NewHandlerRegistry() *HandlerRegistry {
+	return &HandlerRegistry{factories: make(map[string]HandlerFactory)}
+}
+
+func (r *HandlerRegistry) Register(name string, f HandlerFactory) {
+	r.factories[name] = f
+}
+
+// Build создает handler зарегистрированного типа
+func (r *HandlerRegistry) Build(name string, d Decoder, log *slog.Logger) (Handler, error) {
+	f, ok := r.factories[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown handler %q (registered: %v)", name, r.names())
+	}
+	return f(d, log), nil
+}
+
+func (r *HandlerRegistry) names() []string {
+	names := make([]string, 0, len(r.factories))
+	for name := range r.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// OrderHandlerFactory - тип "order": заказ целиком, сохраняется через сервис
+func OrderHandlerFactory(orderService service.OrderServiceInterface) HandlerFactory {
+	return func(d Decoder, log *slog.Logger) Handler {
+		return newOrderMessageHandler(orderService, d, log)
+	}
+}
+
+// newOrderMessageHandler декодирует заказ из сообщения и сохраняет его через сервис.
+// Уже сохраненный заказ возвращается как repository.ErrAlreadyExists: consumer
+// считает его повторной доставкой (skipRedelivered), replay - дубликатом.
+func newOrderMessageHandler(orderService service.OrderServiceInterface, d Decoder, log *slog.Logger) Handler {
+	return func(ctx context.Context, m kafka.Message) error {
+		order, err := decodeOrder(d, m)
+		if err != nil {
+			return err
+		}
+
+		if err := orderService.SaveOrder(ctx, order); err != nil {
+			return fmt.Errorf("failed to save order %s: %w", order.OrderUid, err)
+		}
+
+		log.InfoContext(ctx, "order saved",
+			slog.String("order_uid", order.OrderUid),
+			slog.Int("partition", m.Partition),
+			slog.Int64("offset", m.Offset),
+		)
+		return nil
+	}
+}
+
+// CancelMessage - сообщение отмены заказа для обработчика "order_cancel"
+type CancelMessage struct {
+	OrderUid string `json:"order_uid"`
+	Reason   string `json:"reason"`
+	// Actor - кто отменил; по умолчанию kafka:<топик>
+	Actor string `json:"actor"`
+}
+
+// OrderCancelHandlerFactory - тип "order_cancel": мягкое удаление заказа через
+// сервис. Повторная отмена не ошибка, неизвестный заказ уходит в DLQ.
+func OrderCancelHandlerFactory(orderService service.OrderServiceInterface) HandlerFactory {
+	return func(d Decoder, log *slog.Logger) Handler {
+		return func(ctx context.Context, m kafka.Message) error {
+			var msg CancelMessage
+			if err := d.Decode(m.Value, &msg); err != nil {
+				return fmt.Errorf("%w: %w", ErrInvalidMessage, err)
+			}
+			if msg.Actor == "" {
+				msg.Actor = "kafka:" + m.Topic
+			}
+
+			err := orderService.DeleteOrder(ctx, msg.OrderUid, msg.Reason, msg.Actor)
+			switch {
+			case errors.Is(err, repository.ErrAlreadyDeleted):
+				log.InfoContext(ctx, "order already cancelled", slog.String("order_uid", msg.OrderUid))
+				return nil
+			case errors.Is(err, service.ErrInvalidOrderID):
+				return fmt.Errorf("%w: %w", ErrInvalidMessage, err)
+			case err != nil:
+				return fmt.Errorf("failed to cancel order %s: %w", msg.OrderUid, err)
+			}
+
+			log.InfoContext(ctx, "order cancelled",
+				slog.String("order_uid", msg.OrderUid),
+				slog.Int("partition", m.Partition),
+				slog.Int64("offset", m.Offset),
+			)
+			return nil
+		}
+	}
+}
+
+// OrderValidator - то, что нужно для dry-run: проверка без сохранения
+type OrderValidator interface {
+	ValidateOrder(order *domain.Order) error
+}
+
+// NewOrderValidationHandler только декодирует и валидирует заказ (dry-run replay)
+func NewOrderValidationHandler(v OrderValidator) Handler {
+	return func(ctx context.Context, m kafka.Message) error {
+		order, err := decodeOrder(JSONDecoder{}, m)
+		if err != nil {
+			return err
+		}
+		return v.ValidateOrder(order)
+	}
+}
+
+func decodeOrder(d Decoder, m kafka.Message) (*domain.Order, error) {
+	var order domain.Order
+	if err := d.Decode(m.Value, &order); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidMessage, err)
+	}
+	return &order, nil
+}
@@ -2,61 +2,347 @@ package kafka
 
 import (
 	"context"
-	"encoding/json"
-	"github.com/Sergi-Ch/WB_L0_2025/domain"
+	"errors"
+	"fmt"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/health"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/logger"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/repository"
 	"github.com/Sergi-Ch/WB_L0_2025/internal/service"
 	"github.com/segmentio/kafka-go"
-	"log"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// ErrInvalidMessage - сообщение не удалось декодировать
+var ErrInvalidMessage = errors.New("invalid message")
+
+// Handler обрабатывает одно сообщение из топика
+type Handler func(ctx context.Context, m kafka.Message) error
+
+// Middleware оборачивает Handler (метрики, трейсинг и т.п.)
+type Middleware func(Handler) Handler
+
+// MessageReader - то, что consumer'у нужно от reader'а группы.
+// Реализуется *kafka.Reader, в тестах - kafkatest.Broker.
+type MessageReader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	Stats() kafka.ReaderStats
+	Close() error
+}
+
+// ReaderFactory создает reader; вызывается при старте и при resume после drain
+type ReaderFactory func(cfg kafka.ReaderConfig) MessageReader
+
+// NewKafkaReader - ReaderFactory для настоящего брокера
+func NewKafkaReader(cfg kafka.ReaderConfig) MessageReader {
+	return kafka.NewReader(cfg)
+}
+
+// ConsumerConfig - один reader группы на один топик
+type ConsumerConfig struct {
+	Brokers  []string
+	Topic    string
+	GroupID  string
+	Security SecurityConfig
+	// DeadLetter, если задан, получает сообщения, которые handler не смог
+	// обработать; без него такие сообщения только логируются
+	DeadLetter *DeadLetterWriter
+	// NewReader - nil для настоящего Kafka (NewKafkaReader)
+	NewReader ReaderFactory
+	// RetryBackoff - первая пауза перед повтором сообщения, когда хранилище
+	// недоступно; удваивается до maxRetryBackoff. 0 - DefaultRetryBackoff.
+	RetryBackoff time.Duration
+}
+
+const (
+	DefaultRetryBackoff = time.Second
+	maxRetryBackoff     = 30 * time.Second
 )
 
 type Consumer struct {
-	reader       *kafka.Reader
-	orderService *service.OrderService
+	readerConfig kafka.ReaderConfig
+	newReader    ReaderFactory
+	client       *kafka.Client
+	handler      Handler
+	dlq          *DeadLetterWriter
+	retryBackoff time.Duration
+	log          *slog.Logger
+
+	// состояние для admin API (см. consumer_admin.go)
+	mu        sync.Mutex
+	reader    MessageReader // nil после drain
+	state     ConsumerState
+	since     time.Time
+	changed   chan struct{} // закрывается при каждой смене состояния
+	stopFetch context.CancelFunc
+	handled   int64
+	failed    int64
+	lastErr   error
+	lastErrAt time.Time
 }
 
-func NewConsumer(brokers []string, topic, groupID string, orderService *service.OrderService) *Consumer {
-	r := kafka.NewReader(kafka.ReaderConfig{
-		Brokers: brokers,
-		Topic:   topic,
-		GroupID: groupID,
-	})
-	return &Consumer{reader: r, orderService: orderService}
+func NewConsumer(cfg ConsumerConfig, handler Handler, log *slog.Logger) (*Consumer, error) {
+	clientID := consumerClientID()
+	dialer, err := cfg.Security.Dialer(clientID)
+	if err != nil {
+		return nil, err
+	}
+	transport, err := cfg.Security.Transport(clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	readerConfig := kafka.ReaderConfig{
+		Brokers: cfg.Brokers,
+		Topic:   cfg.Topic,
+		GroupID: cfg.GroupID,
+		Dialer:  dialer,
+	}
+	newReader := cfg.NewReader
+	if newReader == nil {
+		newReader = NewKafkaReader
+	}
+	retryBackoff := cfg.RetryBackoff
+	if retryBackoff <= 0 {
+		retryBackoff = DefaultRetryBackoff
+	}
+	log = log.With(slog.String("component", "kafka_consumer"), slog.String("topic", cfg.Topic))
+	return &Consumer{
+		readerConfig: readerConfig,
+		newReader:    newReader,
+		client:       &kafka.Client{Addr: kafka.TCP(cfg.Brokers...), Timeout: 10 * time.Second, Transport: transport},
+		handler:      skipRedelivered(handler, log),
+		dlq:          cfg.DeadLetter,
+		retryBackoff: retryBackoff,
+		log:          log,
+		reader:       newReader(readerConfig),
+		state:        ConsumerRunning,
+		since:        time.Now(),
+		changed:      make(chan struct{}),
+	}, nil
+}
+
+// Topic - топик, который читает consumer
+func (c *Consumer) Topic() string {
+	return c.readerConfig.Topic
+}
+
+// consumerClientID - уникальный client id, по нему в DescribeGroups
+// находятся партиции, назначенные именно этому экземпляру
+func consumerClientID() string {
+	return "order-service-" + logger.NewRequestID()[:12]
+}
+
+// Use добавляет middleware вокруг обработчика сообщений.
+// Первый переданный middleware оказывается самым внешним.
+func (c *Consumer) Use(middlewares ...Middleware) {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		c.handler = middlewares[i](c.handler)
+	}
 }
 
+// commitTimeout - сколько ждать коммита офсета при остановке сервиса
+const commitTimeout = 5 * time.Second
+
+// Start читает сообщения до отмены ctx. Офсет коммитится после обработки
+// сообщения (at-least-once), поэтому pause и drain не теряют сообщений.
+// Если хранилище недоступно, сообщение не коммитится и не уходит в DLQ:
+// его обработка повторяется с растущей паузой, пока не пройдет.
 func (c *Consumer) Start(ctx context.Context) error {
-	log.Printf("Kafka consumer started...\n")
+	c.log.Info("kafka consumer started")
+	var retry *pendingRetry
 	for {
-		select {
-		case <-ctx.Done():
-			log.Println("Kafka consumer context cancelled")
+		reader, fetchCtx, cancel, err := c.waitRunning(ctx)
+		if err != nil {
+			c.log.Info("kafka consumer context cancelled")
 			return nil
-		default:
+		}
 
-			m, err := c.reader.ReadMessage(ctx)
+		var m kafka.Message
+		if retry != nil && retry.reader == reader {
+			// пауза прерывается pause/drain и остановкой сервиса
+			err = sleep(fetchCtx, retry.backoff)
+			cancel()
 			if err != nil {
 				if ctx.Err() != nil {
+					c.log.Info("kafka consumer context cancelled")
 					return nil
 				}
-
+				continue
+			}
+			m = retry.message
+		} else {
+			// после drain reader новый: незакоммиченное сообщение группа
+			// отдаст заново, повторять его здесь не нужно
+			retry = nil
+			m, err = reader.FetchMessage(fetchCtx)
+			// до cancel: после него fetchCtx.Err() всегда не nil
+			interrupted := fetchCtx.Err() != nil
+			cancel()
+			if err != nil {
+				if ctx.Err() != nil {
+					c.log.Info("kafka consumer context cancelled")
+					return nil
+				}
+				if interrupted {
+					// чтение прервано pause/drain
+					continue
+				}
+				c.setError(err)
 				return err
 			}
+		}
 
-			var order domain.Order
-			if err := json.Unmarshal(m.Value, &order); err != nil {
-				log.Printf("invalid message: %v", err)
-				continue
+		msgCtx := messageContext(ctx, m)
+		err = c.handler(msgCtx, m)
+		c.recordHandled(err)
+		switch {
+		case errors.Is(err, service.ErrUnavailable):
+			retry = retry.next(reader, m, c.retryBackoff)
+			c.log.WarnContext(msgCtx, "storage unavailable, message will be retried",
+				slog.Int("partition", m.Partition),
+				slog.Int64("offset", m.Offset),
+				slog.Duration("retry_in", retry.backoff),
+				logger.Err(err),
+			)
+			continue
+		case err != nil && ctx.Err() != nil:
+			// обработка прервана остановкой сервиса: не коммитим,
+			// сообщение перечитается после рестарта
+			c.log.WarnContext(msgCtx, "message handling interrupted by shutdown",
+				slog.Int("partition", m.Partition),
+				slog.Int64("offset", m.Offset),
+				logger.Err(err),
+			)
+			return nil
+		case err != nil:
+			c.log.ErrorContext(msgCtx, "failed to handle message",
+				slog.Int("partition", m.Partition),
+				slog.Int64("offset", m.Offset),
+				logger.Err(err),
+			)
+			// сообщение уходит в DLQ, а без DLQ только логируется и коммитится.
+			// Если запись в DLQ не удалась, офсет не коммитим: сообщение
+			// перечитается после рестарта.
+			if c.dlq != nil {
+				if err := c.dlq.Write(context.WithoutCancel(msgCtx), m, err); err != nil {
+					c.setError(err)
+					return err
+				}
 			}
+		}
+		retry = nil
 
-			if err := c.orderService.SaveOrder(ctx, &order); err != nil {
-				log.Printf("failed to save order: %v", err)
-				continue
-			}
+		// коммитим и при остановке сервиса, чтобы обработанное не перечитывать
+		commitCtx, cancelCommit := context.WithTimeout(context.WithoutCancel(ctx), commitTimeout)
+		err = reader.CommitMessages(commitCtx, m)
+		cancelCommit()
+		if err != nil {
+			c.setError(err)
+			return fmt.Errorf("failed to commit offset %d of partition %d: %w", m.Offset, m.Partition, err)
+		}
+	}
+}
 
-			log.Printf("order saved>>> %s", order.OrderUid)
+// pendingRetry - сообщение, которое не удалось обработать из-за недоступного
+// хранилища. Повторяется только тем reader'ом, который его прочитал.
+type pendingRetry struct {
+	reader  MessageReader
+	message kafka.Message
+	backoff time.Duration
+}
+
+// next возвращает повтор сообщения m с удвоенной паузой (первый - с initial)
+func (r *pendingRetry) next(reader MessageReader, m kafka.Message, initial time.Duration) *pendingRetry {
+	backoff := initial
+	if r != nil && r.reader == reader && r.message.Partition == m.Partition && r.message.Offset == m.Offset {
+		backoff = min(r.backoff*2, max(maxRetryBackoff, initial))
+	}
+	return &pendingRetry{reader: reader, message: m, backoff: backoff}
+}
+
+// sleep ждет d или отмены ctx
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// skipRedelivered считает повторно доставленный заказ обработанным: офсет
+// коммитится после обработки, и после рестарта или ребалансировки сообщения
+// приходят снова. Replay вызывает handler без этой обертки и считает такие
+// сообщения duplicate.
+func skipRedelivered(next Handler, log *slog.Logger) Handler {
+	return func(ctx context.Context, m kafka.Message) error {
+		err := next(ctx, m)
+		if errors.Is(err, repository.ErrAlreadyExists) {
+			log.InfoContext(ctx, "order already saved, message skipped",
+				slog.Int("partition", m.Partition),
+				slog.Int64("offset", m.Offset),
+				logger.Err(err),
+			)
+			return nil
 		}
+		return err
 	}
 }
+
+// messageContext кладет в контекст request_id из заголовка сообщения
+// (или новый, если продюсер его не передал)
+func messageContext(ctx context.Context, m kafka.Message) context.Context {
+	id := ""
+	for _, h := range m.Headers {
+		if h.Key == logger.RequestIDHeader {
+			id = string(h.Value)
+			break
+		}
+	}
+	if id == "" {
+		id = logger.NewRequestID()
+	}
+	return logger.WithRequestID(ctx, id)
+}
+
+// HealthChecker проверяет reader по его статистике: ошибки без единого
+// прочитанного сообщения с прошлой проверки или лаг больше maxLag
+// (0 - лаг не проверяется). Stats() сбрасывает счетчики при каждом вызове.
+// Остановленный через admin API consumer считается здоровым: это штатное
+// состояние, и HTTP-чтение должно продолжать работать.
+func (c *Consumer) HealthChecker(maxLag int64) health.Checker {
+	return health.CheckerFunc(func(context.Context) error {
+		c.mu.Lock()
+		reader, state := c.reader, c.state
+		c.mu.Unlock()
+		if state != ConsumerRunning || reader == nil {
+			return nil
+		}
+
+		stats := reader.Stats()
+		if stats.Errors > 0 && stats.Messages == 0 {
+			return fmt.Errorf("kafka reader: %d errors and no messages since last check", stats.Errors)
+		}
+		if maxLag > 0 && stats.Lag > maxLag {
+			return fmt.Errorf("kafka reader lag %d exceeds %d", stats.Lag, maxLag)
+		}
+		return nil
+	})
+}
+
 func (c *Consumer) Close() error {
-	log.Printf("closing kafka consumer...\n")
-	return c.reader.Close()
+	c.log.Info("closing kafka consumer")
+	c.mu.Lock()
+	reader := c.reader
+	c.reader = nil
+	c.mu.Unlock()
+	if reader == nil {
+		return nil
+	}
+	return reader.Close()
 }
@@ -0,0 +1,474 @@
+// Package kafkatest - брокер Kafka в памяти для детерминированных end-to-end
+// тестов consumer'ов: топики с партициями, consumer group с ребалансом,
+// закоммиченные офсеты, история коммитов и внедряемые ошибки.
+//
+// Broker.NewReader и Broker.NewWriter подставляются в kafka.ManagerConfig
+// (или kafka.ConsumerConfig) вместо настоящего брокера.
+package kafkatest
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"io"
+	"sync"
+	"time"
+
+	internalKafka "github.com/Sergi-Ch/WB_L0_2025/internal/kafka"
+	"github.com/segmentio/kafka-go"
+)
+
+// ErrNoGroup - коммит reader'ом без GroupID (как у kafka-go)
+var ErrNoGroup = errors.New("kafkatest: commit is not available without GroupID")
+
+// Commit - один закоммиченный офсет (следующий к чтению)
+type Commit struct {
+	Group     string
+	Topic     string
+	Partition int
+	Offset    int64
+}
+
+type topicPartition struct {
+	topic     string
+	partition int
+}
+
+type group struct {
+	committed map[topicPartition]int64
+	members   []*Reader // в порядке вступления
+	commits   []Commit
+}
+
+type Broker struct {
+	mu      sync.Mutex
+	changed chan struct{} // закрывается при любом изменении состояния
+	topics  map[string][][]kafka.Message
+	groups  map[string]*group
+
+	// внедренные ошибки по топикам, расходуются по одной
+	fetchErrs  map[string][]error
+	commitErrs map[string][]error
+	writeErrs  map[string][]error
+}
+
+func NewBroker() *Broker {
+	return &Broker{
+		changed:    make(chan struct{}),
+		topics:     make(map[string][][]kafka.Message),
+		groups:     make(map[string]*group),
+		fetchErrs:  make(map[string][]error),
+		commitErrs: make(map[string][]error),
+		writeErrs:  make(map[string][]error),
+	}
+}
+
+// CreateTopic создает топик (или добавляет партиции существующему) и
+// перераспределяет партиции между участниками групп
+func (b *Broker) CreateTopic(topic string, partitions int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.createTopic(topic, partitions)
+	b.rebalanceAll(topic)
+	b.notify()
+}
+
+func (b *Broker) createTopic(topic string, partitions int) {
+	for len(b.topics[topic]) < partitions {
+		b.topics[topic] = append(b.topics[topic], nil)
+	}
+}
+
+// Produce пишет сообщения как kafka.Writer с балансировкой по ключу.
+// Несуществующий топик создается с одной партицией.
+func (b *Broker) Produce(topic string, msgs ...kafka.Message) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := pop(b.writeErrs, topic); err != nil {
+		return err
+	}
+	b.produce(topic, msgs)
+	return nil
+}
+
+// ProduceTo пишет сообщение в конкретную партицию и возвращает его офсет
+func (b *Broker) ProduceTo(topic string, partition int, m kafka.Message) int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.createTopic(topic, partition+1)
+	return b.append(topic, partition, m)
+}
+
+func (b *Broker) produce(topic string, msgs []kafka.Message) {
+	if _, ok := b.topics[topic]; !ok {
+		b.createTopic(topic, 1)
+		b.rebalanceAll(topic)
+	}
+	n := len(b.topics[topic])
+	for _, m := range msgs {
+		partition := 0
+		if len(m.Key) > 0 {
+			h := fnv.New32a()
+			_, _ = h.Write(m.Key)
+			partition = int(h.Sum32() % uint32(n))
+		}
+		b.append(topic, partition, m)
+	}
+}
+
+func (b *Broker) append(topic string, partition int, m kafka.Message) int64 {
+	log := b.topics[topic][partition]
+	m.Topic = topic
+	m.Partition = partition
+	m.Offset = int64(len(log))
+	if m.Time.IsZero() {
+		m.Time = time.Now()
+	}
+	b.topics[topic][partition] = append(log, m)
+	b.notify()
+	return m.Offset
+}
+
+// Messages возвращает сообщения топика по партициям в порядке офсетов
+func (b *Broker) Messages(topic string) []kafka.Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var out []kafka.Message
+	for _, log := range b.topics[topic] {
+		out = append(out, log...)
+	}
+	return out
+}
+
+// Committed - закоммиченные группой офсеты топика по партициям
+func (b *Broker) Committed(groupID, topic string) map[int]int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := make(map[int]int64)
+	if g, ok := b.groups[groupID]; ok {
+		for tp, offset := range g.committed {
+			if tp.topic == topic {
+				out[tp.partition] = offset
+			}
+		}
+	}
+	return out
+}
+
+// Commits - история всех коммитов группы
+func (b *Broker) Commits(groupID string) []Commit {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if g, ok := b.groups[groupID]; ok {
+		return append([]Commit(nil), g.commits...)
+	}
+	return nil
+}
+
+// Lag - сколько сообщений топика группа еще не закоммитила
+func (b *Broker) Lag(groupID, topic string) int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var lag int64
+	for p, log := range b.topics[topic] {
+		lag += int64(len(log)) - b.committed(groupID, topic, p)
+	}
+	return lag
+}
+
+// Assignments - партиции топика у каждого участника группы в порядке вступления
+func (b *Broker) Assignments(groupID, topic string) [][]int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var out [][]int
+	if g, ok := b.groups[groupID]; ok {
+		for _, r := range g.members {
+			if r.cfg.Topic == topic {
+				out = append(out, append([]int(nil), r.assigned...))
+			}
+		}
+	}
+	return out
+}
+
+// FailFetch - следующий FetchMessage по топику вернет err
+func (b *Broker) FailFetch(topic string, err error) {
+	b.inject(b.fetchErrs, topic, err)
+}
+
+// FailCommit - следующий CommitMessages по топику вернет err
+func (b *Broker) FailCommit(topic string, err error) {
+	b.inject(b.commitErrs, topic, err)
+}
+
+// FailWrite - следующая запись в топик вернет err
+func (b *Broker) FailWrite(topic string, err error) {
+	b.inject(b.writeErrs, topic, err)
+}
+
+func (b *Broker) inject(errs map[string][]error, topic string, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	errs[topic] = append(errs[topic], err)
+	b.notify()
+}
+
+// WaitFor ждет, пока cond не станет истинным. Условие перепроверяется
+// после каждого изменения состояния брокера (запись, коммит, ребаланс).
+func (b *Broker) WaitFor(ctx context.Context, cond func() bool) error {
+	for {
+		b.mu.Lock()
+		changed := b.changed
+		b.mu.Unlock()
+
+		if cond() {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// NewReader - kafka.ReaderFactory. С GroupID reader вступает в группу
+// и получает партиции при ребалансе, без него - читает cfg.Partition.
+func (b *Broker) NewReader(cfg kafka.ReaderConfig) internalKafka.MessageReader {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	r := &Reader{b: b, cfg: cfg, pos: make(map[int]int64)}
+	if cfg.GroupID == "" {
+		r.assigned = []int{cfg.Partition}
+		b.createTopic(cfg.Topic, cfg.Partition+1)
+		return r
+	}
+
+	g := b.group(cfg.GroupID)
+	g.members = append(g.members, r)
+	b.rebalance(g, cfg.Topic)
+	b.notify()
+	return r
+}
+
+// NewWriter - kafka.WriterFactory
+func (b *Broker) NewWriter(topic string) internalKafka.MessageWriter {
+	return &Writer{b: b, topic: topic}
+}
+
+func (b *Broker) group(id string) *group {
+	g, ok := b.groups[id]
+	if !ok {
+		g = &group{committed: make(map[topicPartition]int64)}
+		b.groups[id] = g
+	}
+	return g
+}
+
+func (b *Broker) committed(groupID, topic string, partition int) int64 {
+	if g, ok := b.groups[groupID]; ok {
+		return g.committed[topicPartition{topic, partition}]
+	}
+	return 0
+}
+
+func (b *Broker) rebalanceAll(topic string) {
+	for _, g := range b.groups {
+		b.rebalance(g, topic)
+	}
+}
+
+// rebalance раздает партиции топика его подписчикам в группе по кругу
+// (как RoundRobinGroupBalancer). Позиции сбрасываются на закоммиченные
+// офсеты: незакоммиченные сообщения будут прочитаны повторно. Reader'ы
+// других топиков группы не затрагиваются - в отличие от настоящего Kafka,
+// где ребаланс общий, но для consumer'ов с at-least-once это неотличимо.
+func (b *Broker) rebalance(g *group, topic string) {
+	var members []*Reader
+	for _, r := range g.members {
+		if r.cfg.Topic == topic {
+			members = append(members, r)
+		}
+	}
+	if len(members) == 0 {
+		return
+	}
+	for _, r := range members {
+		r.assigned = nil
+		r.pos = make(map[int]int64)
+		r.next = 0
+	}
+	for p := range b.topics[topic] {
+		r := members[p%len(members)]
+		r.assigned = append(r.assigned, p)
+		r.pos[p] = b.committed(r.cfg.GroupID, topic, p)
+	}
+}
+
+func (b *Broker) notify() {
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+func pop(errs map[string][]error, topic string) error {
+	if len(errs[topic]) == 0 {
+		return nil
+	}
+	err := errs[topic][0]
+	errs[topic] = errs[topic][1:]
+	return err
+}
+
+// Reader - участник группы (или reader одной партиции без группы)
+type Reader struct {
+	b   *Broker
+	cfg kafka.ReaderConfig
+
+	// под b.mu
+	assigned []int
+	pos      map[int]int64
+	next     int
+	closed   bool
+	messages int64 // для Stats, сбрасываются при каждом вызове
+	errors   int64
+}
+
+func (r *Reader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	b := r.b
+	for {
+		// отмененный ctx важнее готовых сообщений: после остановки ничего не читаем
+		if err := ctx.Err(); err != nil {
+			return kafka.Message{}, err
+		}
+		b.mu.Lock()
+		if r.closed {
+			b.mu.Unlock()
+			return kafka.Message{}, io.EOF
+		}
+		if err := pop(b.fetchErrs, r.cfg.Topic); err != nil {
+			r.errors++
+			b.mu.Unlock()
+			return kafka.Message{}, err
+		}
+		// партиции по кругу, чтобы одна не забирала все чтение
+		for i := range r.assigned {
+			idx := (r.next + i) % len(r.assigned)
+			p := r.assigned[idx]
+			log := b.topics[r.cfg.Topic][p]
+			if r.pos[p] < int64(len(log)) {
+				m := log[r.pos[p]]
+				r.pos[p]++
+				r.next = (idx + 1) % len(r.assigned)
+				r.messages++
+				b.mu.Unlock()
+				return m, nil
+			}
+		}
+		changed := b.changed
+		b.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return kafka.Message{}, ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+func (r *Reader) CommitMessages(_ context.Context, msgs ...kafka.Message) error {
+	b := r.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if r.cfg.GroupID == "" {
+		return ErrNoGroup
+	}
+	if err := pop(b.commitErrs, r.cfg.Topic); err != nil {
+		r.errors++
+		return err
+	}
+	g := b.group(r.cfg.GroupID)
+	for _, m := range msgs {
+		tp := topicPartition{m.Topic, m.Partition}
+		// коммит не откатывает офсет назад, как и в kafka-go
+		if next := m.Offset + 1; next > g.committed[tp] {
+			g.committed[tp] = next
+		}
+		g.commits = append(g.commits, Commit{Group: r.cfg.GroupID, Topic: m.Topic, Partition: m.Partition, Offset: m.Offset + 1})
+	}
+	b.notify()
+	return nil
+}
+
+// Stats возвращает число прочитанных сообщений и ошибок с прошлого вызова и лаг
+func (r *Reader) Stats() kafka.ReaderStats {
+	b := r.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var lag int64
+	for _, p := range r.assigned {
+		lag += int64(len(b.topics[r.cfg.Topic][p])) - r.pos[p]
+	}
+	stats := kafka.ReaderStats{
+		Topic:    r.cfg.Topic,
+		Messages: r.messages,
+		Errors:   r.errors,
+		Lag:      lag,
+	}
+	r.messages, r.errors = 0, 0
+	return stats
+}
+
+// Close выводит reader из группы, его партиции переходят остальным
+func (r *Reader) Close() error {
+	b := r.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	if g, ok := b.groups[r.cfg.GroupID]; ok {
+		for i, m := range g.members {
+			if m == r {
+				g.members = append(g.members[:i], g.members[i+1:]...)
+				break
+			}
+		}
+		b.rebalance(g, r.cfg.Topic)
+	}
+	b.notify()
+	return nil
+}
+
+// Writer пишет в топик брокера, как kafka.Writer с балансировкой по ключу
+type Writer struct {
+	b      *Broker
+	topic  string
+	closed bool
+}
+
+func (w *Writer) WriteMessages(_ context.Context, msgs ...kafka.Message) error {
+	b := w.b
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if w.closed {
+		return io.ErrClosedPipe
+	}
+	if err := pop(b.writeErrs, w.topic); err != nil {
+		return err
+	}
+	b.produce(w.topic, msgs)
+	return nil
+}
+
+func (w *Writer) Close() error {
+	w.b.mu.Lock()
+	defer w.b.mu.Unlock()
+	w.closed = true
+	return nil
+}
@@ -0,0 +1,260 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/Sergi-Ch/WB_L0_2025/internal/health"
+)
+
+// ErrUnknownTopic - топик не обслуживается этим менеджером
+var ErrUnknownTopic = errors.New("unknown topic")
+
+// TopicConfig описывает один читаемый топик
+type TopicConfig struct {
+	Topic   string
+	Handler string // имя в HandlerRegistry
+	// Decoding - декодер сообщений, см. NewDecoder
+	Decoding string
+	// Concurrency - число reader'ов топика в группе. Больше числа
+	// партиций смысла не имеет: лишние reader'ы останутся без партиций.
+	Concurrency int
+	// DLQTopic - куда отправлять необработанные сообщения, пусто - только логировать
+	DLQTopic string
+}
+
+// WriterFactory создает writer в топик (для DLQ)
+type WriterFactory func(topic string) MessageWriter
+
+// ManagerConfig - общие настройки всех топиков
+type ManagerConfig struct {
+	Brokers  []string
+	GroupID  string
+	Security SecurityConfig
+	Topics   []TopicConfig
+	// NewReader и NewWriter - nil для настоящего Kafka; в тестах
+	// подставляются методы kafkatest.Broker
+	NewReader ReaderFactory
+	NewWriter WriterFactory
+	// RetryBackoff - см. ConsumerConfig.RetryBackoff
+	RetryBackoff time.Duration
+}
+
+// Manager запускает consumer'ы всех топиков в одной группе и управляет
+// ими как одним целым: общий Start/Close, health-check и admin API.
+type Manager struct {
+	consumers []*Consumer
+	dlqs      []*DeadLetterWriter
+	log       *slog.Logger
+}
+
+func NewManager(cfg ManagerConfig, handlers *HandlerRegistry, log *slog.Logger) (*Manager, error) {
+	if len(cfg.Topics) == 0 {
+		return nil, errors.New("no topics to consume")
+	}
+
+	m := &Manager{log: log.With(slog.String("component", "kafka_manager"))}
+	for _, t := range cfg.Topics {
+		decoder, err := NewDecoder(t.Decoding)
+		if err != nil {
+			m.Close()
+			return nil, fmt.Errorf("topic %s: %w", t.Topic, err)
+		}
+		handlerLog := log.With(slog.String("component", "kafka_consumer"), slog.String("topic", t.Topic))
+		handler, err := handlers.Build(t.Handler, decoder, handlerLog)
+		if err != nil {
+			m.Close()
+			return nil, fmt.Errorf("topic %s: %w", t.Topic, err)
+		}
+
+		var dlq *DeadLetterWriter
+		switch {
+		case t.DLQTopic == "":
+		case cfg.NewWriter != nil:
+			dlq = NewDeadLetterWriterFrom(t.DLQTopic, cfg.NewWriter(t.DLQTopic))
+		default:
+			if dlq, err = NewDeadLetterWriter(cfg.Brokers, t.DLQTopic, cfg.Security); err != nil {
+				m.Close()
+				return nil, fmt.Errorf("topic %s: %w", t.Topic, err)
+			}
+		}
+		if dlq != nil {
+			m.dlqs = append(m.dlqs, dlq)
+		}
+
+		for range max(t.Concurrency, 1) {
+			c, err := NewConsumer(ConsumerConfig{
+				Brokers:      cfg.Brokers,
+				Topic:        t.Topic,
+				GroupID:      cfg.GroupID,
+				Security:     cfg.Security,
+				DeadLetter:   dlq,
+				NewReader:    cfg.NewReader,
+				RetryBackoff: cfg.RetryBackoff,
+			}, handler, log)
+			if err != nil {
+				m.Close()
+				return nil, fmt.Errorf("topic %s: %w", t.Topic, err)
+			}
+			m.consumers = append(m.consumers, c)
+		}
+
+		m.log.Info("topic registered",
+			slog.String("topic", t.Topic),
+			slog.String("handler", t.Handler),
+			slog.Int("concurrency", max(t.Concurrency, 1)),
+			slog.String("dlq_topic", t.DLQTopic),
+		)
+	}
+	return m, nil
+}
+
+// Use добавляет middleware вокруг обработчиков всех топиков
+func (m *Manager) Use(middlewares ...Middleware) {
+	for _, c := range m.consumers {
+		c.Use(middlewares...)
+	}
+}
+
+// Start запускает все consumer'ы и ждет их завершения. Ошибка одного
+// останавливает остальные.
+func (m *Manager) Start(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for _, c := range m.consumers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := c.Start(ctx); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("topic %s: %w", c.Topic(), err))
+				mu.Unlock()
+				cancel()
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// HealthChecker объединяет проверки всех consumer'ов
+func (m *Manager) HealthChecker(maxLag int64) health.Checker {
+	checkers := make([]health.Checker, len(m.consumers))
+	for i, c := range m.consumers {
+		checkers[i] = c.HealthChecker(maxLag)
+	}
+	return health.CheckerFunc(func(ctx context.Context) error {
+		var errs []error
+		for i, check := range checkers {
+			if err := check.Check(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("topic %s: %w", m.consumers[i].Topic(), err))
+			}
+		}
+		return errors.Join(errs...)
+	})
+}
+
+// Pause ставит на паузу consumer'ы топика (все, если topic пустой)
+func (m *Manager) Pause(topic string) error {
+	return m.each(topic, (*Consumer).Pause)
+}
+
+// Resume возобновляет consumer'ы топика (все, если topic пустой)
+func (m *Manager) Resume(topic string) error {
+	return m.each(topic, (*Consumer).Resume)
+}
+
+// Drain останавливает consumer'ы топика (все, если topic пустой) параллельно
+func (m *Manager) Drain(ctx context.Context, topic string) error {
+	consumers, err := m.selectTopic(topic)
+	if err != nil {
+		return err
+	}
+
+	errs := make([]error, len(consumers))
+	var wg sync.WaitGroup
+	for i, c := range consumers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = c.Drain(ctx)
+		}()
+	}
+	wg.Wait()
+	// у всех consumer'ов один ctx: таймаут возвращаем как есть, без дублей
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return errors.Join(errs...)
+}
+
+// Status - состояние каждого consumer'а (по одному на reader)
+func (m *Manager) Status(ctx context.Context) []ConsumerStatus {
+	statuses := make([]ConsumerStatus, len(m.consumers))
+	var wg sync.WaitGroup
+	for i, c := range m.consumers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses[i] = c.Status(ctx)
+		}()
+	}
+	wg.Wait()
+	return statuses
+}
+
+func (m *Manager) each(topic string, fn func(*Consumer) error) error {
+	consumers, err := m.selectTopic(topic)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, c := range consumers {
+		if err := fn(c); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m *Manager) selectTopic(topic string) ([]*Consumer, error) {
+	if topic == "" {
+		return m.consumers, nil
+	}
+	var selected []*Consumer
+	for _, c := range m.consumers {
+		if c.Topic() == topic {
+			selected = append(selected, c)
+		}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownTopic, topic)
+	}
+	return selected, nil
+}
+
+// Close закрывает reader'ы и DLQ-writer'ы; вызывать после завершения Start
+func (m *Manager) Close() error {
+	var errs []error
+	for _, c := range m.consumers {
+		if err := c.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, d := range m.dlqs {
+		if err := d.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,605 @@
+package kafka_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Sergi-Ch/WB_L0_2025/domain"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/kafka"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/kafka/kafkatest"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/repository"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/service"
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+const (
+	testTopic = "orders"
+	testGroup = "order-service"
+	testDLQ   = "orders-dlq"
+)
+
+// e2e: настоящие Manager, Consumer, обработчик "order" и OrderService,
+// брокер - kafkatest.Broker, хранилище - repository.MemoryRepository
+
+func TestManagerConsumesAllPartitions(t *testing.T) {
+	broker := kafkatest.NewBroker()
+	broker.CreateTopic(testTopic, 3)
+	repo := repository.NewMemoryRepository(repository.MemoryOptions{})
+	for i := range 30 {
+		produceOrder(t, broker, orderUID(i))
+	}
+
+	m := newManager(t, broker, repo, kafka.TopicConfig{Topic: testTopic, Handler: "order"})
+	stop := start(t, m)
+
+	waitFor(t, broker, func() bool { return broker.Lag(testGroup, testTopic) == 0 })
+	if err := stop(); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+
+	if got := repo.Len(); got != 30 {
+		t.Errorf("saved %d orders, want 30", got)
+	}
+	committed := broker.Committed(testGroup, testTopic)
+	if len(committed) != 3 {
+		t.Errorf("committed partitions = %v, want 3", committed)
+	}
+	var total int64
+	for _, offset := range committed {
+		total += offset
+	}
+	if total != 30 {
+		t.Errorf("committed offsets sum = %d, want 30", total)
+	}
+}
+
+func TestManagerSendsInvalidMessageToDLQ(t *testing.T) {
+	broker := kafkatest.NewBroker()
+	repo := repository.NewMemoryRepository(repository.MemoryOptions{})
+	produceOrder(t, broker, orderUID(1))
+	if err := broker.Produce(testTopic, kafkago.Message{Key: []byte("broken"), Value: []byte("{not json")}); err != nil {
+		t.Fatal(err)
+	}
+	produceOrder(t, broker, orderUID(2))
+
+	m := newManager(t, broker, repo, kafka.TopicConfig{Topic: testTopic, Handler: "order", DLQTopic: testDLQ})
+	stop := start(t, m)
+
+	waitFor(t, broker, func() bool { return broker.Lag(testGroup, testTopic) == 0 })
+	if err := stop(); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+
+	if got := repo.Len(); got != 2 {
+		t.Errorf("saved %d orders, want 2", got)
+	}
+	dead := broker.Messages(testDLQ)
+	if len(dead) != 1 {
+		t.Fatalf("DLQ has %d messages, want 1", len(dead))
+	}
+	if string(dead[0].Key) != "broken" || string(dead[0].Value) != "{not json" {
+		t.Errorf("DLQ message = %q/%q, want original key and value", dead[0].Key, dead[0].Value)
+	}
+	headers := make(map[string]string)
+	for _, h := range dead[0].Headers {
+		headers[h.Key] = string(h.Value)
+	}
+	if headers[kafka.HeaderDLQTopic] != testTopic || headers[kafka.HeaderDLQOffset] != "1" || headers[kafka.HeaderDLQError] == "" {
+		t.Errorf("unexpected DLQ headers: %v", headers)
+	}
+}
+
+func TestManagerCancelsOrders(t *testing.T) {
+	const cancelTopic, cancelDLQ = "order-cancellations", "order-cancellations-dlq"
+	broker := kafkatest.NewBroker()
+	repo := repository.NewMemoryRepository(repository.MemoryOptions{})
+	ctx := context.Background()
+	for _, uid := range []string{orderUID(1), orderUID(2)} {
+		order := validOrder(uid)
+		if err := repo.SaveOrders(ctx, &order); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.SoftDeleteOrder(ctx, orderUID(2), domain.Deletion{Actor: "support"}); err != nil {
+		t.Fatal(err)
+	}
+
+	messages := []struct{ key, value string }{
+		{"cancel", `{"order_uid":"` + orderUID(1) + `","reason":"customer request"}`},
+		// повторная отмена подтверждается, а не уходит в DLQ
+		{"again", `{"order_uid":"` + orderUID(1) + `"}`},
+		{"already deleted", `{"order_uid":"` + orderUID(2) + `"}`},
+		{"unknown", `{"order_uid":"` + orderUID(3) + `"}`},
+		{"malformed", `{"order_uid":`},
+		{"empty uid", `{"reason":"no uid"}`},
+	}
+	for _, msg := range messages {
+		if err := broker.Produce(cancelTopic, kafkago.Message{Key: []byte(msg.key), Value: []byte(msg.value)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m := newManager(t, broker, repo, kafka.TopicConfig{Topic: cancelTopic, Handler: "order_cancel", DLQTopic: cancelDLQ})
+	stop := start(t, m)
+	waitFor(t, broker, func() bool { return broker.Lag(testGroup, cancelTopic) == 0 })
+	if err := stop(); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+
+	if d := deletion(t, repo, orderUID(1)); d == nil || d.Reason != "customer request" || d.Actor != "kafka:"+cancelTopic {
+		t.Errorf("deletion of %s = %+v", orderUID(1), d)
+	}
+	if d := deletion(t, repo, orderUID(2)); d == nil || d.Actor != "support" {
+		t.Errorf("repeated cancel overwrote deletion: %+v", d)
+	}
+
+	var dead []string
+	for _, m := range broker.Messages(cancelDLQ) {
+		dead = append(dead, string(m.Key))
+	}
+	if want := []string{"unknown", "malformed", "empty uid"}; !slices.Equal(dead, want) {
+		t.Errorf("DLQ keys = %v, want %v", dead, want)
+	}
+}
+
+func TestManagerSkipsRedeliveredOrders(t *testing.T) {
+	broker := kafkatest.NewBroker()
+	repo := repository.NewMemoryRepository(repository.MemoryOptions{})
+	// заказ уже сохранен до рестарта, но офсет не успели закоммитить
+	saved := validOrder(orderUID(1))
+	if err := repo.SaveOrders(context.Background(), &saved); err != nil {
+		t.Fatal(err)
+	}
+	produceOrder(t, broker, orderUID(1))
+	produceOrder(t, broker, orderUID(2))
+
+	m := newManager(t, broker, repo, kafka.TopicConfig{Topic: testTopic, Handler: "order", DLQTopic: testDLQ})
+	stop := start(t, m)
+	waitFor(t, broker, func() bool { return broker.Lag(testGroup, testTopic) == 0 })
+
+	if st := status(t, m)[0]; st.Failed != 0 || st.Handled != 2 {
+		t.Errorf("handled %d, failed %d, want 2 and 0", st.Handled, st.Failed)
+	}
+	if err := stop(); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+	if dead := broker.Messages(testDLQ); len(dead) != 0 {
+		t.Errorf("DLQ has %d messages, want none", len(dead))
+	}
+	if repo.Len() != 2 {
+		t.Errorf("saved %d orders, want 2", repo.Len())
+	}
+}
+
+// unavailable - ошибка соединения с базой, которую сервис считает ErrUnavailable
+var unavailable = &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+
+func TestManagerRetriesWhenStorageUnavailable(t *testing.T) {
+	broker := kafkatest.NewBroker()
+	// первые три попытки сохранения - база недоступна
+	var attempts atomic.Int32
+	repo := repository.NewMemoryRepository(repository.MemoryOptions{Err: func(op string) error {
+		if op == repository.OpSaveOrders && attempts.Add(1) <= 3 {
+			return unavailable
+		}
+		return nil
+	}})
+	produceOrder(t, broker, orderUID(1))
+	produceOrder(t, broker, orderUID(2))
+
+	m := newManager(t, broker, repo, kafka.TopicConfig{Topic: testTopic, Handler: "order", DLQTopic: testDLQ})
+	stop := start(t, m)
+	waitFor(t, broker, func() bool { return broker.Lag(testGroup, testTopic) == 0 })
+	if err := stop(); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+
+	if repo.Len() != 2 {
+		t.Errorf("saved %d orders, want 2", repo.Len())
+	}
+	if dead := broker.Messages(testDLQ); len(dead) != 0 {
+		t.Errorf("DLQ has %d messages, want none", len(dead))
+	}
+	// неудачные попытки не коммитятся: по коммиту на сообщение, по порядку
+	var offsets []int64
+	for _, c := range broker.Commits(testGroup) {
+		offsets = append(offsets, c.Offset)
+	}
+	if want := []int64{1, 2}; !slices.Equal(offsets, want) {
+		t.Errorf("commits = %v, want %v", offsets, want)
+	}
+}
+
+func TestManagerKeepsMessageWhileStorageUnavailable(t *testing.T) {
+	broker := kafkatest.NewBroker()
+	attempts := make(chan struct{}, 100)
+	repo := repository.NewMemoryRepository(repository.MemoryOptions{Err: func(string) error {
+		attempts <- struct{}{}
+		return unavailable
+	}})
+	produceOrder(t, broker, orderUID(1))
+
+	// без DLQ сообщение тоже не коммитится и не теряется
+	m := newManager(t, broker, repo, kafka.TopicConfig{Topic: testTopic, Handler: "order"})
+	stop := start(t, m)
+	for range 3 {
+		select {
+		case <-attempts:
+		case <-time.After(5 * time.Second):
+			t.Fatal("message was not retried")
+		}
+	}
+	if err := stop(); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+
+	if committed := broker.Committed(testGroup, testTopic); len(committed) != 0 {
+		t.Errorf("committed = %v, want nothing", committed)
+	}
+	if lag := broker.Lag(testGroup, testTopic); lag != 1 {
+		t.Errorf("lag = %d, want 1", lag)
+	}
+}
+
+func TestManagerStopsWhenDLQWriteFails(t *testing.T) {
+	broker := kafkatest.NewBroker()
+	repo := repository.NewMemoryRepository(repository.MemoryOptions{})
+	if err := broker.Produce(testTopic, kafkago.Message{Value: []byte("{not json")}); err != nil {
+		t.Fatal(err)
+	}
+	broker.FailWrite(testDLQ, errors.New("dlq unavailable"))
+
+	m := newManager(t, broker, repo, kafka.TopicConfig{Topic: testTopic, Handler: "order", DLQTopic: testDLQ})
+	err := runToCompletion(t, m)
+	if err == nil {
+		t.Fatal("Start returned nil, want DLQ error")
+	}
+	// не записали в DLQ - не коммитим, сообщение перечитается после рестарта
+	if committed := broker.Committed(testGroup, testTopic); len(committed) != 0 {
+		t.Errorf("committed = %v, want nothing", committed)
+	}
+}
+
+func TestManagerStopsOnFetchError(t *testing.T) {
+	broker := kafkatest.NewBroker()
+	broker.CreateTopic(testTopic, 1)
+	broker.FailFetch(testTopic, errors.New("connection reset"))
+
+	m := newManager(t, broker, repository.NewMemoryRepository(repository.MemoryOptions{}), kafka.TopicConfig{Topic: testTopic, Handler: "order"})
+	err := runToCompletion(t, m)
+	if err == nil {
+		t.Fatal("Start returned nil, want fetch error")
+	}
+
+	st := status(t, m)[0]
+	if st.LastError == nil {
+		t.Error("status has no last error")
+	}
+}
+
+func TestManagerStopsOnCommitError(t *testing.T) {
+	broker := kafkatest.NewBroker()
+	repo := repository.NewMemoryRepository(repository.MemoryOptions{})
+	produceOrder(t, broker, orderUID(1))
+	broker.FailCommit(testTopic, errors.New("not coordinator"))
+
+	m := newManager(t, broker, repo, kafka.TopicConfig{Topic: testTopic, Handler: "order"})
+	if err := runToCompletion(t, m); err == nil {
+		t.Fatal("Start returned nil, want commit error")
+	}
+	// заказ сохранен, но офсет не закоммичен: после рестарта будет дубликат
+	if repo.Len() != 1 {
+		t.Errorf("saved %d orders, want 1", repo.Len())
+	}
+	if lag := broker.Lag(testGroup, testTopic); lag != 1 {
+		t.Errorf("lag = %d, want 1", lag)
+	}
+}
+
+func TestManagerCommitsInFlightMessageOnShutdown(t *testing.T) {
+	broker := kafkatest.NewBroker()
+	// block задерживает сохранение, entered сигнализирует о его начале
+	block, entered := make(chan struct{}), make(chan struct{}, 1)
+	repo := repository.NewMemoryRepository(repository.MemoryOptions{Err: func(op string) error {
+		if op == repository.OpSaveOrders {
+			select {
+			case entered <- struct{}{}:
+			default:
+			}
+			<-block
+		}
+		return nil
+	}})
+	produceOrder(t, broker, orderUID(1))
+	produceOrder(t, broker, orderUID(2))
+
+	m := newManager(t, broker, repo, kafka.TopicConfig{Topic: testTopic, Handler: "order"})
+	stop := start(t, m)
+
+	<-entered
+	stopped := make(chan error, 1)
+	go func() { stopped <- stop() }()
+	// сервис останавливается, пока первый заказ еще сохраняется
+	time.Sleep(20 * time.Millisecond)
+	close(block)
+	if err := <-stopped; err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+
+	if got := broker.Committed(testGroup, testTopic)[0]; got != 1 {
+		t.Errorf("committed offset = %d, want 1 (in-flight message committed, next not read)", got)
+	}
+	if repo.Len() != 1 {
+		t.Errorf("saved %d orders, want 1", repo.Len())
+	}
+}
+
+func TestManagerRebalancesOnDrain(t *testing.T) {
+	broker := kafkatest.NewBroker()
+	broker.CreateTopic(testTopic, 4)
+	repo := repository.NewMemoryRepository(repository.MemoryOptions{})
+
+	m := newManager(t, broker, repo, kafka.TopicConfig{Topic: testTopic, Handler: "order", Concurrency: 2})
+	stop := start(t, m)
+
+	assignments := broker.Assignments(testGroup, testTopic)
+	if len(assignments) != 2 || len(assignments[0]) != 2 || len(assignments[1]) != 2 {
+		t.Fatalf("assignments = %v, want 2 partitions per reader", assignments)
+	}
+
+	for i := range 20 {
+		produceOrder(t, broker, orderUID(i))
+	}
+	waitFor(t, broker, func() bool { return repo.Len() >= 5 })
+
+	// drain закрывает оба reader'а посреди чтения: обработанное закоммичено
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := m.Drain(ctx, testTopic); err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+	if got := broker.Assignments(testGroup, testTopic); len(got) != 0 {
+		t.Errorf("assignments after drain = %v, want none", got)
+	}
+
+	// после resume reader'ы заново вступают в группу и дочитывают топик
+	if err := m.Resume(testTopic); err != nil {
+		t.Fatalf("Resume: %v", err)
+	}
+	for i := 20; i < 30; i++ {
+		produceOrder(t, broker, orderUID(i))
+	}
+	waitFor(t, broker, func() bool { return broker.Lag(testGroup, testTopic) == 0 })
+	if err := stop(); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+	if got := repo.Len(); got != 30 {
+		t.Errorf("saved %d orders, want 30", got)
+	}
+}
+
+func TestManagerPauseResume(t *testing.T) {
+	broker := kafkatest.NewBroker()
+	broker.CreateTopic(testTopic, 2)
+	repo := repository.NewMemoryRepository(repository.MemoryOptions{})
+
+	m := newManager(t, broker, repo, kafka.TopicConfig{Topic: testTopic, Handler: "order"})
+	stop := start(t, m)
+
+	produceOrder(t, broker, orderUID(1))
+	waitFor(t, broker, func() bool { return broker.Lag(testGroup, testTopic) == 0 })
+
+	if err := m.Pause(testTopic); err != nil {
+		t.Fatalf("Pause: %v", err)
+	}
+	for i := 2; i <= 5; i++ {
+		produceOrder(t, broker, orderUID(i))
+	}
+	time.Sleep(50 * time.Millisecond)
+	if lag := broker.Lag(testGroup, testTopic); lag != 4 {
+		t.Errorf("lag while paused = %d, want 4", lag)
+	}
+	if st := status(t, m)[0]; st.State != kafka.ConsumerPaused {
+		t.Errorf("state = %s, want paused", st.State)
+	}
+
+	if err := m.Resume(testTopic); err != nil {
+		t.Fatalf("Resume: %v", err)
+	}
+	waitFor(t, broker, func() bool { return broker.Lag(testGroup, testTopic) == 0 })
+	if err := stop(); err != nil {
+		t.Fatalf("Start returned error: %v", err)
+	}
+	if got := repo.Len(); got != 5 {
+		t.Errorf("saved %d orders, want 5", got)
+	}
+}
+
+func TestManagerRebalancesWhenReaderLeaves(t *testing.T) {
+	broker := kafkatest.NewBroker()
+	broker.CreateTopic(testTopic, 3)
+
+	first := broker.NewReader(kafkago.ReaderConfig{Topic: testTopic, GroupID: testGroup})
+	second := broker.NewReader(kafkago.ReaderConfig{Topic: testTopic, GroupID: testGroup})
+	if got := broker.Assignments(testGroup, testTopic); fmt.Sprint(got) != "[[0 2] [1]]" {
+		t.Fatalf("assignments = %v, want [[0 2] [1]]", got)
+	}
+
+	broker.ProduceTo(testTopic, 1, kafkago.Message{Value: []byte("a")})
+	broker.ProduceTo(testTopic, 1, kafkago.Message{Value: []byte("b")})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	m, err := second.FetchMessage(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := second.CommitMessages(ctx, m); err != nil {
+		t.Fatal(err)
+	}
+	// второй сообщение "b" прочитал, но не закоммитил и ушел из группы
+	if _, err := second.FetchMessage(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := broker.Assignments(testGroup, testTopic); fmt.Sprint(got) != "[[0 1 2]]" {
+		t.Fatalf("assignments after leave = %v, want [[0 1 2]]", got)
+	}
+	m, err = first.FetchMessage(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(m.Value) != "b" || m.Offset != 1 {
+		t.Errorf("first got %q at offset %d, want uncommitted \"b\" at 1", m.Value, m.Offset)
+	}
+	if err := first.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newManager(t *testing.T, broker *kafkatest.Broker, repo *repository.MemoryRepository, topics ...kafka.TopicConfig) *kafka.Manager {
+	t.Helper()
+	log := slog.New(slog.DiscardHandler)
+	handlers := kafka.NewHandlerRegistry()
+	orderService := service.NewOrderService(repo, repository.NewCache(), log)
+	handlers.Register("order", kafka.OrderHandlerFactory(orderService))
+	handlers.Register("order_cancel", kafka.OrderCancelHandlerFactory(orderService))
+
+	m, err := kafka.NewManager(kafka.ManagerConfig{
+		GroupID:   testGroup,
+		Topics:    topics,
+		NewReader: broker.NewReader,
+		NewWriter: broker.NewWriter,
+		// повтор при недоступном хранилище без долгих пауз
+		RetryBackoff: time.Millisecond,
+	}, handlers, log)
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	return m
+}
+
+// start запускает Manager в фоне; stop останавливает его, как serve при SIGTERM
+func start(t *testing.T, m *kafka.Manager) (stop func() error) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- m.Start(ctx) }()
+
+	var once sync.Once
+	var err error
+	stop = func() error {
+		once.Do(func() {
+			cancel()
+			select {
+			case err = <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("Start did not return after cancel")
+			}
+			if closeErr := m.Close(); closeErr != nil {
+				t.Errorf("Close: %v", closeErr)
+			}
+		})
+		return err
+	}
+	t.Cleanup(func() { _ = stop() })
+	return stop
+}
+
+// runToCompletion ждет, пока Start завершится сам (с ошибкой)
+func runToCompletion(t *testing.T, m *kafka.Manager) error {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := m.Start(ctx)
+	if ctx.Err() != nil {
+		t.Fatal("Start did not return before timeout")
+	}
+	if closeErr := m.Close(); closeErr != nil {
+		t.Errorf("Close: %v", closeErr)
+	}
+	return err
+}
+
+// status - состояние consumer'ов; запросы к брокеру у фейка падают, важно только состояние
+func status(t *testing.T, m *kafka.Manager) []kafka.ConsumerStatus {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	return m.Status(ctx)
+}
+
+func waitFor(t *testing.T, broker *kafkatest.Broker, cond func() bool) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := broker.WaitFor(ctx, cond); err != nil {
+		t.Fatalf("condition not met: %v", err)
+	}
+}
+
+func orderUID(i int) string {
+	return "e2e-order-" + strconv.Itoa(i)
+}
+
+func produceOrder(t *testing.T, broker *kafkatest.Broker, uid string) {
+	t.Helper()
+	value, err := json.Marshal(validOrder(uid))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := broker.Produce(testTopic, kafkago.Message{Key: []byte(uid), Value: value}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func validOrder(uid string) domain.Order {
+	return domain.Order{
+		OrderUid:    uid,
+		TrackNumber: "WBILMTESTTRACK",
+		Entry:       "WBIL",
+		Locale:      "en",
+		CustomerId:  "test",
+		DateCreated: time.Now().Add(-time.Hour).UTC(),
+		Delivery: domain.Delivery{
+			Name:  "Test Testov",
+			Phone: "+9720000000",
+			City:  "Kiryat Mozkin",
+			Email: "test@gmail.com",
+		},
+		Payment: domain.Payment{
+			Transaction: uid,
+			Currency:    "USD",
+			Amount:      1817,
+		},
+		Items: []domain.Item{{
+			ChrtId:      9934930,
+			TrackNumber: "WBILMTESTTRACK",
+			Price:       453,
+			Name:        "Mascaras",
+			TotalPrice:  317,
+		}},
+	}
+}
+
+// deletion - отметка об удалении заказа, nil если заказ не удален
+func deletion(t *testing.T, repo *repository.MemoryRepository, uid string) *domain.Deletion {
+	t.Helper()
+	order, err := repo.GetByID(context.Background(), uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return order.Deletion
+}
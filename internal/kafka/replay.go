@@ -0,0 +1,333 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Sergi-Ch/WB_L0_2025/internal/logger"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/repository"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/service"
+	"github.com/segmentio/kafka-go"
+)
+
+// ReplayRange задает диапазон перечитывания. Начало - FromTime, если оно
+// задано, иначе FromOffset; конец (не включительно) - ToTime, если задано,
+// иначе ToOffset, а при ToOffset < 0 - конец партиции на момент старта.
+type ReplayRange struct {
+	Topic      string
+	Partitions []int // пусто - все партиции топика
+	FromOffset int64
+	ToOffset   int64
+	FromTime   time.Time
+	ToTime     time.Time
+}
+
+// ReplayOutcome - чем закончилась обработка сообщения
+type ReplayOutcome string
+
+const (
+	ReplayAccepted  ReplayOutcome = "accepted"
+	ReplayRejected  ReplayOutcome = "rejected"  // не декодировалось или не прошло валидацию
+	ReplayDuplicate ReplayOutcome = "duplicate" // заказ уже сохранен
+	ReplayFailed    ReplayOutcome = "failed"    // прочие ошибки (например, недоступна база)
+)
+
+// ReplayResult - результат обработки одного сообщения
+type ReplayResult struct {
+	Partition int           `json:"partition"`
+	Offset    int64         `json:"offset"`
+	Key       string        `json:"key,omitempty"`
+	Outcome   ReplayOutcome `json:"outcome"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// PartitionReport - итог по одной партиции
+type PartitionReport struct {
+	Partition  int   `json:"partition"`
+	FromOffset int64 `json:"from_offset"`
+	ToOffset   int64 `json:"to_offset"`
+	Read       int   `json:"read"`
+	// Idle - чтение остановлено по IdleTimeout, не дойдя до ToOffset
+	Idle bool `json:"idle,omitempty"`
+}
+
+// ReplayReport - сводный отчет перечитывания
+type ReplayReport struct {
+	Topic      string            `json:"topic"`
+	DryRun     bool              `json:"dry_run"`
+	Partitions []PartitionReport `json:"partitions"`
+	Read       int               `json:"read"`
+	Accepted   int               `json:"accepted"`
+	Rejected   int               `json:"rejected"`
+	Duplicate  int               `json:"duplicate"`
+	Failed     int               `json:"failed"`
+	Duration   string            `json:"duration"`
+}
+
+func (r *ReplayReport) add(res ReplayResult) {
+	r.Read++
+	switch res.Outcome {
+	case ReplayAccepted:
+		r.Accepted++
+	case ReplayRejected:
+		r.Rejected++
+	case ReplayDuplicate:
+		r.Duplicate++
+	default:
+		r.Failed++
+	}
+}
+
+// DefaultReplayIdleTimeout - сколько ждать следующего сообщения партиции
+const DefaultReplayIdleTimeout = 10 * time.Second
+
+// Replayer перечитывает диапазоны партиций отдельными reader'ами без
+// consumer group: офсеты группы основного consumer'а не меняются и не коммитятся.
+type Replayer struct {
+	brokers []string
+	dialer  *kafka.Dialer
+	log     *slog.Logger
+
+	// DryRun только помечает отчет; какой handler использовать, решает вызывающий
+	DryRun bool
+	// IdleTimeout - сколько ждать следующего сообщения, прежде чем считать
+	// партицию прочитанной. Офсеты перед концом диапазона могут не
+	// существовать (compaction, маркеры транзакций), и без таймаута чтение
+	// ждало бы их вечно.
+	IdleTimeout time.Duration
+	// OnResult, если задан, вызывается для каждого обработанного сообщения
+	OnResult func(ReplayResult)
+}
+
+func NewReplayer(brokers []string, sec SecurityConfig, log *slog.Logger) (*Replayer, error) {
+	dialer, err := sec.Dialer("order-service-replay")
+	if err != nil {
+		return nil, err
+	}
+	return &Replayer{
+		brokers:     brokers,
+		dialer:      dialer,
+		log:         log.With(slog.String("component", "kafka_replay")),
+		IdleTimeout: DefaultReplayIdleTimeout,
+	}, nil
+}
+
+// Replay прогоняет каждое сообщение диапазона через handler. Ошибки
+// отдельных сообщений попадают в отчет и не прерывают перечитывание.
+func (r *Replayer) Replay(ctx context.Context, rng ReplayRange, handler Handler) (ReplayReport, error) {
+	start := time.Now()
+	report := ReplayReport{Topic: rng.Topic, DryRun: r.DryRun}
+	defer func() { report.Duration = time.Since(start).String() }()
+
+	partitions := rng.Partitions
+	if len(partitions) == 0 {
+		var err error
+		if partitions, err = r.topicPartitions(ctx, rng.Topic); err != nil {
+			return report, err
+		}
+	}
+
+	for _, p := range partitions {
+		pr, err := r.replayPartition(ctx, rng, p, handler, &report)
+		report.Partitions = append(report.Partitions, pr)
+		if err != nil {
+			return report, fmt.Errorf("partition %d: %w", p, err)
+		}
+	}
+	return report, nil
+}
+
+func (r *Replayer) replayPartition(ctx context.Context, rng ReplayRange, partition int, handler Handler, report *ReplayReport) (PartitionReport, error) {
+	pr := PartitionReport{Partition: partition}
+	log := r.log.With(slog.String("topic", rng.Topic), slog.Int("partition", partition))
+
+	from, to, err := r.resolveRange(ctx, rng, partition)
+	if err != nil {
+		return pr, err
+	}
+	pr.FromOffset, pr.ToOffset = from, to
+
+	log.InfoContext(ctx, "replay started", slog.Int64("from_offset", from), slog.Int64("to_offset", to))
+	if from >= to {
+		return pr, nil
+	}
+
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:   r.brokers,
+		Topic:     rng.Topic,
+		Partition: partition,
+		Dialer:    r.dialer,
+	})
+	defer reader.Close()
+
+	if err := reader.SetOffset(from); err != nil {
+		return pr, fmt.Errorf("failed to set replay start: %w", err)
+	}
+	return pr, r.readPartition(ctx, reader, &pr, handler, report, log)
+}
+
+// messageFetcher - чтение одной партиции (*kafka.Reader без группы)
+type messageFetcher interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+}
+
+// readPartition читает сообщения до pr.ToOffset (не включительно). Чтение
+// заканчивается и раньше, если IdleTimeout нет новых сообщений: pr.Idle.
+func (r *Replayer) readPartition(ctx context.Context, reader messageFetcher, pr *PartitionReport, handler Handler, report *ReplayReport, log *slog.Logger) error {
+	to := pr.ToOffset
+	for {
+		m, err := r.fetch(ctx, reader)
+		if err != nil {
+			if ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+				log.WarnContext(ctx, "no messages before the end of replay range, stopping",
+					slog.Int64("to_offset", to), slog.Duration("idle_timeout", r.IdleTimeout))
+				pr.Idle = true
+				return nil
+			}
+			return fmt.Errorf("failed to fetch message: %w", err)
+		}
+		if m.Offset >= to {
+			return nil
+		}
+		pr.Read++
+
+		msgCtx := messageContext(ctx, m)
+		res := ReplayResult{Partition: m.Partition, Offset: m.Offset, Key: string(m.Key)}
+		err = handler(msgCtx, m)
+		res.Outcome = classify(err)
+		if err != nil {
+			res.Error = err.Error()
+			log.DebugContext(msgCtx, "replayed message not accepted",
+				slog.Int64("offset", m.Offset),
+				slog.String("outcome", string(res.Outcome)),
+				logger.Err(err),
+			)
+		}
+
+		report.add(res)
+		if r.OnResult != nil {
+			r.OnResult(res)
+		}
+
+		if m.Offset >= to-1 {
+			return nil
+		}
+	}
+}
+
+// fetch - следующее сообщение с ожиданием не дольше IdleTimeout
+func (r *Replayer) fetch(ctx context.Context, reader messageFetcher) (kafka.Message, error) {
+	if r.IdleTimeout <= 0 {
+		return reader.FetchMessage(ctx)
+	}
+	fetchCtx, cancel := context.WithTimeout(ctx, r.IdleTimeout)
+	defer cancel()
+	return reader.FetchMessage(fetchCtx)
+}
+
+// resolveRange переводит время/офсеты в [from, to) с учетом реальных границ партиции
+func (r *Replayer) resolveRange(ctx context.Context, rng ReplayRange, partition int) (int64, int64, error) {
+	conn, err := r.dialLeader(ctx, rng.Topic, partition)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer conn.Close()
+	return partitionRange(conn, rng)
+}
+
+// partitionOffsets - границы партиции и поиск офсета по времени (*kafka.Conn)
+type partitionOffsets interface {
+	ReadOffsets() (first, last int64, err error)
+	ReadOffset(t time.Time) (int64, error)
+}
+
+// partitionRange ограничивает диапазон границами партиции: from - не раньше
+// первого сохраненного офсета, to - не дальше high watermark. Время позже
+// последнего сообщения (Kafka отвечает офсетом -1) - конец партиции.
+func partitionRange(conn partitionOffsets, rng ReplayRange) (int64, int64, error) {
+	first, last, err := conn.ReadOffsets()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read partition offsets: %w", err)
+	}
+
+	from := rng.FromOffset
+	if !rng.FromTime.IsZero() {
+		if from, err = conn.ReadOffset(rng.FromTime); err != nil {
+			return 0, 0, fmt.Errorf("failed to resolve start time: %w", err)
+		}
+		if from < 0 {
+			from = last
+		}
+	}
+	to := last
+	if !rng.ToTime.IsZero() {
+		if to, err = conn.ReadOffset(rng.ToTime); err != nil {
+			return 0, 0, fmt.Errorf("failed to resolve end time: %w", err)
+		}
+		if to < 0 {
+			to = last
+		}
+	} else if rng.ToOffset >= 0 {
+		to = rng.ToOffset
+	}
+
+	return max(from, first), min(to, last), nil
+}
+
+func (r *Replayer) topicPartitions(ctx context.Context, topic string) ([]int, error) {
+	conn, err := r.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	parts, err := conn.ReadPartitions(topic)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read partitions of %s: %w", topic, err)
+	}
+	ids := make([]int, 0, len(parts))
+	for _, p := range parts {
+		ids = append(ids, p.ID)
+	}
+	return ids, nil
+}
+
+func (r *Replayer) dial(ctx context.Context) (*kafka.Conn, error) {
+	var lastErr error
+	for _, broker := range r.brokers {
+		conn, err := r.dialer.DialContext(ctx, "tcp", broker)
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	return nil, fmt.Errorf("failed to dial kafka: %w", lastErr)
+}
+
+func (r *Replayer) dialLeader(ctx context.Context, topic string, partition int) (*kafka.Conn, error) {
+	var lastErr error
+	for _, broker := range r.brokers {
+		conn, err := r.dialer.DialLeader(ctx, "tcp", broker, topic, partition)
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	return nil, fmt.Errorf("failed to dial partition leader: %w", lastErr)
+}
+
+func classify(err error) ReplayOutcome {
+	switch {
+	case err == nil:
+		return ReplayAccepted
+	case errors.Is(err, repository.ErrAlreadyExists):
+		return ReplayDuplicate
+	case errors.Is(err, ErrInvalidMessage), errors.Is(err, service.ErrInvalidOrder):
+		return ReplayRejected
+	default:
+		return ReplayFailed
+	}
+}
@@ -0,0 +1,174 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/Sergi-Ch/WB_L0_2025/internal/repository"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/service"
+	"github.com/segmentio/kafka-go"
+)
+
+// fakeOffsets - партиция с офсетами [first, last) и поиском по времени
+type fakeOffsets struct {
+	first, last int64
+	byTime      map[time.Time]int64
+}
+
+func (f fakeOffsets) ReadOffsets() (int64, int64, error) { return f.first, f.last, nil }
+
+func (f fakeOffsets) ReadOffset(t time.Time) (int64, error) {
+	offset, ok := f.byTime[t]
+	if !ok {
+		return 0, errors.New("unexpected time")
+	}
+	return offset, nil
+}
+
+func TestPartitionRange(t *testing.T) {
+	day := time.Date(2025, 10, 18, 0, 0, 0, 0, time.UTC)
+	future := day.AddDate(1, 0, 0)
+	conn := fakeOffsets{first: 100, last: 500, byTime: map[time.Time]int64{
+		day:                200,
+		day.Add(time.Hour): 300,
+		// позже последнего сообщения Kafka отвечает -1
+		future: -1,
+	}}
+
+	tests := []struct {
+		name     string
+		rng      ReplayRange
+		from, to int64
+	}{
+		{"offsets", ReplayRange{FromOffset: 150, ToOffset: 250}, 150, 250},
+		{"to end of partition", ReplayRange{FromOffset: 150, ToOffset: -1}, 150, 500},
+		{"from before retention", ReplayRange{FromOffset: 0, ToOffset: -1}, 100, 500},
+		{"to beyond high watermark", ReplayRange{FromOffset: 150, ToOffset: 10_000}, 150, 500},
+		{"times", ReplayRange{FromTime: day, ToTime: day.Add(time.Hour)}, 200, 300},
+		{"time wins over offset", ReplayRange{FromOffset: 400, FromTime: day, ToOffset: -1}, 200, 500},
+		{"to time after last message", ReplayRange{FromTime: day, ToTime: future}, 200, 500},
+		{"from time after last message", ReplayRange{FromTime: future, ToOffset: -1}, 500, 500},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to, err := partitionRange(conn, tt.rng)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if from != tt.from || to != tt.to {
+				t.Errorf("partitionRange() = [%d, %d), want [%d, %d)", from, to, tt.from, tt.to)
+			}
+		})
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		err  error
+		want ReplayOutcome
+	}{
+		{nil, ReplayAccepted},
+		{fmt.Errorf("save: %w", repository.ErrAlreadyExists), ReplayDuplicate},
+		{fmt.Errorf("%w: unexpected EOF", ErrInvalidMessage), ReplayRejected},
+		{fmt.Errorf("%w: empty order_uid", service.ErrInvalidOrder), ReplayRejected},
+		{errors.New("connection refused"), ReplayFailed},
+	}
+	for _, tt := range tests {
+		if got := classify(tt.err); got != tt.want {
+			t.Errorf("classify(%v) = %s, want %s", tt.err, got, tt.want)
+		}
+	}
+}
+
+// fakeFetcher отдает сообщения по порядку, затем ждет отмены контекста
+type fakeFetcher struct {
+	messages []kafka.Message
+}
+
+func (f *fakeFetcher) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	if len(f.messages) == 0 {
+		<-ctx.Done()
+		return kafka.Message{}, ctx.Err()
+	}
+	m := f.messages[0]
+	f.messages = f.messages[1:]
+	return m, nil
+}
+
+func messagesAt(offsets ...int64) []kafka.Message {
+	messages := make([]kafka.Message, len(offsets))
+	for i, offset := range offsets {
+		messages[i] = kafka.Message{Offset: offset, Key: []byte(fmt.Sprint(offset))}
+	}
+	return messages
+}
+
+func TestReadPartition(t *testing.T) {
+	outcomes := map[string]error{
+		"11": repository.ErrAlreadyExists,
+		"12": ErrInvalidMessage,
+		"13": errors.New("db is down"),
+	}
+	handler := func(_ context.Context, m kafka.Message) error { return outcomes[string(m.Key)] }
+
+	tests := []struct {
+		name     string
+		messages []kafka.Message
+		to       int64
+		read     int
+		idle     bool
+	}{
+		{"stops at last offset", messagesAt(10, 11, 12, 13, 14), 14, 4, false},
+		{"stops past the end", messagesAt(10, 11, 12, 13, 20), 15, 4, false},
+		// последний офсет диапазона отсутствует (compaction, маркер транзакции)
+		{"gap at the end", messagesAt(10, 11, 12, 13), 15, 4, true},
+		{"empty partition", nil, 15, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Replayer{IdleTimeout: 20 * time.Millisecond}
+			var results []ReplayResult
+			r.OnResult = func(res ReplayResult) { results = append(results, res) }
+
+			pr := PartitionReport{FromOffset: 10, ToOffset: tt.to}
+			var report ReplayReport
+			done := make(chan error, 1)
+			go func() {
+				done <- r.readPartition(context.Background(), &fakeFetcher{messages: tt.messages}, &pr, handler, &report, slog.New(slog.DiscardHandler))
+			}()
+
+			select {
+			case err := <-done:
+				if err != nil {
+					t.Fatalf("readPartition() error = %v", err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("readPartition() did not stop")
+			}
+			if pr.Read != tt.read || pr.Idle != tt.idle || len(results) != tt.read {
+				t.Errorf("read %d (%d results), idle %v, want %d, %v", pr.Read, len(results), pr.Idle, tt.read, tt.idle)
+			}
+			if tt.read == 4 && (report.Accepted != 1 || report.Duplicate != 1 || report.Rejected != 1 || report.Failed != 1) {
+				t.Errorf("report = %+v, want one of each outcome", report)
+			}
+		})
+	}
+}
+
+func TestReadPartitionCancel(t *testing.T) {
+	r := &Replayer{IdleTimeout: time.Hour}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	pr := PartitionReport{ToOffset: 10}
+	err := r.readPartition(ctx, &fakeFetcher{}, &pr, func(context.Context, kafka.Message) error { return nil },
+		&ReplayReport{}, slog.New(slog.DiscardHandler))
+	// отмена запуска - ошибка, а не остановка по простою
+	if !errors.Is(err, context.Canceled) || pr.Idle {
+		t.Errorf("readPartition() with canceled context = %v, idle %v", err, pr.Idle)
+	}
+}
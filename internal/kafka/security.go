@@ -0,0 +1,147 @@
+package kafka
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+)
+
+// dialTimeout совпадает с таймаутом kafka.DefaultDialer
+const dialTimeout = 10 * time.Second
+
+// TLSConfig - TLS до брокеров. Пути к файлам в PEM.
+type TLSConfig struct {
+	Enabled  bool
+	CAFile   string // пусто - системные корневые сертификаты
+	CertFile string // клиентский сертификат для mTLS
+	KeyFile  string
+	// ServerName - имя для проверки сертификата брокера, если оно
+	// отличается от адреса подключения
+	ServerName         string
+	InsecureSkipVerify bool
+}
+
+// SASLConfig - аутентификация SASL. Пароль берется из PasswordFile, если он задан.
+type SASLConfig struct {
+	Mechanism    string // "", plain, scram-sha-256, scram-sha-512
+	Username     string
+	Password     string
+	PasswordFile string
+}
+
+// SecurityConfig - настройки подключения, общие для consumer'а, replay и продюсеров
+type SecurityConfig struct {
+	TLS  TLSConfig
+	SASL SASLConfig
+}
+
+// Dialer возвращает dialer для Reader и Conn с TLS/SASL из конфигурации
+func (s SecurityConfig) Dialer(clientID string) (*kafka.Dialer, error) {
+	tlsCfg, mechanism, err := s.build()
+	if err != nil {
+		return nil, err
+	}
+	return &kafka.Dialer{
+		ClientID:      clientID,
+		Timeout:       dialTimeout,
+		DualStack:     true,
+		TLS:           tlsCfg,
+		SASLMechanism: mechanism,
+	}, nil
+}
+
+// Transport возвращает транспорт для kafka.Writer и kafka.Client
+func (s SecurityConfig) Transport(clientID string) (*kafka.Transport, error) {
+	tlsCfg, mechanism, err := s.build()
+	if err != nil {
+		return nil, err
+	}
+	return &kafka.Transport{
+		ClientID:    clientID,
+		DialTimeout: dialTimeout,
+		TLS:         tlsCfg,
+		SASL:        mechanism,
+	}, nil
+}
+
+func (s SecurityConfig) build() (*tls.Config, sasl.Mechanism, error) {
+	tlsCfg, err := s.TLS.config()
+	if err != nil {
+		return nil, nil, fmt.Errorf("kafka tls: %w", err)
+	}
+	mechanism, err := s.SASL.mechanism()
+	if err != nil {
+		return nil, nil, fmt.Errorf("kafka sasl: %w", err)
+	}
+	return tlsCfg, mechanism, nil
+}
+
+func (c TLSConfig) config() (*tls.Config, error) {
+	if !c.Enabled {
+		return nil, nil
+	}
+
+	cfg := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", c.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+	if c.CertFile != "" || c.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+func (c SASLConfig) mechanism() (sasl.Mechanism, error) {
+	if c.Mechanism == "" || c.Mechanism == "none" {
+		return nil, nil
+	}
+
+	password := c.Password
+	if c.PasswordFile != "" {
+		data, err := os.ReadFile(c.PasswordFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read password file: %w", err)
+		}
+		// файлы секретов обычно заканчиваются переводом строки
+		password = strings.TrimRight(string(data), "\r\n")
+	}
+	if c.Username == "" || password == "" {
+		return nil, errors.New("username and password are required")
+	}
+
+	switch strings.ToLower(c.Mechanism) {
+	case "plain":
+		return plain.Mechanism{Username: c.Username, Password: password}, nil
+	case "scram-sha-256":
+		return scram.Mechanism(scram.SHA256, c.Username, password)
+	case "scram-sha-512":
+		return scram.Mechanism(scram.SHA512, c.Username, password)
+	default:
+		return nil, fmt.Errorf("unsupported mechanism %q", c.Mechanism)
+	}
+}
@@ -0,0 +1,100 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestRequestIDInLogs(t *testing.T) {
+	var buf bytes.Buffer
+	log, err := New(&buf, "info", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := RequestIDMiddleware(AccessLog(log)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.With(slog.String("component", "test")).InfoContext(r.Context(), "handling")
+	})))
+
+	tests := []struct {
+		name   string
+		header string
+		want   string // "" - сгенерированный
+	}{
+		{"from header", "req-123", "req-123"},
+		{"generated", "", ""},
+		{"too long", strings.Repeat("x", maxRequestIDLen+1), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+			req := httptest.NewRequest(http.MethodGet, "/order/1", nil)
+			if tt.header != "" {
+				req.Header.Set(RequestIDHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			id := w.Header().Get(RequestIDHeader)
+			generated := tt.want == "" && id != "" && id != tt.header
+			if id != tt.want && !generated {
+				t.Fatalf("%s = %q", RequestIDHeader, id)
+			}
+			// и запись обработчика (через With), и access log
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			if len(lines) != 2 {
+				t.Fatalf("got %d log records, want 2:\n%s", len(lines), buf.String())
+			}
+			for _, line := range lines {
+				var record map[string]any
+				if err := json.Unmarshal([]byte(line), &record); err != nil {
+					t.Fatal(err)
+				}
+				if record["request_id"] != id {
+					t.Errorf("record %q has request_id %v, want %s", record["msg"], record["request_id"], id)
+				}
+			}
+		})
+	}
+}
+
+func TestTraceIDInLogs(t *testing.T) {
+	var buf bytes.Buffer
+	log, err := New(&buf, "debug", "text")
+	if err != nil {
+		t.Fatal(err)
+	}
+	traceID := trace.TraceID{1, 2, 3}
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  trace.SpanID{4},
+	}))
+
+	log.DebugContext(ctx, "with span")
+	log.Debug("without context")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], "trace_id="+traceID.String()) {
+		t.Errorf("no trace_id in record with span:\n%s", buf.String())
+	}
+	if strings.Contains(lines[len(lines)-1], "trace_id") || strings.Contains(lines[len(lines)-1], "request_id") {
+		t.Errorf("correlation ids in record without context: %s", lines[len(lines)-1])
+	}
+}
+
+func TestNewInvalid(t *testing.T) {
+	if _, err := New(&bytes.Buffer{}, "verbose", "json"); err == nil {
+		t.Error("New accepted an unknown level")
+	}
+	if _, err := New(&bytes.Buffer{}, "info", "xml"); err == nil {
+		t.Error("New accepted an unknown format")
+	}
+}
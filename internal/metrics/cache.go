@@ -0,0 +1,45 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/Sergi-Ch/WB_L0_2025/domain"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/repository"
+)
+
+// cache - декоратор над RedisInterface, снимающий hit/miss и латентность
+type cache struct {
+	repository.RedisInterface
+	m *Metrics
+}
+
+// InstrumentCache оборачивает кэш заказов метриками
+func (m *Metrics) InstrumentCache(next repository.RedisInterface) repository.RedisInterface {
+	return &cache{RedisInterface: next, m: m}
+}
+
+func (c *cache) Get(ctx context.Context, orderUID string) (*domain.Order, bool) {
+	start := time.Now()
+	order, ok := c.RedisInterface.Get(ctx, orderUID)
+	c.m.cacheDuration.WithLabelValues("get").Observe(time.Since(start).Seconds())
+
+	if ok {
+		c.m.cacheHits.Inc()
+	} else {
+		c.m.cacheMisses.Inc()
+	}
+	return order, ok
+}
+
+func (c *cache) Set(ctx context.Context, orderUID string, order domain.Order) {
+	start := time.Now()
+	c.RedisInterface.Set(ctx, orderUID, order)
+	c.m.cacheDuration.WithLabelValues("set").Observe(time.Since(start).Seconds())
+}
+
+func (c *cache) Delete(ctx context.Context, orderUID string) {
+	start := time.Now()
+	c.RedisInterface.Delete(ctx, orderUID)
+	c.m.cacheDuration.WithLabelValues("delete").Observe(time.Since(start).Seconds())
+}
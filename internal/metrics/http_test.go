@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestHTTPMiddlewareRouteLabel(t *testing.T) {
+	m := New()
+	r := chi.NewRouter()
+	r.Use(m.HTTPMiddleware)
+	r.Route("/api/v2", func(r chi.Router) {
+		r.Get("/order/{order_uid}", func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		})
+	})
+
+	for _, path := range []string{"/api/v2/order/b563feb7b2b84b6test", "/api/v2/order/other-order", "/missing"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, _ := io.ReadAll(w.Body)
+	out := string(body)
+
+	// оба заказа - одна серия по шаблону маршрута
+	if want := `order_service_http_requests_total{method="GET",route="/api/v2/order/{order_uid}",status="404"} 2`; !strings.Contains(out, want) {
+		t.Errorf("metrics have no %s:\n%s", want, out)
+	}
+	// путь без маршрута не заводит серию на каждый URL
+	if want := `route="unmatched"`; !strings.Contains(out, want) {
+		t.Errorf("metrics have no %s", want)
+	}
+	if strings.Contains(out, "b563feb7b2b84b6test") || strings.Contains(out, "other-order") {
+		t.Error("order_uid leaked into metric labels")
+	}
+}
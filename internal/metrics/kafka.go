@@ -0,0 +1,32 @@
+package metrics
+
+import (
+	"context"
+	"strconv"
+
+	internalKafka "github.com/Sergi-Ch/WB_L0_2025/internal/kafka"
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaMiddleware считает обработанные/упавшие сообщения и лаг по партициям
+func (m *Metrics) KafkaMiddleware(next internalKafka.Handler) internalKafka.Handler {
+	return func(ctx context.Context, msg kafka.Message) error {
+		partition := strconv.Itoa(msg.Partition)
+
+		err := next(ctx, msg)
+		if err != nil {
+			m.kafkaFailed.WithLabelValues(msg.Topic, partition).Inc()
+		} else {
+			m.kafkaConsumed.WithLabelValues(msg.Topic, partition).Inc()
+		}
+
+		if msg.HighWaterMark > 0 {
+			lag := msg.HighWaterMark - msg.Offset - 1
+			if lag < 0 {
+				lag = 0
+			}
+			m.kafkaLag.WithLabelValues(msg.Topic, partition).Set(float64(lag))
+		}
+		return err
+	}
+}
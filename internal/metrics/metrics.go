@@ -0,0 +1,118 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "order_service"
+
+// Metrics хранит все коллекторы сервиса в собственном registry
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+
+	kafkaConsumed *prometheus.CounterVec
+	kafkaFailed   *prometheus.CounterVec
+	kafkaLag      *prometheus.GaugeVec
+
+	cacheHits     prometheus.Counter
+	cacheMisses   prometheus.Counter
+	cacheDuration *prometheus.HistogramVec
+
+	serviceDuration *prometheus.HistogramVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "Number of HTTP requests by route pattern, method and status.",
+		}, []string{"route", "method", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "HTTP request latency by route pattern, method and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+
+		kafkaConsumed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "kafka",
+			Name:      "messages_consumed_total",
+			Help:      "Number of Kafka messages handled successfully.",
+		}, []string{"topic", "partition"}),
+		kafkaFailed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "kafka",
+			Name:      "messages_failed_total",
+			Help:      "Number of Kafka messages whose handling failed.",
+		}, []string{"topic", "partition"}),
+		kafkaLag: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "kafka",
+			Name:      "partition_lag",
+			Help:      "Messages left in the partition after the last consumed one.",
+		}, []string{"topic", "partition"}),
+
+		cacheHits: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "cache",
+			Name:      "hits_total",
+			Help:      "Number of order cache hits.",
+		}),
+		cacheMisses: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "cache",
+			Name:      "misses_total",
+			Help:      "Number of order cache misses.",
+		}),
+		cacheDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "cache",
+			Name:      "operation_duration_seconds",
+			Help:      "Order cache operation latency.",
+			Buckets:   []float64{.0001, .0005, .001, .0025, .005, .01, .025, .05, .1},
+		}, []string{"operation"}),
+
+		serviceDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "service",
+			Name:      "operation_duration_seconds",
+			Help:      "OrderService operation latency by result.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"operation", "result"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests, m.httpDuration,
+		m.kafkaConsumed, m.kafkaFailed, m.kafkaLag,
+		m.cacheHits, m.cacheMisses, m.cacheDuration,
+		m.serviceDuration,
+	)
+	return m
+}
+
+// Handler отдает метрики в формате Prometheus (GET /metrics)
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+func result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
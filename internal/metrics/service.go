@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/Sergi-Ch/WB_L0_2025/domain"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/service"
+)
+
+// orderService - декоратор над OrderServiceInterface, снимающий латентность операций
+type orderService struct {
+	service.OrderServiceInterface
+	m *Metrics
+}
+
+// InstrumentService оборачивает сервис заказов метриками
+func (m *Metrics) InstrumentService(next service.OrderServiceInterface) service.OrderServiceInterface {
+	return &orderService{OrderServiceInterface: next, m: m}
+}
+
+func (s *orderService) SaveOrder(ctx context.Context, order *domain.Order) error {
+	start := time.Now()
+	err := s.OrderServiceInterface.SaveOrder(ctx, order)
+	s.m.serviceDuration.WithLabelValues("save_order", result(err)).Observe(time.Since(start).Seconds())
+	return err
+}
+
+func (s *orderService) GetOrderByID(ctx context.Context, id string, opts service.GetOptions) (*domain.Order, error) {
+	start := time.Now()
+	order, err := s.OrderServiceInterface.GetOrderByID(ctx, id, opts)
+	s.m.serviceDuration.WithLabelValues("get_order_by_id", result(err)).Observe(time.Since(start).Seconds())
+	return order, err
+}
+
+func (s *orderService) DeleteOrder(ctx context.Context, id, reason, actor string) error {
+	start := time.Now()
+	err := s.OrderServiceInterface.DeleteOrder(ctx, id, reason, actor)
+	s.m.serviceDuration.WithLabelValues("delete_order", result(err)).Observe(time.Since(start).Seconds())
+	return err
+}
+
+func (s *orderService) UpdateDelivery(ctx context.Context, id string, patch []byte, version int64, actor string) (*domain.Order, error) {
+	start := time.Now()
+	order, err := s.OrderServiceInterface.UpdateDelivery(ctx, id, patch, version, actor)
+	s.m.serviceDuration.WithLabelValues("update_delivery", result(err)).Observe(time.Since(start).Seconds())
+	return order, err
+}
@@ -0,0 +1,128 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Migration - один файл NNN_name.up.sql
+type Migration struct {
+	Version string
+	Path    string
+}
+
+// Load читает список *.up.sql из каталога, отсортированный по имени
+func Load(dir string) ([]Migration, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.up.sql"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list migrations: %w", err)
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no *.up.sql migrations found in %s", dir)
+	}
+	sort.Strings(paths)
+
+	migrations := make([]Migration, 0, len(paths))
+	for _, p := range paths {
+		migrations = append(migrations, Migration{
+			Version: strings.TrimSuffix(filepath.Base(p), ".up.sql"),
+			Path:    p,
+		})
+	}
+	return migrations, nil
+}
+
+// database - то, чем отличаются Postgres (pgx) и SQLite (database/sql)
+type database interface {
+	ensureTable(ctx context.Context) error
+	versions(ctx context.Context) ([]string, error)
+	// apply выполняет миграцию и записывает ее версию в одной транзакции
+	apply(ctx context.Context, version, sql string) error
+}
+
+// Up применяет еще не примененные миграции из dir, каждую в своей транзакции,
+// и записывает их версии в schema_migrations. Возвращает примененные версии.
+func Up(ctx context.Context, pool *pgxpool.Pool, dir string, log *slog.Logger) ([]string, error) {
+	return up(ctx, pgxDatabase{pool}, dir, log)
+}
+
+func up(ctx context.Context, db database, dir string, log *slog.Logger) ([]string, error) {
+	migrations, err := Load(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.ensureTable(ctx); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	versions, err := db.versions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	done := make(map[string]bool, len(versions))
+	for _, v := range versions {
+		done[v] = true
+	}
+
+	var applied []string
+	for _, m := range migrations {
+		if done[m.Version] {
+			continue
+		}
+		sql, err := os.ReadFile(m.Path)
+		if err != nil {
+			return applied, fmt.Errorf("failed to read migration %s: %w", m.Version, err)
+		}
+		if err := db.apply(ctx, m.Version, string(sql)); err != nil {
+			return applied, err
+		}
+		log.InfoContext(ctx, "migration applied", slog.String("version", m.Version))
+		applied = append(applied, m.Version)
+	}
+	return applied, nil
+}
+
+type pgxDatabase struct {
+	pool *pgxpool.Pool
+}
+
+func (d pgxDatabase) ensureTable(ctx context.Context) error {
+	_, err := d.pool.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    VARCHAR PRIMARY KEY,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)`)
+	return err
+}
+
+func (d pgxDatabase) versions(ctx context.Context) ([]string, error) {
+	rows, err := d.pool.Query(ctx, `SELECT version FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+func (d pgxDatabase) apply(ctx context.Context, version, sql string) error {
+	tx, err := d.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, sql); err != nil {
+		return fmt.Errorf("failed to execute migration %s: %w", version, err)
+	}
+	if _, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version) VALUES ($1)`, version); err != nil {
+		return fmt.Errorf("failed to record migration %s: %w", version, err)
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,185 @@
+package pii
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Sergi-Ch/WB_L0_2025/domain"
+)
+
+// Режимы поля в pii.fields
+const (
+	ModeShow = "show" // отдавать как есть
+	ModeMask = "mask" // оставить первые и последние символы: +7999***2233, j***@example.com
+	ModeOmit = "omit" // убрать поле из ответа
+)
+
+// Fields - поля заказа с персональными данными; пути - как в JSON заказа,
+// одинаковые в /api/v1, /api/v2 и экспорте
+var Fields = []string{
+	"delivery.name", "delivery.phone", "delivery.zip", "delivery.city",
+	"delivery.address", "delivery.region", "delivery.email",
+	"payment.transaction", "payment.request_id", "payment.bank",
+	"customer_id",
+}
+
+// Policy скрывает персональные данные в заказах и истории доставки
+type Policy struct {
+	// rules - путь поля -> mask или omit
+	rules map[string]string
+}
+
+// NewPolicy - fields: путь из Fields -> show, mask или omit
+func NewPolicy(fields map[string]string) (*Policy, error) {
+	p := &Policy{rules: make(map[string]string)}
+	for path, mode := range fields {
+		if !slices.Contains(Fields, path) {
+			return nil, fmt.Errorf("unknown pii field %q (expected one of %s)", path, strings.Join(Fields, ", "))
+		}
+		switch mode {
+		case ModeShow:
+		case ModeMask, ModeOmit:
+			p.rules[path] = mode
+		default:
+			return nil, fmt.Errorf("pii field %s: mode must be show, mask or omit, got %q", path, mode)
+		}
+	}
+	return p, nil
+}
+
+// Rules - поля, которые политика скрывает, с режимами (для лога при старте)
+func (p *Policy) Rules() []string {
+	rules := make([]string, 0, len(p.rules))
+	for path, mode := range p.rules {
+		rules = append(rules, path+"="+mode)
+	}
+	sort.Strings(rules)
+	return rules
+}
+
+// Order возвращает заказ (domain.Order или его представление в версии API)
+// в виде JSON-объекта без персональных данных
+func (p *Policy) Order(order any) (any, error) {
+	var doc map[string]any
+	if err := roundTrip(order, &doc); err != nil {
+		return nil, err
+	}
+	p.apply(doc, "")
+	return doc, nil
+}
+
+// History - то же для истории доставки: правила delivery.* применяются к before и after
+func (p *Policy) History(changes []domain.DeliveryChange) (any, error) {
+	var docs []map[string]any
+	if err := roundTrip(changes, &docs); err != nil {
+		return nil, err
+	}
+	for _, doc := range docs {
+		for _, key := range []string{"before", "after"} {
+			if delivery, ok := doc[key].(map[string]any); ok {
+				p.apply(delivery, "delivery.")
+			}
+		}
+	}
+	return docs, nil
+}
+
+// apply применяет правила с путями, начинающимися с prefix, к объекту doc
+func (p *Policy) apply(doc map[string]any, prefix string) {
+	for path, mode := range p.rules {
+		rest, ok := strings.CutPrefix(path, prefix)
+		if !ok {
+			continue
+		}
+		parts := strings.Split(rest, ".")
+		obj := doc
+		for _, part := range parts[:len(parts)-1] {
+			if obj, ok = obj[part].(map[string]any); !ok {
+				break
+			}
+		}
+		if obj == nil {
+			continue
+		}
+		field := parts[len(parts)-1]
+		value, ok := obj[field].(string)
+		if !ok {
+			continue
+		}
+		if mode == ModeOmit {
+			delete(obj, field)
+		} else {
+			obj[field] = Mask(field, value)
+		}
+	}
+}
+
+// Mask скрывает значение поля field: телефон - кроме кода и последних
+// четырех цифр, email - кроме первой буквы и домена, остальное - кроме
+// первой буквы каждого слова
+func Mask(field, value string) string {
+	if value == "" {
+		return ""
+	}
+	switch field {
+	case "phone":
+		return maskPhone(value)
+	case "email":
+		return maskEmail(value)
+	default:
+		return maskWords(value)
+	}
+}
+
+func maskPhone(phone string) string {
+	r := []rune(phone)
+	if len(r) < 10 {
+		return maskTail(r, 2)
+	}
+	return string(r[:5]) + "***" + string(r[len(r)-4:])
+}
+
+func maskEmail(email string) string {
+	local, domain, ok := strings.Cut(email, "@")
+	if !ok || local == "" {
+		return maskWords(email)
+	}
+	first, _ := utf8.DecodeRuneInString(local)
+	return string(first) + "***@" + domain
+}
+
+func maskWords(s string) string {
+	words := strings.Fields(s)
+	for i, w := range words {
+		first, _ := utf8.DecodeRuneInString(w)
+		words[i] = string(first) + "***"
+	}
+	return strings.Join(words, " ")
+}
+
+// maskTail оставляет только последние n символов
+func maskTail(r []rune, n int) string {
+	if len(r) <= n {
+		return "***"
+	}
+	return "***" + string(r[len(r)-n:])
+}
+
+func roundTrip(v, out any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode for pii masking: %w", err)
+	}
+	// числа остаются json.Number, чтобы int64 не терял точность через float64
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(out); err != nil {
+		return fmt.Errorf("failed to decode for pii masking: %w", err)
+	}
+	return nil
+}
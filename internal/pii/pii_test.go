@@ -0,0 +1,116 @@
+package pii
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Sergi-Ch/WB_L0_2025/domain"
+)
+
+func TestMask(t *testing.T) {
+	tests := []struct {
+		field string
+		value string
+		want  string
+	}{
+		{"phone", "+79991112233", "+7999***2233"},
+		{"phone", "12345", "***45"},
+		{"phone", "7", "***"},
+		{"email", "john@example.com", "j***@example.com"},
+		{"email", "юля@почта.рф", "ю***@почта.рф"},
+		{"email", "not-an-email", "n***"},
+		{"name", "Test Testov", "T*** T***"},
+		{"address", "Ploshad Mira 15", "P*** M*** 1***"},
+		{"bank", "", ""},
+	}
+	for _, tt := range tests {
+		if got := Mask(tt.field, tt.value); got != tt.want {
+			t.Errorf("Mask(%s, %q) = %q, want %q", tt.field, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	p, err := NewPolicy(map[string]string{
+		"delivery.phone": ModeMask,
+		"delivery.email": ModeMask,
+		"delivery.zip":   ModeOmit,
+		"delivery.city":  ModeShow,
+		"payment.bank":   ModeOmit,
+		"customer_id":    ModeMask,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	order := domain.Order{
+		OrderUid:   "test-1",
+		CustomerId: "test",
+		Delivery:   domain.Delivery{Name: "Test Testov", Phone: "+9720000000", Zip: "2639809", City: "Kiryat Mozkin", Email: "test@gmail.com"},
+		Payment:    domain.Payment{Bank: "alpha", Amount: 1 << 60},
+		Version:    3,
+	}
+	masked, err := p.Order(&order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(masked)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got domain.Order
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	want := order
+	want.CustomerId = "t***"
+	want.Delivery = domain.Delivery{Name: "Test Testov", Phone: "+9720***0000", City: "Kiryat Mozkin", Email: "t***@gmail.com"}
+	want.Payment.Bank = ""
+	if got.CustomerId != want.CustomerId || got.Delivery != want.Delivery || got.Payment != want.Payment || got.Version != 3 {
+		t.Errorf("masked order = %+v, want %+v", got, want)
+	}
+	var raw struct {
+		Delivery map[string]any `json:"delivery"`
+		Payment  map[string]any `json:"payment"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := raw.Delivery["zip"]; ok {
+		t.Error("omitted delivery.zip is present")
+	}
+	if _, ok := raw.Payment["bank"]; ok {
+		t.Error("omitted payment.bank is present")
+	}
+	if order.Delivery.Phone != "+9720000000" {
+		t.Error("Order modified the original order")
+	}
+
+	history, err := p.History([]domain.DeliveryChange{{
+		Version: 2, ChangedAt: time.Now(),
+		Before: domain.Delivery{Phone: "+79991112233", Zip: "101000"},
+		After:  domain.Delivery{Phone: "+79994445566", Zip: "101001"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes := history.([]map[string]any)
+	before, after := changes[0]["before"].(map[string]any), changes[0]["after"].(map[string]any)
+	if before["phone"] != "+7999***2233" || after["phone"] != "+7999***5566" {
+		t.Errorf("history phones = %v, %v", before["phone"], after["phone"])
+	}
+	if _, ok := after["zip"]; ok {
+		t.Error("omitted zip is present in history")
+	}
+}
+
+func TestNewPolicyErrors(t *testing.T) {
+	if _, err := NewPolicy(map[string]string{"delivery.passport": ModeMask}); err == nil {
+		t.Error("unknown field accepted")
+	}
+	if _, err := NewPolicy(map[string]string{"delivery.phone": "hide"}); err == nil {
+		t.Error("unknown mode accepted")
+	}
+}
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"github.com/Sergi-Ch/WB_L0_2025/domain"
 	"sync"
 )
@@ -11,8 +12,9 @@ type Cache struct {
 }
 
 type CacheInterface interface {
-	Get(orderUID string) (*domain.Order, bool)
-	Set(orderUID string, order domain.Order)
+	Get(ctx context.Context, orderUID string) (*domain.Order, bool)
+	Set(ctx context.Context, orderUID string, order domain.Order)
+	Delete(ctx context.Context, orderUID string)
 }
 
 func NewCache() *Cache {
@@ -21,7 +23,7 @@ func NewCache() *Cache {
 	}
 }
 
-func (c *Cache) Get(orderUID string) (*domain.Order, bool) {
+func (c *Cache) Get(_ context.Context, orderUID string) (*domain.Order, bool) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
@@ -29,8 +31,14 @@ func (c *Cache) Get(orderUID string) (*domain.Order, bool) {
 	return &order, ok
 }
 
-func (c *Cache) Set(orderUID string, order domain.Order) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+func (c *Cache) Set(_ context.Context, orderUID string, order domain.Order) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.orders[orderUID] = order
 }
+
+func (c *Cache) Delete(_ context.Context, orderUID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.orders, orderUID)
+}
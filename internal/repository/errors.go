@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"database/sql/driver"
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrAlreadyExists - заказ с таким order_uid уже сохранен
+var ErrAlreadyExists = errors.New("order already exists")
+
+// ErrNotFound - заказа с таким order_uid нет
+var ErrNotFound = errors.New("order not found")
+
+// ErrAlreadyDeleted - заказ уже помечен удаленным
+var ErrAlreadyDeleted = errors.New("order already deleted")
+
+// ErrVersionConflict - версия заказа изменилась с момента его чтения
+var ErrVersionConflict = errors.New("order version conflict")
+
+// IsUnavailable - ошибка вызвана недоступностью хранилища (нет соединения,
+// таймаут, перегрузка), а не самим запросом: его можно повторить позже
+func IsUnavailable(err error) bool {
+	if err == nil {
+		return false
+	}
+	var connErr *pgconn.ConnectError
+	if errors.As(err, &connErr) || pgconn.Timeout(err) || errors.Is(err, driver.ErrBadConn) {
+		return true
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// 08 - connection exception, 53 - insufficient resources,
+		// 57P01..57P03 - сервер останавливается или еще не готов
+		return strings.HasPrefix(pgErr.Code, "08") || strings.HasPrefix(pgErr.Code, "53") ||
+			pgErr.Code == "57P01" || pgErr.Code == "57P02" || pgErr.Code == "57P03"
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/Sergi-Ch/WB_L0_2025/domain"
+)
+
+// marshalChange - доставка до и после изменения для delivery_history
+func marshalChange(before, after domain.Delivery) (string, string, error) {
+	b, err := json.Marshal(before)
+	if err != nil {
+		return "", "", fmt.Errorf("marshal delivery failed: %w", err)
+	}
+	a, err := json.Marshal(after)
+	if err != nil {
+		return "", "", fmt.Errorf("marshal delivery failed: %w", err)
+	}
+	return string(b), string(a), nil
+}
+
+func unmarshalChange(c *domain.DeliveryChange, before, after []byte) error {
+	if err := json.Unmarshal(before, &c.Before); err != nil {
+		return fmt.Errorf("invalid delivery history of version %d: %w", c.Version, err)
+	}
+	if err := json.Unmarshal(after, &c.After); err != nil {
+		return fmt.Errorf("invalid delivery history of version %d: %w", c.Version, err)
+	}
+	return nil
+}
@@ -0,0 +1,237 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sergi-Ch/WB_L0_2025/domain"
+)
+
+// Имена операций для MemoryOptions.Err
+const (
+	OpSaveOrders      = "SaveOrders"
+	OpGetByID         = "GetByID"
+	OpListOrders      = "ListOrders"
+	OpDeleteOrders    = "DeleteOrders"
+	OpSoftDeleteOrder = "SoftDeleteOrder"
+	OpUpdateDelivery  = "UpdateDelivery"
+	OpDeliveryHistory = "DeliveryHistory"
+)
+
+// MemoryOptions - имитация поведения настоящей базы
+type MemoryOptions struct {
+	// Latency - задержка каждой операции; прерывается отменой ctx
+	Latency time.Duration
+	// Err вызывается перед каждой операцией (op - одна из Op*);
+	// ненулевая ошибка возвращается вместо результата
+	Err func(op string) error
+}
+
+// MemoryRepository - PostgresRepInterface в памяти с той же семантикой,
+// что и PostgresRepository: дубликаты, фильтры, сортировка и пагинация.
+// Для тестов, бенчмарков и запуска без базы.
+type MemoryRepository struct {
+	mu      sync.RWMutex
+	orders  map[string]domain.Order
+	history map[string][]domain.DeliveryChange
+	opts    MemoryOptions
+}
+
+func NewMemoryRepository(opts MemoryOptions) *MemoryRepository {
+	return &MemoryRepository{
+		orders:  make(map[string]domain.Order),
+		history: make(map[string][]domain.DeliveryChange),
+		opts:    opts,
+	}
+}
+
+func (r *MemoryRepository) SaveOrders(ctx context.Context, order *domain.Order) error {
+	if err := r.simulate(ctx, OpSaveOrders); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.orders[order.OrderUid]; ok {
+		return fmt.Errorf("%w: %s", ErrAlreadyExists, order.OrderUid)
+	}
+	stored := cloneOrder(*order)
+	stored.Version = max(stored.Version, 1)
+	r.orders[order.OrderUid] = stored
+	return nil
+}
+
+func (r *MemoryRepository) GetByID(ctx context.Context, orderUID string) (*domain.Order, error) {
+	if err := r.simulate(ctx, OpGetByID); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	order, ok := r.orders[orderUID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, orderUID)
+	}
+	order = cloneOrder(order)
+	return &order, nil
+}
+
+func (r *MemoryRepository) ListOrders(ctx context.Context, filter OrderFilter) ([]domain.Order, error) {
+	if err := r.simulate(ctx, OpListOrders); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	orders := make([]domain.Order, 0, len(r.orders))
+	for _, order := range r.orders {
+		if !filter.CreatedFrom.IsZero() && order.DateCreated.Before(filter.CreatedFrom) {
+			continue
+		}
+		if !filter.CreatedTo.IsZero() && !order.DateCreated.Before(filter.CreatedTo) {
+			continue
+		}
+		if filter.CustomerID != "" && order.CustomerId != filter.CustomerID {
+			continue
+		}
+		if !filter.IncludeDeleted && order.Deletion != nil {
+			continue
+		}
+		orders = append(orders, cloneOrder(order))
+	}
+	r.mu.RUnlock()
+
+	// как ORDER BY date_created DESC, order_uid
+	slices.SortFunc(orders, func(a, b domain.Order) int {
+		if c := b.DateCreated.Compare(a.DateCreated); c != 0 {
+			return c
+		}
+		return strings.Compare(a.OrderUid, b.OrderUid)
+	})
+
+	if filter.Offset > 0 {
+		orders = orders[min(filter.Offset, len(orders)):]
+	}
+	if filter.Limit > 0 && filter.Limit < len(orders) {
+		orders = orders[:filter.Limit]
+	}
+	return orders, nil
+}
+
+func (r *MemoryRepository) DeleteOrders(ctx context.Context, orderUIDs []string) (int, error) {
+	if err := r.simulate(ctx, OpDeleteOrders); err != nil {
+		return 0, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	deleted := 0
+	for _, uid := range orderUIDs {
+		if _, ok := r.orders[uid]; ok {
+			delete(r.orders, uid)
+			delete(r.history, uid)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (r *MemoryRepository) SoftDeleteOrder(ctx context.Context, orderUID string, deletion domain.Deletion) error {
+	if err := r.simulate(ctx, OpSoftDeleteOrder); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	order, ok := r.orders[orderUID]
+	switch {
+	case !ok:
+		return fmt.Errorf("%w: %s", ErrNotFound, orderUID)
+	case order.Deletion != nil:
+		return fmt.Errorf("%w: %s", ErrAlreadyDeleted, orderUID)
+	}
+	order.Deletion = &deletion
+	order.Version++
+	order.UpdatedAt = deletion.At
+	r.orders[orderUID] = order
+	return nil
+}
+
+func (r *MemoryRepository) UpdateDelivery(ctx context.Context, orderUID string, update DeliveryUpdate) (*domain.Order, error) {
+	if err := r.simulate(ctx, OpUpdateDelivery); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	order, ok := r.orders[orderUID]
+	switch {
+	case !ok || order.Deletion != nil:
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, orderUID)
+	case order.Version != update.Version:
+		return nil, fmt.Errorf("%w: %s", ErrVersionConflict, orderUID)
+	}
+
+	change := domain.DeliveryChange{
+		Version:   order.Version + 1,
+		ChangedAt: update.At,
+		Actor:     update.Actor,
+		Before:    order.Delivery,
+		After:     update.Delivery,
+	}
+	order.Delivery = update.Delivery
+	order.Version = change.Version
+	order.UpdatedAt = update.At
+	r.orders[orderUID] = order
+	r.history[orderUID] = append(r.history[orderUID], change)
+
+	order = cloneOrder(order)
+	return &order, nil
+}
+
+func (r *MemoryRepository) DeliveryHistory(ctx context.Context, orderUID string) ([]domain.DeliveryChange, error) {
+	if err := r.simulate(ctx, OpDeliveryHistory); err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]domain.DeliveryChange{}, r.history[orderUID]...), nil
+}
+
+// Len - число сохраненных заказов
+func (r *MemoryRepository) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.orders)
+}
+
+func (r *MemoryRepository) simulate(ctx context.Context, op string) error {
+	if r.opts.Latency > 0 {
+		t := time.NewTimer(r.opts.Latency)
+		defer t.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+	if r.opts.Err != nil {
+		return r.opts.Err(op)
+	}
+	return nil
+}
+
+// cloneOrder копирует заказ вместе с товарами, чтобы вызывающий код
+// не мог изменить сохраненное состояние (как и при чтении из базы)
+func cloneOrder(order domain.Order) domain.Order {
+	order.Items = append([]domain.Item{}, order.Items...)
+	if order.Deletion != nil {
+		deletion := *order.Deletion
+		order.Deletion = &deletion
+	}
+	return order
+}
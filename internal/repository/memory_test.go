@@ -0,0 +1,51 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Sergi-Ch/WB_L0_2025/internal/repository"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/repository/repotest"
+)
+
+func TestMemoryRepositoryContract(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repository.PostgresRepInterface {
+		return repository.NewMemoryRepository(repository.MemoryOptions{})
+	})
+}
+
+func TestMemoryRepositoryInjectedError(t *testing.T) {
+	errDown := errors.New("database is down")
+	repo := repository.NewMemoryRepository(repository.MemoryOptions{
+		Err: func(op string) error {
+			if op == repository.OpGetByID {
+				return errDown
+			}
+			return nil
+		},
+	})
+	ctx := context.Background()
+
+	if err := repo.SaveOrders(ctx, repotest.Order("memory-1", time.Now())); err != nil {
+		t.Fatalf("SaveOrders: %v", err)
+	}
+	if _, err := repo.GetByID(ctx, "memory-1"); !errors.Is(err, errDown) {
+		t.Fatalf("GetByID = %v, want injected error", err)
+	}
+}
+
+func TestMemoryRepositoryLatencyRespectsContext(t *testing.T) {
+	repo := repository.NewMemoryRepository(repository.MemoryOptions{Latency: time.Minute})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := repo.SaveOrders(ctx, repotest.Order("memory-1", time.Now()))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("SaveOrders = %v, want context.DeadlineExceeded", err)
+	}
+	if repo.Len() != 0 {
+		t.Errorf("order saved despite cancelled context")
+	}
+}
@@ -0,0 +1,191 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Таблицы, партиционированные по месяцам date_created (миграция 003).
+// Порядок - от ссылающихся к orders: так их можно отсоединять по одной.
+var partitionedTables = []string{"items", "payments", "deliveries", "orders"}
+
+// partitionSuffix - суффикс партиций месяца: orders_p2025_03
+const partitionSuffix = "p2006_01"
+
+// monthStart - начало месяца t в UTC
+func monthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// createPartitions создает партиции месяца t, если их нет
+func (r *PostgresRepository) createPartitions(ctx context.Context, t time.Time) (bool, error) {
+	var created bool
+	if err := r.db.QueryRow(ctx, `SELECT create_order_partitions($1)`, t).Scan(&created); err != nil {
+		return false, fmt.Errorf("create partitions for %s: %w", monthStart(t).Format("2006-01"), err)
+	}
+	return created, nil
+}
+
+// isNoPartition - заказ не попал ни в одну партицию orders. Postgres
+// возвращает check_violation без имени ограничения.
+func isNoPartition(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == pgerrcode.CheckViolation && pgErr.ConstraintName == ""
+}
+
+// EnsurePartitions создает партиции для months месяцев начиная с месяца from
+// и возвращает месяцы, для которых они созданы
+func (r *PostgresRepository) EnsurePartitions(ctx context.Context, from time.Time, months int) ([]time.Time, error) {
+	var created []time.Time
+	for i := range months {
+		month := monthStart(from).AddDate(0, i, 0)
+		ok, err := r.createPartitions(ctx, month)
+		if err != nil {
+			return created, err
+		}
+		if ok {
+			created = append(created, month)
+			r.log.InfoContext(ctx, "partitions created", slog.String("month", month.Format("2006-01")))
+		}
+	}
+	return created, nil
+}
+
+// Partitions возвращает месяцы, для которых есть партиция orders, по возрастанию
+func (r *PostgresRepository) Partitions(ctx context.Context) ([]time.Time, error) {
+	rows, err := r.db.Query(ctx, `SELECT c.relname FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		WHERE i.inhparent = 'orders'::regclass`)
+	if err != nil {
+		return nil, fmt.Errorf("list partitions failed: %w", err)
+	}
+	names, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("list partitions failed: %w", err)
+	}
+
+	months := make([]time.Time, 0, len(names))
+	for _, name := range names {
+		// партиции, созданные вручную под другими именами, не трогаем
+		month, err := time.Parse("orders_"+partitionSuffix, name)
+		if err != nil {
+			continue
+		}
+		months = append(months, month)
+	}
+	slices.SortFunc(months, time.Time.Compare)
+	return months, nil
+}
+
+// ExpirePartitions убирает партиции месяцев, закончившихся до before, вместе
+// с их заказами в order_uids. detach=false удаляет таблицы, detach=true
+// оставляет их отдельными таблицами *_detached (например, для pg_dump).
+// Возвращает убранные месяцы.
+func (r *PostgresRepository) ExpirePartitions(ctx context.Context, before time.Time, detach bool) ([]time.Time, error) {
+	months, err := r.Partitions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var expired []time.Time
+	for _, month := range months {
+		if month.AddDate(0, 1, 0).After(before) {
+			break
+		}
+		if err := r.expireMonth(ctx, month, detach); err != nil {
+			return expired, fmt.Errorf("expire partitions for %s: %w", month.Format("2006-01"), err)
+		}
+		expired = append(expired, month)
+		r.log.InfoContext(ctx, "partitions expired",
+			slog.String("month", month.Format("2006-01")),
+			slog.Bool("detached", detach),
+		)
+	}
+	return expired, nil
+}
+
+func (r *PostgresRepository) expireMonth(ctx context.Context, month time.Time, detach bool) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// та же блокировка, что у create_order_partitions: несколько экземпляров
+	// сервиса не убирают один месяц одновременно
+	suffix := month.Format(partitionSuffix)
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('create_order_partitions'))`); err != nil {
+		return err
+	}
+	var attached bool
+	err = tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM pg_inherits WHERE inhrelid = to_regclass($1))`,
+		"orders_"+suffix).Scan(&attached)
+	if err != nil {
+		return err
+	}
+	if !attached {
+		return nil
+	}
+
+	for _, table := range partitionedTables {
+		name := table + "_" + suffix
+		_, err := tx.Exec(ctx, fmt.Sprintf(`ALTER TABLE %s DETACH PARTITION %s`,
+			pgx.Identifier{table}.Sanitize(), pgx.Identifier{name}.Sanitize()))
+		if err != nil {
+			return err
+		}
+		if !detach {
+			if _, err := tx.Exec(ctx, `DROP TABLE `+pgx.Identifier{name}.Sanitize()); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// отсоединенная таблица сохраняет внешние ключи на orders и order_uids:
+		// они мешают отсоединить orders и каскадно удалили бы архив
+		if err := dropForeignKeys(ctx, tx, name); err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, fmt.Sprintf(`ALTER TABLE %s RENAME TO %s`,
+			pgx.Identifier{name}.Sanitize(), pgx.Identifier{name + "_detached"}.Sanitize()))
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM order_uids WHERE date_created >= $1 AND date_created < $2`,
+		month, month.AddDate(0, 1, 0))
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func dropForeignKeys(ctx context.Context, tx pgx.Tx, table string) error {
+	rows, err := tx.Query(ctx, `SELECT conname FROM pg_constraint
+		WHERE conrelid = to_regclass($1) AND contype = 'f'`, table)
+	if err != nil {
+		return err
+	}
+	names, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		_, err := tx.Exec(ctx, fmt.Sprintf(`ALTER TABLE %s DROP CONSTRAINT %s`,
+			pgx.Identifier{table}.Sanitize(), pgx.Identifier{name}.Sanitize()))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -2,48 +2,138 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/Sergi-Ch/WB_L0_2025/domain"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
 	"time"
 )
 
 type PostgresRepository struct {
-	db *pgxpool.Pool
+	db  *pgxpool.Pool
+	log *slog.Logger
 }
 
 type PostgresRepInterface interface {
 	SaveOrders(ctx context.Context, order *domain.Order) error
 	GetByID(ctx context.Context, orderUID string) (*domain.Order, error)
+	ListOrders(ctx context.Context, filter OrderFilter) ([]domain.Order, error)
+	// DeleteOrders удаляет заказы вместе с доставкой, оплатой и товарами.
+	// Неизвестные order_uid пропускаются; возвращает число удаленных заказов.
+	DeleteOrders(ctx context.Context, orderUIDs []string) (int, error)
+	// SoftDeleteOrder помечает заказ удаленным, не трогая его данные.
+	// ErrNotFound - заказа нет, ErrAlreadyDeleted - он уже удален.
+	SoftDeleteOrder(ctx context.Context, orderUID string, deletion domain.Deletion) error
+	// UpdateDelivery заменяет доставку заказа версии update.Version, увеличивает
+	// версию и пишет изменение в историю. ErrNotFound - заказа нет или он
+	// удален, ErrVersionConflict - версия другая. Возвращает измененный заказ.
+	UpdateDelivery(ctx context.Context, orderUID string, update DeliveryUpdate) (*domain.Order, error)
+	// DeliveryHistory - изменения доставки заказа по возрастанию версии
+	DeliveryHistory(ctx context.Context, orderUID string) ([]domain.DeliveryChange, error)
 }
 
-func NewPostgresRepository(dsn string) (*PostgresRepository, error) {
-	db, err := pgxpool.New(context.Background(), dsn)
+type DeliveryUpdate struct {
+	Version  int64 // ожидаемая текущая версия заказа
+	Delivery domain.Delivery
+	Actor    string
+	At       time.Time
+}
+
+// OrderFilter - параметры выборки заказов. Результат отсортирован
+// от новых к старым (date_created DESC, затем order_uid).
+type OrderFilter struct {
+	CreatedFrom time.Time // включительно, нулевое значение - без ограничения
+	CreatedTo   time.Time // не включительно, нулевое значение - без ограничения
+	CustomerID  string
+	// IncludeDeleted - вместе с мягко удаленными заказами
+	IncludeDeleted bool
+	Limit          int // 0 - без ограничения
+	Offset         int
+}
+
+func NewPostgresRepository(dsn string, log *slog.Logger) (*PostgresRepository, error) {
+	cfg, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, err
+	}
+	// каждый SQL-запрос попадает в трейс отдельным спаном
+	cfg.ConnConfig.Tracer = pgxTracer{}
+
+	db, err := pgxpool.NewWithConfig(context.Background(), cfg)
 	if err != nil {
 		return nil, err
 	}
-	return &PostgresRepository{db: db}, nil
+	return &PostgresRepository{db: db, log: log.With(slog.String("component", "postgres"))}, nil
 }
 
-func (r *PostgresRepository) SaveOrders(ctx context.Context, order *domain.Order) error {
+func (r *PostgresRepository) SaveOrders(ctx context.Context, order *domain.Order) (err error) {
+	ctx, span := tracer.Start(ctx, "PostgresRepository.SaveOrders",
+		trace.WithAttributes(attribute.String("order_uid", order.OrderUid)))
+	defer func() { endSpan(span, err) }()
+
+	err = r.saveOrder(ctx, order)
+	if !isNoPartition(err) {
+		return err
+	}
+	// заказ за месяц, для которого еще нет партиции (например, replay старых
+	// сообщений): создаем ее и повторяем запись
+	if _, err := r.createPartitions(ctx, order.DateCreated); err != nil {
+		return err
+	}
+	r.log.InfoContext(ctx, "partition created on demand",
+		slog.String("order_uid", order.OrderUid),
+		slog.Time("date_created", order.DateCreated),
+	)
+	return r.saveOrder(ctx, order)
+}
+
+func (r *PostgresRepository) saveOrder(ctx context.Context, order *domain.Order) error {
+	// удаленный заказ (например, из архива) сохраняется вместе с отметкой
+	var deletion domain.Deletion
+	var deletedAt, updatedAt *time.Time
+	if order.Deletion != nil {
+		deletion, deletedAt = *order.Deletion, &order.Deletion.At
+	}
+	if !order.UpdatedAt.IsZero() {
+		updatedAt = &order.UpdatedAt
+	}
+
 	tx, err := r.db.Begin(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to begin tx: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
+	_, err = tx.Exec(ctx, `INSERT INTO order_uids (order_uid, date_created) VALUES ($1, $2)`,
+		order.OrderUid, order.DateCreated)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return fmt.Errorf("%w: %s", ErrAlreadyExists, order.OrderUid)
+		}
+		return fmt.Errorf("insert order uid failed: %w", err)
+	}
+
 	_, err = tx.Exec(ctx, `insert into orders (order_uid,track_number, entry, locale, internal_signature, 
-                    customer_id, delivery_service, shardkey, sm_id,date_created, oof_shard)
-                    values ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)`, order.OrderUid, order.TrackNumber, order.Entry, order.Locale,
+                    customer_id, delivery_service, shardkey, sm_id,date_created, oof_shard,
+                    deleted_at, delete_reason, deleted_by, version, updated_at)
+                    values ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16)`, order.OrderUid, order.TrackNumber, order.Entry, order.Locale,
 		order.InternalSignature, order.CustomerId, order.DeliveryService,
-		order.Shardkey, order.SmId, order.DateCreated, order.OofShard)
+		order.Shardkey, order.SmId, order.DateCreated, order.OofShard,
+		deletedAt, deletion.Reason, deletion.Actor, max(order.Version, 1), updatedAt)
 	if err != nil {
 		return fmt.Errorf("insert orders faiked: %w", err)
 	}
 
-	_, err = tx.Exec(ctx, `insert into deliveries (order_uid, name, phone, zip, city, address, region, email) values 
-                                                                                    ($1,$2,$3,$4,$5,$6,$7,$8)
-	`, order.OrderUid, order.Delivery.Name, order.Delivery.Phone,
+	_, err = tx.Exec(ctx, `insert into deliveries (order_uid, date_created, name, phone, zip, city, address, region, email)
+		values ($1,$2,$3,$4,$5,$6,$7,$8,$9)
+	`, order.OrderUid, order.DateCreated, order.Delivery.Name, order.Delivery.Phone,
 		order.Delivery.Zip, order.Delivery.City, order.Delivery.Address,
 		order.Delivery.Region, order.Delivery.Email)
 	if err != nil {
@@ -52,10 +142,10 @@ func (r *PostgresRepository) SaveOrders(ctx context.Context, order *domain.Order
 
 	_, err = tx.Exec(ctx, `
 		INSERT INTO payments (
-			order_uid, transaction, request_id, currency, provider,
+			order_uid, date_created, transaction, request_id, currency, provider,
 			amount, payment_dt, bank, delivery_cost, goods_total, custom_fee
-		) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)
-	`, order.OrderUid, order.Payment.Transaction, order.Payment.RequestId,
+		) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12)
+	`, order.OrderUid, order.DateCreated, order.Payment.Transaction, order.Payment.RequestId,
 		order.Payment.Currency, order.Payment.Provider, order.Payment.Amount,
 		order.Payment.PaymentDt, order.Payment.Bank, order.Payment.DeliveryCost,
 		order.Payment.GoodsTotal, order.Payment.CustomFee)
@@ -66,101 +156,296 @@ func (r *PostgresRepository) SaveOrders(ctx context.Context, order *domain.Order
 	for _, item := range order.Items {
 		_, err = tx.Exec(ctx, `
 			INSERT INTO items (
-				order_uid, chrt_id, track_number, price, rid, name,
+				order_uid, date_created, chrt_id, track_number, price, rid, name,
 				sale, size, total_price, nm_id, brand, status
-			) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12)
-		`, order.OrderUid, item.ChrtId, item.TrackNumber, item.Price, item.Rid,
+			) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13)
+		`, order.OrderUid, order.DateCreated, item.ChrtId, item.TrackNumber, item.Price, item.Rid,
 			item.Name, item.Sale, item.Size, item.TotalPrice, item.NmId, item.Brand, item.Status)
 		if err != nil {
 			return fmt.Errorf("insert item failed: %w", err)
 		}
 	}
 
-	if err = tx.Commit(ctx); err != nil {
+	_, commitSpan := tracer.Start(ctx, "postgres COMMIT")
+	err = tx.Commit(ctx)
+	endSpan(commitSpan, err)
+	if err != nil {
 		return fmt.Errorf("commit failed: %w", err)
 	}
 
+	r.log.DebugContext(ctx, "order stored",
+		slog.String("order_uid", order.OrderUid),
+		slog.Int("items", len(order.Items)),
+	)
 	return nil
 }
 
-func (r *PostgresRepository) GetByID(ctx context.Context, orderUID string) (*domain.Order, error) {
-	rows, err := r.db.Query(ctx, `
-		SELECT 
-			o.order_uid, o.track_number, o.entry, o.locale, o.internal_signature,
-			o.customer_id, o.delivery_service, o.shardkey, o.sm_id, o.date_created, o.oof_shard,
+// orderMonth - условие на date_created заказа $1: по нему Postgres при
+// выполнении отбрасывает чужие партиции
+const orderMonth = `date_created = (SELECT date_created FROM order_uids WHERE order_uid = $1)`
 
-			d.name, d.phone, d.zip, d.city, d.address, d.region, d.email,
+// GetByID читает заказ и его части отдельными запросами одним батчем (один
+// round trip): отсутствующие доставка или оплата остаются пустыми, заказ без
+// товаров возвращается с пустым списком
+func (r *PostgresRepository) GetByID(ctx context.Context, orderUID string) (*domain.Order, error) {
+	batch := &pgx.Batch{}
+	batch.Queue(`SELECT order_uid, track_number, entry, locale, internal_signature, customer_id,
+			delivery_service, shardkey, sm_id, date_created, oof_shard, deleted_at, delete_reason, deleted_by,
+			version, updated_at
+		FROM orders WHERE order_uid = $1 AND `+orderMonth, orderUID)
+	batch.Queue(`SELECT name, phone, zip, city, address, region, email
+		FROM deliveries WHERE order_uid = $1 AND `+orderMonth, orderUID)
+	batch.Queue(`SELECT transaction, request_id, currency, provider, amount, payment_dt, bank,
+			delivery_cost, goods_total, custom_fee
+		FROM payments WHERE order_uid = $1 AND `+orderMonth, orderUID)
+	batch.Queue(`SELECT chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status
+		FROM items WHERE order_uid = $1 AND `+orderMonth+` ORDER BY id`, orderUID)
 
-			p.transaction, p.request_id, p.currency, p.provider,
-			p.amount, p.payment_dt, p.bank, p.delivery_cost, p.goods_total, p.custom_fee,
+	br := r.db.SendBatch(ctx, batch)
+	defer br.Close()
 
-			i.chrt_id, i.track_number, i.price, i.rid, i.name, i.sale, i.size,
-			i.total_price, i.nm_id, i.brand, i.status
-		FROM orders o
-		LEFT JOIN deliveries d ON d.order_uid = o.order_uid
-		LEFT JOIN payments p  ON p.order_uid = o.order_uid
-		LEFT JOIN items i    ON i.order_uid = o.order_uid
-		WHERE o.order_uid = $1;
-	`, orderUID)
+	var (
+		order     domain.Order
+		deletion  domain.Deletion
+		deletedAt *time.Time
+		updatedAt *time.Time
+	)
+	err := br.QueryRow().Scan(
+		&order.OrderUid, &order.TrackNumber, &order.Entry, &order.Locale, &order.InternalSignature,
+		&order.CustomerId, &order.DeliveryService, &order.Shardkey, &order.SmId, &order.DateCreated, &order.OofShard,
+		&deletedAt, &deletion.Reason, &deletion.Actor, &order.Version, &updatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, orderUID)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("query failed: %w", err)
+		return nil, fmt.Errorf("query order failed: %w", err)
+	}
+	// timestamptz читается в локальной зоне процесса
+	order.DateCreated = order.DateCreated.UTC()
+	if deletedAt != nil {
+		deletion.At = deletedAt.UTC()
+		order.Deletion = &deletion
+	}
+	if updatedAt != nil {
+		order.UpdatedAt = updatedAt.UTC()
 	}
-	defer rows.Close()
 
-	var order domain.Order
-	order.Items = []domain.Item{}
+	d := &order.Delivery
+	err = br.QueryRow().Scan(&d.Name, &d.Phone, &d.Zip, &d.City, &d.Address, &d.Region, &d.Email)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("query delivery failed: %w", err)
+	}
+
+	p := &order.Payment
+	err = br.QueryRow().Scan(&p.Transaction, &p.RequestId, &p.Currency, &p.Provider,
+		&p.Amount, &p.PaymentDt, &p.Bank, &p.DeliveryCost, &p.GoodsTotal, &p.CustomFee)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("query payment failed: %w", err)
+	}
 
-	firstRow := true
-	for rows.Next() {
+	rows, err := br.Query()
+	if err != nil {
+		return nil, fmt.Errorf("query items failed: %w", err)
+	}
+	order.Items, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.Item, error) {
 		var item domain.Item
+		err := row.Scan(&item.ChrtId, &item.TrackNumber, &item.Price, &item.Rid, &item.Name,
+			&item.Sale, &item.Size, &item.TotalPrice, &item.NmId, &item.Brand, &item.Status)
+		return item, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scan items failed: %w", err)
+	}
+	return &order, nil
+}
+
+func (r *PostgresRepository) ListOrders(ctx context.Context, filter OrderFilter) ([]domain.Order, error) {
+	query := `SELECT order_uid FROM orders WHERE 1=1`
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if !filter.CreatedFrom.IsZero() {
+		query += " AND date_created >= " + arg(filter.CreatedFrom)
+	}
+	if !filter.CreatedTo.IsZero() {
+		query += " AND date_created < " + arg(filter.CreatedTo)
+	}
+	if filter.CustomerID != "" {
+		query += " AND customer_id = " + arg(filter.CustomerID)
+	}
+	if !filter.IncludeDeleted {
+		query += " AND deleted_at IS NULL"
+	}
+	query += " ORDER BY date_created DESC, order_uid"
+	if filter.Limit > 0 {
+		query += " LIMIT " + arg(filter.Limit)
+	}
+	if filter.Offset > 0 {
+		query += " OFFSET " + arg(filter.Offset)
+	}
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list query failed: %w", err)
+	}
+	uids, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("scan order uids failed: %w", err)
+	}
 
-		if firstRow {
+	orders := make([]domain.Order, 0, len(uids))
+	for _, uid := range uids {
+		order, err := r.GetByID(ctx, uid)
+		if err != nil {
+			return nil, fmt.Errorf("load order %s: %w", uid, err)
+		}
+		orders = append(orders, *order)
+	}
+	return orders, nil
+}
 
-			err = rows.Scan(
-				&order.OrderUid, &order.TrackNumber, &order.Entry, &order.Locale, &order.InternalSignature,
-				&order.CustomerId, &order.DeliveryService, &order.Shardkey, &order.SmId, &order.DateCreated, &order.OofShard,
+func (r *PostgresRepository) DeleteOrders(ctx context.Context, orderUIDs []string) (int, error) {
+	// части заказа удаляются каскадом от order_uids
+	tag, err := r.db.Exec(ctx, `DELETE FROM order_uids WHERE order_uid = ANY($1)`, orderUIDs)
+	if err != nil {
+		return 0, fmt.Errorf("delete orders failed: %w", err)
+	}
+	return int(tag.RowsAffected()), nil
+}
 
-				&order.Delivery.Name, &order.Delivery.Phone, &order.Delivery.Zip,
-				&order.Delivery.City, &order.Delivery.Address, &order.Delivery.Region, &order.Delivery.Email,
+func (r *PostgresRepository) SoftDeleteOrder(ctx context.Context, orderUID string, deletion domain.Deletion) error {
+	tag, err := r.db.Exec(ctx, `UPDATE orders SET deleted_at = $2, delete_reason = $3, deleted_by = $4,
+			version = version + 1, updated_at = $2
+		WHERE order_uid = $1 AND deleted_at IS NULL AND `+orderMonth,
+		orderUID, deletion.At, deletion.Reason, deletion.Actor)
+	if err != nil {
+		return fmt.Errorf("soft delete order failed: %w", err)
+	}
+	if tag.RowsAffected() > 0 {
+		return nil
+	}
+
+	// ничего не обновлено: заказа нет или он уже удален
+	var exists bool
+	err = r.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM order_uids WHERE order_uid = $1)`, orderUID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("soft delete order failed: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrNotFound, orderUID)
+	}
+	return fmt.Errorf("%w: %s", ErrAlreadyDeleted, orderUID)
+}
 
-				&order.Payment.Transaction, &order.Payment.RequestId, &order.Payment.Currency, &order.Payment.Provider,
-				&order.Payment.Amount, &order.Payment.PaymentDt, &order.Payment.Bank, &order.Payment.DeliveryCost,
-				&order.Payment.GoodsTotal, &order.Payment.CustomFee,
+func (r *PostgresRepository) UpdateDelivery(ctx context.Context, orderUID string, update DeliveryUpdate) (_ *domain.Order, err error) {
+	ctx, span := tracer.Start(ctx, "PostgresRepository.UpdateDelivery",
+		trace.WithAttributes(attribute.String("order_uid", orderUID)))
+	defer func() { endSpan(span, err) }()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
 
-				&item.ChrtId, &item.TrackNumber, &item.Price, &item.Rid, &item.Name, &item.Sale,
-				&item.Size, &item.TotalPrice, &item.NmId, &item.Brand, &item.Status,
-			)
-			if err != nil {
-				return nil, fmt.Errorf("scan first row failed: %w", err)
-			}
-			firstRow = false
-		} else {
+	// UPDATE блокирует строку заказа до конца транзакции: параллельное
+	// изменение той же версии получит ErrVersionConflict
+	var created time.Time
+	var version int64
+	err = tx.QueryRow(ctx, `UPDATE orders SET version = version + 1, updated_at = $3
+		WHERE order_uid = $1 AND version = $2 AND deleted_at IS NULL AND `+orderMonth+`
+		RETURNING date_created, version`, orderUID, update.Version, update.At).Scan(&created, &version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, r.updateConflict(ctx, orderUID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("update order version failed: %w", err)
+	}
 
-			err = rows.Scan(
-				new(string), new(string), new(string), new(string), new(string),
-				new(string), new(string), new(string), new(int), new(time.Time), new(string),
+	var before domain.Delivery
+	err = tx.QueryRow(ctx, `SELECT name, phone, zip, city, address, region, email
+		FROM deliveries WHERE order_uid = $1 AND date_created = $2`, orderUID, created).Scan(
+		&before.Name, &before.Phone, &before.Zip, &before.City, &before.Address, &before.Region, &before.Email)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("query delivery failed: %w", err)
+	}
 
-				new(string), new(string), new(string), new(string), new(string), new(string), new(string), new(string),
+	// у заказа может не быть доставки: тогда она создается
+	d := update.Delivery
+	_, err = tx.Exec(ctx, `INSERT INTO deliveries (order_uid, date_created, name, phone, zip, city, address, region, email)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)
+		ON CONFLICT (order_uid, date_created) DO UPDATE SET name = EXCLUDED.name, phone = EXCLUDED.phone,
+			zip = EXCLUDED.zip, city = EXCLUDED.city, address = EXCLUDED.address,
+			region = EXCLUDED.region, email = EXCLUDED.email`,
+		orderUID, created, d.Name, d.Phone, d.Zip, d.City, d.Address, d.Region, d.Email)
+	if err != nil {
+		return nil, fmt.Errorf("update delivery failed: %w", err)
+	}
 
-				new(string), new(string), new(string), new(string),
-				new(int), new(int64), new(string), new(int), new(int), new(int),
+	beforeJSON, afterJSON, err := marshalChange(before, d)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(ctx, `INSERT INTO delivery_history (order_uid, version, changed_at, actor, before, after)
+		VALUES ($1,$2,$3,$4,$5,$6)`, orderUID, version, update.At, update.Actor, beforeJSON, afterJSON)
+	if err != nil {
+		return nil, fmt.Errorf("insert delivery history failed: %w", err)
+	}
 
-				&item.ChrtId, &item.TrackNumber, &item.Price, &item.Rid, &item.Name, &item.Sale,
-				&item.Size, &item.TotalPrice, &item.NmId, &item.Brand, &item.Status,
-			)
-			if err != nil {
-				return nil, fmt.Errorf("scan item row failed: %w", err)
-			}
-		}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit failed: %w", err)
+	}
+	return r.GetByID(ctx, orderUID)
+}
 
-		order.Items = append(order.Items, item)
+// updateConflict объясняет, почему условный UPDATE заказа ничего не изменил
+func (r *PostgresRepository) updateConflict(ctx context.Context, orderUID string) error {
+	var deleted bool
+	err := r.db.QueryRow(ctx, `SELECT deleted_at IS NOT NULL FROM orders
+		WHERE order_uid = $1 AND `+orderMonth, orderUID).Scan(&deleted)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows), err == nil && deleted:
+		return fmt.Errorf("%w: %s", ErrNotFound, orderUID)
+	case err != nil:
+		return fmt.Errorf("query order failed: %w", err)
 	}
+	return fmt.Errorf("%w: %s", ErrVersionConflict, orderUID)
+}
 
-	if firstRow {
-		return nil, fmt.Errorf("order not found")
+func (r *PostgresRepository) DeliveryHistory(ctx context.Context, orderUID string) ([]domain.DeliveryChange, error) {
+	rows, err := r.db.Query(ctx, `SELECT version, changed_at, actor, before, after
+		FROM delivery_history WHERE order_uid = $1 ORDER BY version`, orderUID)
+	if err != nil {
+		return nil, fmt.Errorf("query delivery history failed: %w", err)
 	}
+	changes, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.DeliveryChange, error) {
+		var (
+			c             domain.DeliveryChange
+			before, after []byte
+		)
+		if err := row.Scan(&c.Version, &c.ChangedAt, &c.Actor, &before, &after); err != nil {
+			return c, err
+		}
+		c.ChangedAt = c.ChangedAt.UTC()
+		return c, unmarshalChange(&c, before, after)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scan delivery history failed: %w", err)
+	}
+	return changes, nil
+}
 
-	return &order, nil
+// Ping проверяет доступность базы (для health-check)
+func (r *PostgresRepository) Ping(ctx context.Context) error {
+	return r.db.Ping(ctx)
+}
+
+// Stat возвращает статистику пула соединений (для метрик)
+func (r *PostgresRepository) Stat() *pgxpool.Stat {
+	return r.db.Stat()
 }
 
 func (r *PostgresRepository) Close() error {
@@ -3,54 +3,116 @@ package repository
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"log/slog"
 	"time"
 
 	"github.com/Sergi-Ch/WB_L0_2025/domain"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/logger"
+	"github.com/redis/go-redis/extra/redisotel/v9"
 	"github.com/redis/go-redis/v9"
 )
 
 type RedisCache struct {
 	client *redis.Client
 	ttl    time.Duration
+	log    *slog.Logger
 }
 
 type RedisInterface interface {
-	Get(orderUID string) (*domain.Order, bool)
-	Set(orderUID string, order domain.Order)
+	Get(ctx context.Context, orderUID string) (*domain.Order, bool)
+	Set(ctx context.Context, orderUID string, order domain.Order)
+	Delete(ctx context.Context, orderUID string)
 }
 
-func NewRedisCache(addr string, ttl time.Duration) *RedisCache {
+func NewRedisCache(addr, password string, db int, ttl time.Duration, log *slog.Logger) *RedisCache {
+	log = log.With(slog.String("component", "redis_cache"))
+
 	client := redis.NewClient(&redis.Options{
 		Addr:     addr,
-		Password: "",
-		DB:       0,
+		Password: password,
+		DB:       db,
 	})
+	// спаны на каждую команду Redis
+	if err := redisotel.InstrumentTracing(client); err != nil {
+		log.Warn("failed to instrument redis tracing", logger.Err(err))
+	}
 
 	return &RedisCache{
 		client: client,
 		ttl:    ttl,
+		log:    log,
 	}
 }
 
-func (r *RedisCache) Get(orderUID string) (*domain.Order, bool) {
-	ctx := context.Background()
+func (r *RedisCache) Get(ctx context.Context, orderUID string) (*domain.Order, bool) {
 	data, err := r.client.Get(ctx, "order:"+orderUID).Bytes()
 	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			r.log.WarnContext(ctx, "redis get failed", slog.String("order_uid", orderUID), logger.Err(err))
+		}
 		return nil, false
 	}
 
 	var order domain.Order
 	if err := json.Unmarshal(data, &order); err != nil {
+		r.log.WarnContext(ctx, "invalid cached order", slog.String("order_uid", orderUID), logger.Err(err))
 		return nil, false
 	}
 
 	return &order, true
 }
 
-func (r *RedisCache) Set(orderUID string, order domain.Order) {
-	ctx := context.Background()
-	data, _ := json.Marshal(order)
-	r.client.Set(ctx, "order:"+orderUID, data, r.ttl)
+func (r *RedisCache) Set(ctx context.Context, orderUID string, order domain.Order) {
+	data, err := json.Marshal(order)
+	if err != nil {
+		r.log.ErrorContext(ctx, "failed to marshal order for cache", slog.String("order_uid", orderUID), logger.Err(err))
+		return
+	}
+	if err := r.client.Set(ctx, "order:"+orderUID, data, r.ttl).Err(); err != nil {
+		r.log.WarnContext(ctx, "redis set failed", slog.String("order_uid", orderUID), logger.Err(err))
+	}
+}
+
+func (r *RedisCache) Delete(ctx context.Context, orderUID string) {
+	if err := r.client.Del(ctx, "order:"+orderUID).Err(); err != nil {
+		r.log.WarnContext(ctx, "redis delete failed", slog.String("order_uid", orderUID), logger.Err(err))
+	}
+}
+
+// Flush удаляет из Redis все закэшированные заказы и возвращает их количество
+func (r *RedisCache) Flush(ctx context.Context) (int, error) {
+	deleted := 0
+	iter := r.client.Scan(ctx, 0, "order:*", 500).Iterator()
+	batch := make([]string, 0, 500)
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		n, err := r.client.Del(ctx, batch...).Result()
+		deleted += int(n)
+		batch = batch[:0]
+		return err
+	}
+
+	for iter.Next(ctx) {
+		batch = append(batch, iter.Val())
+		if len(batch) == cap(batch) {
+			if err := flush(); err != nil {
+				return deleted, err
+			}
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return deleted, err
+	}
+	return deleted, flush()
+}
+
+// Ping проверяет доступность Redis (для health-check)
+func (r *RedisCache) Ping(ctx context.Context) error {
+	return r.client.Ping(ctx).Err()
 }
 
 func (r *RedisCache) Close() error {
@@ -0,0 +1,482 @@
+// Package repotest - контрактные тесты PostgresRepInterface. Их проходит
+// каждая реализация хранилища, так что подмена одной другой в тестах и
+// бенчмарках не меняет поведения сервиса.
+package repotest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Sergi-Ch/WB_L0_2025/domain"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/repository"
+)
+
+// Factory возвращает пустое хранилище; вызывается для каждого подтеста
+type Factory func(t *testing.T) repository.PostgresRepInterface
+
+// Run прогоняет контракт на хранилищах из newRepo
+func Run(t *testing.T, newRepo Factory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, repo repository.PostgresRepInterface)
+	}{
+		{"SaveAndGet", testSaveAndGet},
+		{"SaveDuplicate", testSaveDuplicate},
+		{"SaveDuplicateConcurrent", testSaveDuplicateConcurrent},
+		{"GetUnknown", testGetUnknown},
+		{"SaveWithoutItems", testSaveWithoutItems},
+		{"SaveZeroValues", testSaveZeroValues},
+		{"SaveLargeValues", testSaveLargeValues},
+		{"ListOrdering", testListOrdering},
+		{"ListFilters", testListFilters},
+		{"ListPagination", testListPagination},
+		{"ListEmpty", testListEmpty},
+		{"DeleteOrders", testDeleteOrders},
+		{"SoftDelete", testSoftDelete},
+		{"SaveDeleted", testSaveDeleted},
+		{"UpdateDelivery", testUpdateDelivery},
+		{"UpdateDeliveryCreates", testUpdateDeliveryCreates},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newRepo(t))
+		})
+	}
+}
+
+// baseTime - без монотонных часов и точнее микросекунды, как хранит Postgres
+var baseTime = time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// Order - валидный заказ с двумя товарами
+func Order(uid string, created time.Time) *domain.Order {
+	return &domain.Order{
+		OrderUid:          uid,
+		TrackNumber:       "WBILMTESTTRACK",
+		Entry:             "WBIL",
+		Locale:            "en",
+		InternalSignature: "sig",
+		CustomerId:        "test",
+		DeliveryService:   "meest",
+		Shardkey:          "9",
+		SmId:              99,
+		DateCreated:       created,
+		OofShard:          "1",
+		Delivery: domain.Delivery{
+			Name:    "Test Testov",
+			Phone:   "+9720000000",
+			Zip:     "2639809",
+			City:    "Kiryat Mozkin",
+			Address: "Ploshad Mira 15",
+			Region:  "Kraiot",
+			Email:   "test@gmail.com",
+		},
+		Payment: domain.Payment{
+			Transaction:  uid,
+			Currency:     "USD",
+			Provider:     "wbpay",
+			Amount:       1817,
+			PaymentDt:    1637907727,
+			Bank:         "alpha",
+			DeliveryCost: 1500,
+			GoodsTotal:   317,
+		},
+		Items: []domain.Item{
+			{ChrtId: 9934930, TrackNumber: "WBILMTESTTRACK", Price: 453, Rid: "ab4219087a764ae0btest",
+				Name: "Mascaras", Sale: 30, Size: "0", TotalPrice: 317, NmId: 2389212, Brand: "Vivienne Sabo", Status: 202},
+			{ChrtId: 9934931, TrackNumber: "WBILMTESTTRACK", Price: 100, Rid: "ab4219087a764ae0btest2",
+				Name: "Brush", Size: "0", TotalPrice: 100, NmId: 2389213, Brand: "Vivienne Sabo", Status: 202},
+		},
+		Version: 1,
+	}
+}
+
+func testSaveAndGet(t *testing.T, repo repository.PostgresRepInterface) {
+	ctx := context.Background()
+	want := Order("contract-1", baseTime)
+	save(t, repo, want)
+
+	got, err := repo.GetByID(ctx, want.OrderUid)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	assertOrder(t, got, want)
+
+	// возвращается копия: ее изменение не портит сохраненный заказ
+	got.Items[0].Name = "changed"
+	again, err := repo.GetByID(ctx, want.OrderUid)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	assertOrder(t, again, want)
+}
+
+func testSaveDuplicate(t *testing.T, repo repository.PostgresRepInterface) {
+	ctx := context.Background()
+	first := Order("contract-dup", baseTime)
+	save(t, repo, first)
+
+	second := Order("contract-dup", baseTime.Add(time.Hour))
+	second.TrackNumber = "OTHER"
+	err := repo.SaveOrders(ctx, second)
+	if !errors.Is(err, repository.ErrAlreadyExists) {
+		t.Fatalf("SaveOrders duplicate = %v, want ErrAlreadyExists", err)
+	}
+
+	got, err := repo.GetByID(ctx, first.OrderUid)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	assertOrder(t, got, first)
+}
+
+func testSaveDuplicateConcurrent(t *testing.T, repo repository.PostgresRepInterface) {
+	const n = 8
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		ok   int
+		errs []error
+	)
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := repo.SaveOrders(context.Background(), Order("contract-race", baseTime))
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil {
+				ok++
+			} else if !errors.Is(err, repository.ErrAlreadyExists) {
+				errs = append(errs, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if ok != 1 || len(errs) != 0 {
+		t.Fatalf("%d of %d concurrent saves succeeded (want 1), unexpected errors: %v", ok, n, errs)
+	}
+}
+
+func testGetUnknown(t *testing.T, repo repository.PostgresRepInterface) {
+	got, err := repo.GetByID(context.Background(), "contract-missing")
+	if !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("GetByID unknown = %+v, %v, want ErrNotFound", got, err)
+	}
+	if got != nil {
+		t.Errorf("GetByID unknown returned order %+v", got)
+	}
+}
+
+func testSaveWithoutItems(t *testing.T, repo repository.PostgresRepInterface) {
+	want := Order("contract-no-items", baseTime)
+	want.Items = []domain.Item{}
+	save(t, repo, want)
+
+	got, err := repo.GetByID(context.Background(), want.OrderUid)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if got.Items == nil {
+		t.Error("items = nil, want empty slice")
+	}
+	assertOrder(t, got, want)
+	assertUIDs(t, list(t, repo, repository.OrderFilter{}), want.OrderUid)
+}
+
+// testSaveZeroValues - все поля, кроме ключа и даты, пустые
+func testSaveZeroValues(t *testing.T, repo repository.PostgresRepInterface) {
+	want := &domain.Order{
+		OrderUid:    "contract-zero",
+		DateCreated: baseTime,
+		Items:       []domain.Item{{}},
+	}
+	save(t, repo, want)
+	// новый заказ всегда получает версию 1
+	want.Version = 1
+
+	got, err := repo.GetByID(context.Background(), want.OrderUid)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	assertOrder(t, got, want)
+}
+
+// testSaveLargeValues - суммы и идентификаторы за пределами int32
+func testSaveLargeValues(t *testing.T, repo repository.PostgresRepInterface) {
+	const big = int64(1) << 40
+	want := Order("contract-large", baseTime)
+	want.Payment.Amount = big
+	want.Payment.PaymentDt = big + 1
+	want.Payment.GoodsTotal = big + 2
+	want.Items[0].ChrtId = big + 3
+	want.Items[0].NmId = big + 4
+	want.Items[0].TotalPrice = big + 5
+	save(t, repo, want)
+
+	got, err := repo.GetByID(context.Background(), want.OrderUid)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	assertOrder(t, got, want)
+}
+
+func testListOrdering(t *testing.T, repo repository.PostgresRepInterface) {
+	// одинаковое время у b и a: порядок по order_uid
+	save(t, repo, Order("contract-c", baseTime))
+	save(t, repo, Order("contract-b", baseTime.Add(time.Hour)))
+	save(t, repo, Order("contract-a", baseTime.Add(time.Hour)))
+	save(t, repo, Order("contract-d", baseTime.Add(-time.Hour)))
+
+	got := list(t, repo, repository.OrderFilter{})
+	assertUIDs(t, got, "contract-a", "contract-b", "contract-c", "contract-d")
+	want := Order("contract-a", baseTime.Add(time.Hour))
+	assertOrder(t, &got[0], want)
+}
+
+func testListFilters(t *testing.T, repo repository.PostgresRepInterface) {
+	for i := range 4 {
+		o := Order(fmt.Sprintf("contract-%d", i), baseTime.Add(time.Duration(i)*time.Hour))
+		if i%2 == 1 {
+			o.CustomerId = "other"
+		}
+		save(t, repo, o)
+	}
+
+	// CreatedFrom включительно, CreatedTo - нет
+	got := list(t, repo, repository.OrderFilter{
+		CreatedFrom: baseTime.Add(time.Hour),
+		CreatedTo:   baseTime.Add(3 * time.Hour),
+	})
+	assertUIDs(t, got, "contract-2", "contract-1")
+
+	got = list(t, repo, repository.OrderFilter{CustomerID: "other"})
+	assertUIDs(t, got, "contract-3", "contract-1")
+
+	got = list(t, repo, repository.OrderFilter{CustomerID: "other", CreatedTo: baseTime.Add(3 * time.Hour)})
+	assertUIDs(t, got, "contract-1")
+
+	got = list(t, repo, repository.OrderFilter{CustomerID: "nobody"})
+	assertUIDs(t, got)
+}
+
+func testListPagination(t *testing.T, repo repository.PostgresRepInterface) {
+	for i := range 5 {
+		save(t, repo, Order(fmt.Sprintf("contract-%d", i), baseTime.Add(time.Duration(i)*time.Minute)))
+	}
+
+	assertUIDs(t, list(t, repo, repository.OrderFilter{Limit: 2}), "contract-4", "contract-3")
+	assertUIDs(t, list(t, repo, repository.OrderFilter{Limit: 2, Offset: 2}), "contract-2", "contract-1")
+	assertUIDs(t, list(t, repo, repository.OrderFilter{Limit: 2, Offset: 4}), "contract-0")
+	assertUIDs(t, list(t, repo, repository.OrderFilter{Offset: 3}), "contract-1", "contract-0")
+	assertUIDs(t, list(t, repo, repository.OrderFilter{Offset: 10}))
+}
+
+func testListEmpty(t *testing.T, repo repository.PostgresRepInterface) {
+	got := list(t, repo, repository.OrderFilter{})
+	if got == nil {
+		t.Error("ListOrders on empty storage = nil, want empty slice")
+	}
+	assertUIDs(t, got)
+}
+
+func testDeleteOrders(t *testing.T, repo repository.PostgresRepInterface) {
+	ctx := context.Background()
+	for i := range 3 {
+		save(t, repo, Order(fmt.Sprintf("contract-%d", i), baseTime.Add(time.Duration(i)*time.Hour)))
+	}
+
+	n, err := repo.DeleteOrders(ctx, []string{"contract-0", "contract-2", "contract-missing"})
+	if err != nil {
+		t.Fatalf("DeleteOrders: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("DeleteOrders deleted %d orders, want 2", n)
+	}
+	if _, err := repo.GetByID(ctx, "contract-0"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("GetByID deleted order = %v, want ErrNotFound", err)
+	}
+	assertUIDs(t, list(t, repo, repository.OrderFilter{}), "contract-1")
+
+	if n, err := repo.DeleteOrders(ctx, nil); err != nil || n != 0 {
+		t.Errorf("DeleteOrders(nil) = %d, %v, want 0, nil", n, err)
+	}
+	// удаленный заказ можно сохранить заново (например, восстановить из архива)
+	want := Order("contract-0", baseTime)
+	save(t, repo, want)
+	got, err := repo.GetByID(ctx, want.OrderUid)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	assertOrder(t, got, want)
+}
+
+func testSoftDelete(t *testing.T, repo repository.PostgresRepInterface) {
+	ctx := context.Background()
+	save(t, repo, Order("contract-active", baseTime))
+	want := Order("contract-deleted", baseTime.Add(time.Hour))
+	save(t, repo, want)
+
+	deletion := domain.Deletion{At: baseTime.Add(2 * time.Hour), Reason: "customer request", Actor: "support"}
+	if err := repo.SoftDeleteOrder(ctx, want.OrderUid, deletion); err != nil {
+		t.Fatalf("SoftDeleteOrder: %v", err)
+	}
+
+	// GetByID возвращает удаленный заказ с отметкой, скрывать его - дело сервиса
+	got, err := repo.GetByID(ctx, want.OrderUid)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	want.Deletion = &deletion
+	want.Version, want.UpdatedAt = 2, deletion.At
+	assertOrder(t, got, want)
+
+	assertUIDs(t, list(t, repo, repository.OrderFilter{}), "contract-active")
+	assertUIDs(t, list(t, repo, repository.OrderFilter{IncludeDeleted: true}), "contract-deleted", "contract-active")
+
+	again := domain.Deletion{At: baseTime.Add(3 * time.Hour), Reason: "again", Actor: "kafka"}
+	if err := repo.SoftDeleteOrder(ctx, want.OrderUid, again); !errors.Is(err, repository.ErrAlreadyDeleted) {
+		t.Errorf("SoftDeleteOrder twice = %v, want ErrAlreadyDeleted", err)
+	}
+	// повторное удаление не меняет отметку
+	if got, err := repo.GetByID(ctx, want.OrderUid); err != nil || !reflect.DeepEqual(got.Deletion, &deletion) {
+		t.Errorf("deletion after second delete = %+v, %v, want %+v", got.Deletion, err, deletion)
+	}
+	if err := repo.SoftDeleteOrder(ctx, "contract-missing", deletion); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("SoftDeleteOrder unknown order = %v, want ErrNotFound", err)
+	}
+}
+
+// Заказ с отметкой удаления (например, из архива) сохраняется удаленным
+func testSaveDeleted(t *testing.T, repo repository.PostgresRepInterface) {
+	want := Order("contract-restored", baseTime)
+	want.Deletion = &domain.Deletion{At: baseTime.Add(time.Hour), Reason: "duplicate", Actor: "admin"}
+	save(t, repo, want)
+
+	got, err := repo.GetByID(context.Background(), want.OrderUid)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	assertOrder(t, got, want)
+	assertUIDs(t, list(t, repo, repository.OrderFilter{}))
+}
+
+func testUpdateDelivery(t *testing.T, repo repository.PostgresRepInterface) {
+	ctx := context.Background()
+	want := Order("contract-update", baseTime)
+	save(t, repo, want)
+
+	moved := want.Delivery
+	moved.City, moved.Address = "Tel Aviv", "Dizengoff 1"
+	update := repository.DeliveryUpdate{Version: 1, Delivery: moved, Actor: "customer", At: baseTime.Add(time.Hour)}
+	got, err := repo.UpdateDelivery(ctx, want.OrderUid, update)
+	if err != nil {
+		t.Fatalf("UpdateDelivery: %v", err)
+	}
+	before := want.Delivery
+	want.Delivery, want.Version, want.UpdatedAt = moved, 2, update.At
+	assertOrder(t, got, want)
+	if got, err := repo.GetByID(ctx, want.OrderUid); err != nil {
+		t.Fatalf("GetByID: %v", err)
+	} else {
+		assertOrder(t, got, want)
+	}
+
+	// изменение устаревшей версии не применяется
+	stale := update
+	stale.Delivery.City = "Haifa"
+	if _, err := repo.UpdateDelivery(ctx, want.OrderUid, stale); !errors.Is(err, repository.ErrVersionConflict) {
+		t.Errorf("UpdateDelivery stale version = %v, want ErrVersionConflict", err)
+	}
+	if _, err := repo.UpdateDelivery(ctx, "contract-missing", update); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("UpdateDelivery unknown order = %v, want ErrNotFound", err)
+	}
+
+	history, err := repo.DeliveryHistory(ctx, want.OrderUid)
+	if err != nil {
+		t.Fatalf("DeliveryHistory: %v", err)
+	}
+	wantHistory := []domain.DeliveryChange{{Version: 2, ChangedAt: update.At, Actor: "customer", Before: before, After: moved}}
+	if !reflect.DeepEqual(history, wantHistory) {
+		t.Errorf("DeliveryHistory = %+v, want %+v", history, wantHistory)
+	}
+
+	// удаленный заказ не изменяется
+	deletion := domain.Deletion{At: baseTime.Add(2 * time.Hour), Reason: "cancelled"}
+	if err := repo.SoftDeleteOrder(ctx, want.OrderUid, deletion); err != nil {
+		t.Fatalf("SoftDeleteOrder: %v", err)
+	}
+	update.Version = 3
+	if _, err := repo.UpdateDelivery(ctx, want.OrderUid, update); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("UpdateDelivery deleted order = %v, want ErrNotFound", err)
+	}
+}
+
+// Заказ без доставки получает ее при первом изменении
+func testUpdateDeliveryCreates(t *testing.T, repo repository.PostgresRepInterface) {
+	ctx := context.Background()
+	want := Order("contract-no-delivery", baseTime)
+	want.Delivery = domain.Delivery{}
+	save(t, repo, want)
+
+	history, err := repo.DeliveryHistory(ctx, want.OrderUid)
+	if err != nil || history == nil || len(history) != 0 {
+		t.Fatalf("DeliveryHistory before changes = %v, %v, want empty", history, err)
+	}
+
+	delivery := Order("", baseTime).Delivery
+	got, err := repo.UpdateDelivery(ctx, want.OrderUid,
+		repository.DeliveryUpdate{Version: 1, Delivery: delivery, At: baseTime.Add(time.Hour)})
+	if err != nil {
+		t.Fatalf("UpdateDelivery: %v", err)
+	}
+	want.Delivery, want.Version, want.UpdatedAt = delivery, 2, baseTime.Add(time.Hour)
+	assertOrder(t, got, want)
+}
+
+func save(t *testing.T, repo repository.PostgresRepInterface, order *domain.Order) {
+	t.Helper()
+	if err := repo.SaveOrders(context.Background(), order); err != nil {
+		t.Fatalf("SaveOrders %s: %v", order.OrderUid, err)
+	}
+}
+
+func list(t *testing.T, repo repository.PostgresRepInterface, filter repository.OrderFilter) []domain.Order {
+	t.Helper()
+	orders, err := repo.ListOrders(context.Background(), filter)
+	if err != nil {
+		t.Fatalf("ListOrders(%+v): %v", filter, err)
+	}
+	return orders
+}
+
+func assertUIDs(t *testing.T, orders []domain.Order, want ...string) {
+	t.Helper()
+	got := make([]string, len(orders))
+	for i, o := range orders {
+		got[i] = o.OrderUid
+	}
+	if len(want) == 0 {
+		want = []string{}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("order uids = %v, want %v", got, want)
+	}
+}
+
+// assertOrder сравнивает заказы; время - по моменту, без учета часового пояса
+func assertOrder(t *testing.T, got, want *domain.Order) {
+	t.Helper()
+	if !got.DateCreated.Equal(want.DateCreated) {
+		t.Errorf("date_created = %v, want %v", got.DateCreated, want.DateCreated)
+	}
+	g, w := *got, *want
+	g.DateCreated, w.DateCreated = time.Time{}, time.Time{}
+	if !reflect.DeepEqual(g, w) {
+		t.Errorf("order mismatch:\n got %+v\nwant %+v", g, w)
+	}
+}
@@ -0,0 +1,446 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Sergi-Ch/WB_L0_2025/domain"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// sqliteTime - формат date_created в SQLite: UTC с микросекундами, как
+// timestamp в Postgres. Строки фиксированной длины сравниваются как время.
+const sqliteTime = "2006-01-02 15:04:05.000000"
+
+// SQLiteRepository - PostgresRepInterface поверх файла SQLite (без cgo).
+// Схема та же, что у Postgres, миграции - в migrations/sqlite.
+type SQLiteRepository struct {
+	db  *sql.DB
+	log *slog.Logger
+}
+
+// OpenSQLite открывает файл базы (создает, если его нет) с включенными
+// внешними ключами. Соединение одно: SQLite все равно пишет последовательно,
+// а так конкурентные транзакции не получают SQLITE_BUSY.
+func OpenSQLite(path string) (*sql.DB, error) {
+	q := url.Values{}
+	q.Add("_pragma", "foreign_keys(1)")
+	q.Add("_pragma", "busy_timeout(5000)")
+	q.Add("_pragma", "journal_mode(WAL)")
+	db, err := sql.Open("sqlite", "file:"+path+"?"+q.Encode())
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	return db, nil
+}
+
+func NewSQLiteRepository(path string, log *slog.Logger) (*SQLiteRepository, error) {
+	db, err := OpenSQLite(path)
+	if err != nil {
+		return nil, err
+	}
+	return &SQLiteRepository{db: db, log: log.With(slog.String("component", "sqlite"))}, nil
+}
+
+// DB - соединение для миграций
+func (r *SQLiteRepository) DB() *sql.DB {
+	return r.db
+}
+
+func (r *SQLiteRepository) SaveOrders(ctx context.Context, order *domain.Order) (err error) {
+	ctx, span := tracer.Start(ctx, "SQLiteRepository.SaveOrders",
+		trace.WithAttributes(attribute.String("order_uid", order.OrderUid)))
+	defer func() { endSpan(span, err) }()
+
+	var deletion domain.Deletion
+	var deletedAt, updatedAt *string
+	if order.Deletion != nil {
+		deletion = *order.Deletion
+		at := deletion.At.UTC().Format(sqliteTime)
+		deletedAt = &at
+	}
+	if !order.UpdatedAt.IsZero() {
+		at := order.UpdatedAt.UTC().Format(sqliteTime)
+		updatedAt = &at
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO orders (order_uid, track_number, entry, locale, internal_signature,
+			customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard,
+			deleted_at, delete_reason, deleted_by, version, updated_at)
+		VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)`,
+		order.OrderUid, order.TrackNumber, order.Entry, order.Locale, order.InternalSignature,
+		order.CustomerId, order.DeliveryService, order.Shardkey, order.SmId,
+		order.DateCreated.UTC().Format(sqliteTime), order.OofShard,
+		deletedAt, deletion.Reason, deletion.Actor, max(order.Version, 1), updatedAt)
+	if err != nil {
+		var sqliteErr *sqlite.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY {
+			return fmt.Errorf("%w: %s", ErrAlreadyExists, order.OrderUid)
+		}
+		return fmt.Errorf("insert orders failed: %w", err)
+	}
+
+	d := order.Delivery
+	_, err = tx.ExecContext(ctx, `INSERT INTO deliveries (order_uid, name, phone, zip, city, address, region, email)
+		VALUES (?,?,?,?,?,?,?,?)`,
+		order.OrderUid, d.Name, d.Phone, d.Zip, d.City, d.Address, d.Region, d.Email)
+	if err != nil {
+		return fmt.Errorf("insert delivery failed: %w", err)
+	}
+
+	p := order.Payment
+	_, err = tx.ExecContext(ctx, `INSERT INTO payments (order_uid, "transaction", request_id, currency, provider,
+			amount, payment_dt, bank, delivery_cost, goods_total, custom_fee)
+		VALUES (?,?,?,?,?,?,?,?,?,?,?)`,
+		order.OrderUid, p.Transaction, p.RequestId, p.Currency, p.Provider,
+		p.Amount, p.PaymentDt, p.Bank, p.DeliveryCost, p.GoodsTotal, p.CustomFee)
+	if err != nil {
+		return fmt.Errorf("insert payment failed: %w", err)
+	}
+
+	for _, item := range order.Items {
+		_, err = tx.ExecContext(ctx, `INSERT INTO items (order_uid, chrt_id, track_number, price, rid, name,
+				sale, size, total_price, nm_id, brand, status)
+			VALUES (?,?,?,?,?,?,?,?,?,?,?,?)`,
+			order.OrderUid, item.ChrtId, item.TrackNumber, item.Price, item.Rid, item.Name,
+			item.Sale, item.Size, item.TotalPrice, item.NmId, item.Brand, item.Status)
+		if err != nil {
+			return fmt.Errorf("insert item failed: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit failed: %w", err)
+	}
+
+	r.log.DebugContext(ctx, "order stored",
+		slog.String("order_uid", order.OrderUid),
+		slog.Int("items", len(order.Items)),
+	)
+	return nil
+}
+
+// GetByID читает заказ и его части отдельными запросами: отсутствующие
+// доставка или оплата остаются пустыми, заказ без товаров - с пустым списком
+func (r *SQLiteRepository) GetByID(ctx context.Context, orderUID string) (*domain.Order, error) {
+	var (
+		order     domain.Order
+		created   string
+		deletion  domain.Deletion
+		deletedAt sql.NullString
+		updatedAt sql.NullString
+	)
+	err := r.db.QueryRowContext(ctx, `SELECT order_uid, track_number, entry, locale, internal_signature, customer_id,
+			delivery_service, shardkey, sm_id, date_created, oof_shard, deleted_at, delete_reason, deleted_by,
+			version, updated_at
+		FROM orders WHERE order_uid = ?`, orderUID).Scan(
+		&order.OrderUid, &order.TrackNumber, &order.Entry, &order.Locale, &order.InternalSignature,
+		&order.CustomerId, &order.DeliveryService, &order.Shardkey, &order.SmId, &created, &order.OofShard,
+		&deletedAt, &deletion.Reason, &deletion.Actor, &order.Version, &updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, orderUID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query order failed: %w", err)
+	}
+	if order.DateCreated, err = time.Parse(sqliteTime, created); err != nil {
+		return nil, fmt.Errorf("parse date_created %q: %w", created, err)
+	}
+	if deletedAt.Valid {
+		if deletion.At, err = time.Parse(sqliteTime, deletedAt.String); err != nil {
+			return nil, fmt.Errorf("parse deleted_at %q: %w", deletedAt.String, err)
+		}
+		order.Deletion = &deletion
+	}
+	if updatedAt.Valid {
+		if order.UpdatedAt, err = time.Parse(sqliteTime, updatedAt.String); err != nil {
+			return nil, fmt.Errorf("parse updated_at %q: %w", updatedAt.String, err)
+		}
+	}
+
+	d := &order.Delivery
+	err = r.db.QueryRowContext(ctx, `SELECT name, phone, zip, city, address, region, email
+		FROM deliveries WHERE order_uid = ?`, orderUID).Scan(
+		&d.Name, &d.Phone, &d.Zip, &d.City, &d.Address, &d.Region, &d.Email)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("query delivery failed: %w", err)
+	}
+
+	p := &order.Payment
+	err = r.db.QueryRowContext(ctx, `SELECT "transaction", request_id, currency, provider, amount, payment_dt, bank,
+			delivery_cost, goods_total, custom_fee
+		FROM payments WHERE order_uid = ?`, orderUID).Scan(
+		&p.Transaction, &p.RequestId, &p.Currency, &p.Provider,
+		&p.Amount, &p.PaymentDt, &p.Bank, &p.DeliveryCost, &p.GoodsTotal, &p.CustomFee)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("query payment failed: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `SELECT chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status
+		FROM items WHERE order_uid = ? ORDER BY id`, orderUID)
+	if err != nil {
+		return nil, fmt.Errorf("query items failed: %w", err)
+	}
+	defer rows.Close()
+
+	order.Items = []domain.Item{}
+	for rows.Next() {
+		var item domain.Item
+		if err := rows.Scan(&item.ChrtId, &item.TrackNumber, &item.Price, &item.Rid, &item.Name,
+			&item.Sale, &item.Size, &item.TotalPrice, &item.NmId, &item.Brand, &item.Status); err != nil {
+			return nil, fmt.Errorf("scan item failed: %w", err)
+		}
+		order.Items = append(order.Items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read items failed: %w", err)
+	}
+	return &order, nil
+}
+
+func (r *SQLiteRepository) ListOrders(ctx context.Context, filter OrderFilter) ([]domain.Order, error) {
+	query := `SELECT order_uid FROM orders WHERE 1=1`
+	var args []any
+	if !filter.CreatedFrom.IsZero() {
+		query += " AND date_created >= ?"
+		args = append(args, filter.CreatedFrom.UTC().Format(sqliteTime))
+	}
+	if !filter.CreatedTo.IsZero() {
+		query += " AND date_created < ?"
+		args = append(args, filter.CreatedTo.UTC().Format(sqliteTime))
+	}
+	if filter.CustomerID != "" {
+		query += " AND customer_id = ?"
+		args = append(args, filter.CustomerID)
+	}
+	if !filter.IncludeDeleted {
+		query += " AND deleted_at IS NULL"
+	}
+	query += " ORDER BY date_created DESC, order_uid"
+	// в SQLite OFFSET бывает только вместе с LIMIT, -1 - без ограничения
+	if filter.Limit > 0 || filter.Offset > 0 {
+		limit := filter.Limit
+		if limit <= 0 {
+			limit = -1
+		}
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, limit, max(filter.Offset, 0))
+	}
+
+	uids, err := r.orderUIDs(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	// соединение одно: заказы читаем после закрытия курсора по order_uid
+	orders := make([]domain.Order, 0, len(uids))
+	for _, uid := range uids {
+		order, err := r.GetByID(ctx, uid)
+		if err != nil {
+			return nil, fmt.Errorf("load order %s: %w", uid, err)
+		}
+		orders = append(orders, *order)
+	}
+	return orders, nil
+}
+
+func (r *SQLiteRepository) DeleteOrders(ctx context.Context, orderUIDs []string) (int, error) {
+	if len(orderUIDs) == 0 {
+		return 0, nil
+	}
+	args := make([]any, len(orderUIDs))
+	for i, uid := range orderUIDs {
+		args[i] = uid
+	}
+	// части заказа удаляются каскадом (foreign_keys включены в OpenSQLite)
+	res, err := r.db.ExecContext(ctx, `DELETE FROM orders WHERE order_uid IN (?`+
+		strings.Repeat(",?", len(orderUIDs)-1)+`)`, args...)
+	if err != nil {
+		return 0, fmt.Errorf("delete orders failed: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("delete orders failed: %w", err)
+	}
+	return int(n), nil
+}
+
+func (r *SQLiteRepository) SoftDeleteOrder(ctx context.Context, orderUID string, deletion domain.Deletion) error {
+	at := deletion.At.UTC().Format(sqliteTime)
+	res, err := r.db.ExecContext(ctx, `UPDATE orders SET deleted_at = ?, delete_reason = ?, deleted_by = ?,
+			version = version + 1, updated_at = ?
+		WHERE order_uid = ? AND deleted_at IS NULL`,
+		at, deletion.Reason, deletion.Actor, at, orderUID)
+	if err != nil {
+		return fmt.Errorf("soft delete order failed: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("soft delete order failed: %w", err)
+	}
+	if n > 0 {
+		return nil
+	}
+
+	// ничего не обновлено: заказа нет или он уже удален
+	var exists bool
+	err = r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM orders WHERE order_uid = ?)`, orderUID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("soft delete order failed: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrNotFound, orderUID)
+	}
+	return fmt.Errorf("%w: %s", ErrAlreadyDeleted, orderUID)
+}
+
+func (r *SQLiteRepository) UpdateDelivery(ctx context.Context, orderUID string, update DeliveryUpdate) (_ *domain.Order, err error) {
+	ctx, span := tracer.Start(ctx, "SQLiteRepository.UpdateDelivery",
+		trace.WithAttributes(attribute.String("order_uid", orderUID)))
+	defer func() { endSpan(span, err) }()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	at := update.At.UTC().Format(sqliteTime)
+	var version int64
+	err = tx.QueryRowContext(ctx, `UPDATE orders SET version = version + 1, updated_at = ?
+		WHERE order_uid = ? AND version = ? AND deleted_at IS NULL
+		RETURNING version`, at, orderUID, update.Version).Scan(&version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, r.updateConflict(ctx, tx, orderUID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("update order version failed: %w", err)
+	}
+
+	var before domain.Delivery
+	err = tx.QueryRowContext(ctx, `SELECT name, phone, zip, city, address, region, email
+		FROM deliveries WHERE order_uid = ?`, orderUID).Scan(
+		&before.Name, &before.Phone, &before.Zip, &before.City, &before.Address, &before.Region, &before.Email)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("query delivery failed: %w", err)
+	}
+
+	// у заказа может не быть доставки: тогда она создается
+	d := update.Delivery
+	_, err = tx.ExecContext(ctx, `INSERT INTO deliveries (order_uid, name, phone, zip, city, address, region, email)
+		VALUES (?,?,?,?,?,?,?,?)
+		ON CONFLICT (order_uid) DO UPDATE SET name = excluded.name, phone = excluded.phone,
+			zip = excluded.zip, city = excluded.city, address = excluded.address,
+			region = excluded.region, email = excluded.email`,
+		orderUID, d.Name, d.Phone, d.Zip, d.City, d.Address, d.Region, d.Email)
+	if err != nil {
+		return nil, fmt.Errorf("update delivery failed: %w", err)
+	}
+
+	beforeJSON, afterJSON, err := marshalChange(before, d)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO delivery_history (order_uid, version, changed_at, actor, before, after)
+		VALUES (?,?,?,?,?,?)`, orderUID, version, at, update.Actor, beforeJSON, afterJSON)
+	if err != nil {
+		return nil, fmt.Errorf("insert delivery history failed: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit failed: %w", err)
+	}
+	return r.GetByID(ctx, orderUID)
+}
+
+// updateConflict объясняет, почему условный UPDATE заказа ничего не изменил.
+// Соединение одно, поэтому читает в той же транзакции.
+func (r *SQLiteRepository) updateConflict(ctx context.Context, tx *sql.Tx, orderUID string) error {
+	var deleted bool
+	err := tx.QueryRowContext(ctx, `SELECT deleted_at IS NOT NULL FROM orders WHERE order_uid = ?`, orderUID).Scan(&deleted)
+	switch {
+	case errors.Is(err, sql.ErrNoRows), err == nil && deleted:
+		return fmt.Errorf("%w: %s", ErrNotFound, orderUID)
+	case err != nil:
+		return fmt.Errorf("query order failed: %w", err)
+	}
+	return fmt.Errorf("%w: %s", ErrVersionConflict, orderUID)
+}
+
+func (r *SQLiteRepository) DeliveryHistory(ctx context.Context, orderUID string) ([]domain.DeliveryChange, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT version, changed_at, actor, before, after
+		FROM delivery_history WHERE order_uid = ? ORDER BY version`, orderUID)
+	if err != nil {
+		return nil, fmt.Errorf("query delivery history failed: %w", err)
+	}
+	defer rows.Close()
+
+	changes := []domain.DeliveryChange{}
+	for rows.Next() {
+		var (
+			c             domain.DeliveryChange
+			changed       string
+			before, after []byte
+		)
+		if err := rows.Scan(&c.Version, &changed, &c.Actor, &before, &after); err != nil {
+			return nil, fmt.Errorf("scan delivery history failed: %w", err)
+		}
+		if c.ChangedAt, err = time.Parse(sqliteTime, changed); err != nil {
+			return nil, fmt.Errorf("parse changed_at %q: %w", changed, err)
+		}
+		if err := unmarshalChange(&c, before, after); err != nil {
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("read delivery history failed: %w", err)
+	}
+	return changes, nil
+}
+
+func (r *SQLiteRepository) orderUIDs(ctx context.Context, query string, args ...any) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var uids []string
+	for rows.Next() {
+		var uid string
+		if err := rows.Scan(&uid); err != nil {
+			return nil, fmt.Errorf("scan order uids failed: %w", err)
+		}
+		uids = append(uids, uid)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("scan order uids failed: %w", err)
+	}
+	return uids, nil
+}
+
+// Ping проверяет, что файл базы доступен (для health-check)
+func (r *SQLiteRepository) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
+
+func (r *SQLiteRepository) Close() error {
+	return r.db.Close()
+}
@@ -0,0 +1,159 @@
+package repository_test
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Sergi-Ch/WB_L0_2025/domain"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/migrate"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/repository"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/repository/repotest"
+)
+
+func TestSQLiteRepositoryContract(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repository.PostgresRepInterface {
+		return newSQLiteRepository(t)
+	})
+}
+
+// Заказ, записанный в обход SaveOrders (старые данные, ручные правки):
+// без доставки, оплаты и товаров
+func TestSQLiteRepositoryGetByIDMissingChildren(t *testing.T) {
+	repo := newSQLiteRepository(t)
+	ctx := context.Background()
+
+	_, err := repo.DB().ExecContext(ctx, `INSERT INTO orders (order_uid, track_number, date_created)
+		VALUES ('bare-order', 'TRACK', '2025-03-01 12:00:00.000000')`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	order, err := repo.GetByID(ctx, "bare-order")
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	assertBareOrder(t, order)
+}
+
+func newSQLiteRepository(t *testing.T) *repository.SQLiteRepository {
+	t.Helper()
+	log := slog.New(slog.DiscardHandler)
+	repo, err := repository.NewSQLiteRepository(filepath.Join(t.TempDir(), "orders.db"), log)
+	if err != nil {
+		t.Fatalf("NewSQLiteRepository: %v", err)
+	}
+	t.Cleanup(func() { repo.Close() })
+
+	if _, err := migrate.UpSQLite(context.Background(), repo.DB(), "../../migrations/sqlite", log); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return repo
+}
+
+// assertBareOrder проверяет заказ из строки orders без дочерних строк
+func assertBareOrder(t *testing.T, order *domain.Order) {
+	t.Helper()
+	if order.OrderUid != "bare-order" || order.TrackNumber != "TRACK" {
+		t.Errorf("order = %+v", order)
+	}
+	if want := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC); !order.DateCreated.Equal(want) {
+		t.Errorf("date_created = %v, want %v", order.DateCreated, want)
+	}
+	if order.Delivery != (domain.Delivery{}) || order.Payment != (domain.Payment{}) {
+		t.Errorf("delivery/payment = %+v / %+v, want empty", order.Delivery, order.Payment)
+	}
+	if order.Items == nil || len(order.Items) != 0 {
+		t.Errorf("items = %#v, want empty slice", order.Items)
+	}
+}
+
+// Данные, записанные по схеме 001 (NULL, дубли доставки и оплаты),
+// переживают миграцию 002 и читаются так же, как до нее
+func TestSQLiteMigrationUpgradesExistingRows(t *testing.T) {
+	ctx := context.Background()
+	log := slog.New(slog.DiscardHandler)
+	repo, err := repository.NewSQLiteRepository(filepath.Join(t.TempDir(), "orders.db"), log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close()
+	db := repo.DB()
+
+	dir := t.TempDir()
+	initSQL, err := os.ReadFile("../../migrations/sqlite/001_init.up.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "001_init.up.sql"), initSQL, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrate.UpSQLite(ctx, db, dir, log); err != nil {
+		t.Fatalf("migrate 001: %v", err)
+	}
+
+	for _, q := range []string{
+		`INSERT INTO orders (order_uid, track_number, date_created) VALUES ('bare-order', 'TRACK', '2025-03-01 12:00:00.000000')`,
+		`INSERT INTO orders (order_uid, date_created, customer_id) VALUES ('dup-order', '2025-03-02 12:00:00.000000', NULL)`,
+		`INSERT INTO deliveries (order_uid, name) VALUES ('dup-order', 'first'), ('dup-order', 'second'), (NULL, 'orphan')`,
+		`INSERT INTO payments (order_uid, amount, payment_dt) VALUES ('dup-order', 3000000000, NULL), ('dup-order', 1, 1)`,
+		`INSERT INTO items (order_uid, name, price) VALUES ('dup-order', 'a', NULL), ('dup-order', 'b', 2), (NULL, 'orphan', 1)`,
+	} {
+		if _, err := db.ExecContext(ctx, q); err != nil {
+			t.Fatalf("%s: %v", q, err)
+		}
+	}
+
+	if _, err := migrate.UpSQLite(ctx, db, "../../migrations/sqlite", log); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	bare, err := repo.GetByID(ctx, "bare-order")
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	assertBareOrder(t, bare)
+
+	got, err := repo.GetByID(ctx, "dup-order")
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if got.Delivery.Name != "first" || got.Payment.Amount != 3000000000 || got.Payment.PaymentDt != 0 {
+		t.Errorf("delivery/payment = %+v / %+v, want first rows", got.Delivery, got.Payment)
+	}
+	want := []domain.Item{{Name: "a"}, {Name: "b", Price: 2}}
+	if !reflect.DeepEqual(got.Items, want) {
+		t.Errorf("items = %+v, want %+v", got.Items, want)
+	}
+
+	var orphans int
+	if err := db.QueryRowContext(ctx, `SELECT
+			(SELECT COUNT(*) FROM deliveries WHERE order_uid IS NULL) +
+			(SELECT COUNT(*) FROM items WHERE order_uid IS NULL)`).Scan(&orphans); err != nil {
+		t.Fatal(err)
+	}
+	if orphans != 0 {
+		t.Errorf("%d rows without order left", orphans)
+	}
+
+	// вторая доставка запрещена, удаление заказа удаляет его части
+	if _, err := db.ExecContext(ctx, `INSERT INTO deliveries (order_uid) VALUES ('dup-order')`); err == nil {
+		t.Error("second delivery for an order was accepted")
+	}
+	if _, err := db.ExecContext(ctx, `DELETE FROM orders WHERE order_uid = 'dup-order'`); err != nil {
+		t.Fatalf("delete order: %v", err)
+	}
+	var children int
+	if err := db.QueryRowContext(ctx, `SELECT
+			(SELECT COUNT(*) FROM deliveries) + (SELECT COUNT(*) FROM payments) +
+			(SELECT COUNT(*) FROM items)`).Scan(&children); err != nil {
+		t.Fatal(err)
+	}
+	if children != 0 {
+		t.Errorf("%d child rows left after deleting the order", children)
+	}
+}
@@ -2,8 +2,9 @@ package service
 
 import (
 	"context"
-	"errors"
 	"github.com/Sergi-Ch/WB_L0_2025/domain"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/repository"
+	"log/slog"
 	"testing"
 	"time"
 )
@@ -13,9 +14,9 @@ func BenchmarkGetOrderFromCache(b *testing.B) {
 	order := createTestOrder()
 
 	cache := &MockCache{orders: make(map[string]*domain.Order)}
-	postgres := &MockPostgres{orders: make(map[string]*domain.Order)}
+	postgres := repository.NewMemoryRepository(repository.MemoryOptions{Latency: 10 * time.Millisecond})
 
-	service := NewOrderService(postgres, cache)
+	service := NewOrderService(postgres, cache, slog.New(slog.DiscardHandler))
 
 	ctx := context.Background()
 	if err := service.SaveOrder(ctx, order); err != nil {
@@ -25,7 +26,7 @@ func BenchmarkGetOrderFromCache(b *testing.B) {
 	b.ResetTimer()
 	b.RunParallel(func(pb *testing.PB) {
 		for pb.Next() {
-			_, err := service.GetOrderByID(ctx, order.OrderUid)
+			_, err := service.GetOrderByID(ctx, order.OrderUid, GetOptions{})
 			if err != nil {
 				b.Errorf("failed to get order from cache: %v", err)
 			}
@@ -38,21 +39,21 @@ func BenchmarkGetOrderFromDB(b *testing.B) {
 	order := createTestOrder()
 
 	cache := &MockCache{orders: make(map[string]*domain.Order)}
-	postgres := &MockPostgres{orders: make(map[string]*domain.Order)}
+	postgres := repository.NewMemoryRepository(repository.MemoryOptions{Latency: 10 * time.Millisecond})
 
-	service := NewOrderService(postgres, cache)
+	service := NewOrderService(postgres, cache, slog.New(slog.DiscardHandler))
 
 	ctx := context.Background()
 	if err := service.SaveOrder(ctx, order); err != nil {
 		b.Fatalf("failed to save order: %v", err)
 	}
 
-	cache.Delete(order.OrderUid)
+	cache.Delete(ctx, order.OrderUid)
 
 	b.ResetTimer()
 	b.RunParallel(func(pb *testing.PB) {
 		for pb.Next() {
-			_, err := service.GetOrderByID(ctx, order.OrderUid)
+			_, err := service.GetOrderByID(ctx, order.OrderUid, GetOptions{})
 			if err != nil {
 				b.Errorf("failed to get order from DB: %v", err)
 			}
@@ -88,7 +89,7 @@ func createTestOrder() *domain.Order {
 			Currency:     "RUB",
 			Provider:     "SBER",
 			Amount:       1500,
-			PaymentDt:    int(now.Unix()),
+			PaymentDt:    now.Unix(),
 			Bank:         "SBERBANK",
 			DeliveryCost: 500,
 			GoodsTotal:   1000,
@@ -130,34 +131,15 @@ type MockCache struct {
 	orders map[string]*domain.Order
 }
 
-func (m *MockCache) Set(key string, order domain.Order) {
+func (m *MockCache) Set(_ context.Context, key string, order domain.Order) {
 	m.orders[key] = &order
 }
 
-func (m *MockCache) Get(key string) (*domain.Order, bool) {
+func (m *MockCache) Get(_ context.Context, key string) (*domain.Order, bool) {
 	order, exists := m.orders[key]
 	return order, exists
 }
 
-func (m *MockCache) Delete(key string) {
+func (m *MockCache) Delete(_ context.Context, key string) {
 	delete(m.orders, key)
 }
-
-type MockPostgres struct {
-	orders map[string]*domain.Order
-}
-
-func (m *MockPostgres) SaveOrders(ctx context.Context, order *domain.Order) error {
-	time.Sleep(10 * time.Millisecond)
-	m.orders[order.OrderUid] = order
-	return nil
-}
-
-func (m *MockPostgres) GetByID(ctx context.Context, id string) (*domain.Order, error) {
-	time.Sleep(10 * time.Millisecond)
-	order, exists := m.orders[id]
-	if !exists {
-		return nil, errors.New("order not found")
-	}
-	return order, nil
-}
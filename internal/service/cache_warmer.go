@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/Sergi-Ch/WB_L0_2025/internal/logger"
+)
+
+type WarmUpState string
+
+const (
+	WarmUpPending WarmUpState = "pending"
+	WarmUpRunning WarmUpState = "running"
+	WarmUpDone    WarmUpState = "done"
+	WarmUpFailed  WarmUpState = "failed"
+)
+
+// CacheWarmer прогревает кэш при старте и хранит состояние прогрева
+// для readiness-проверки
+type CacheWarmer struct {
+	service *OrderService
+	limit   int
+	log     *slog.Logger
+
+	mu     sync.RWMutex
+	state  WarmUpState
+	warmed int
+	err    error
+}
+
+func NewCacheWarmer(s *OrderService, limit int, log *slog.Logger) *CacheWarmer {
+	return &CacheWarmer{
+		service: s,
+		limit:   limit,
+		log:     log.With(slog.String("component", "cache_warmer")),
+		state:   WarmUpPending,
+	}
+}
+
+// Run выполняет прогрев; повторный вызов прогревает кэш заново
+func (w *CacheWarmer) Run(ctx context.Context) error {
+	w.setState(WarmUpRunning, 0, nil)
+	start := time.Now()
+
+	n, err := w.service.WarmCache(ctx, w.limit)
+	if err != nil {
+		w.setState(WarmUpFailed, n, err)
+		w.log.ErrorContext(ctx, "cache warm-up failed", logger.Err(err))
+		return err
+	}
+
+	w.setState(WarmUpDone, n, nil)
+	w.log.InfoContext(ctx, "cache warmed up",
+		slog.Int("orders", n),
+		slog.Duration("duration", time.Since(start)),
+	)
+	return nil
+}
+
+// State возвращает текущее состояние прогрева и число загруженных заказов
+func (w *CacheWarmer) State() (WarmUpState, int) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.state, w.warmed
+}
+
+// Check реализует health.Checker: ошибка, пока прогрев не завершен успешно
+func (w *CacheWarmer) Check(context.Context) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	switch w.state {
+	case WarmUpDone:
+		return nil
+	case WarmUpFailed:
+		return fmt.Errorf("cache warm-up failed: %w", w.err)
+	default:
+		return errors.New("cache warm-up " + string(w.state))
+	}
+}
+
+func (w *CacheWarmer) setState(state WarmUpState, warmed int, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.state = state
+	w.warmed = warmed
+	w.err = err
+}
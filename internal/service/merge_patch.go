@@ -0,0 +1,67 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/Sergi-Ch/WB_L0_2025/domain"
+)
+
+// ErrInvalidPatch - тело PATCH не является JSON merge patch объекта
+var ErrInvalidPatch = errors.New("invalid patch")
+
+// patchDelivery применяет к доставке JSON merge patch (RFC 7396): поля из
+// patch заменяют текущие, null сбрасывает поле в пустое значение
+func patchDelivery(delivery domain.Delivery, patch []byte) (domain.Delivery, error) {
+	var p any
+	if err := json.Unmarshal(patch, &p); err != nil {
+		return domain.Delivery{}, fmt.Errorf("%w: %w", ErrInvalidPatch, err)
+	}
+	if _, ok := p.(map[string]any); !ok {
+		return domain.Delivery{}, fmt.Errorf("%w: patch must be a JSON object", ErrInvalidPatch)
+	}
+
+	current, err := json.Marshal(delivery)
+	if err != nil {
+		return domain.Delivery{}, err
+	}
+	var target any
+	if err := json.Unmarshal(current, &target); err != nil {
+		return domain.Delivery{}, err
+	}
+	merged, err := json.Marshal(mergePatch(target, p))
+	if err != nil {
+		return domain.Delivery{}, err
+	}
+
+	// неизвестные поля и значения не того типа - ошибка клиента
+	var result domain.Delivery
+	dec := json.NewDecoder(bytes.NewReader(merged))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&result); err != nil {
+		return domain.Delivery{}, fmt.Errorf("%w: %w", ErrInvalidPatch, err)
+	}
+	return result, nil
+}
+
+// mergePatch - алгоритм MergePatch из RFC 7396
+func mergePatch(target, patch any) any {
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]any)
+	if !ok {
+		t = map[string]any{}
+	}
+	for name, value := range p {
+		if value == nil {
+			delete(t, name)
+			continue
+		}
+		t[name] = mergePatch(t[name], value)
+	}
+	return t
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/Sergi-Ch/WB_L0_2025/domain"
+)
+
+// примеры из приложения A RFC 7396
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		target, patch, want string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+		// вложенные объекты сливаются, а не заменяются
+		{`{"a":{"b":"c","d":{"e":"f"}}}`, `{"a":{"d":{"g":"h"}}}`, `{"a":{"b":"c","d":{"e":"f","g":"h"}}}`},
+	}
+	decode := func(s string) any {
+		var v any
+		if err := json.Unmarshal([]byte(s), &v); err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	for _, tt := range tests {
+		got := mergePatch(decode(tt.target), decode(tt.patch))
+		if !reflect.DeepEqual(got, decode(tt.want)) {
+			t.Errorf("mergePatch(%s, %s) = %v, want %s", tt.target, tt.patch, got, tt.want)
+		}
+	}
+}
+
+func TestPatchDelivery(t *testing.T) {
+	delivery := domain.Delivery{Name: "Test Testov", Phone: "+9720000000", City: "Kiryat Mozkin", Region: "Kraiot"}
+
+	got, err := patchDelivery(delivery, []byte(`{"city":"Haifa","region":null}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := delivery
+	want.City, want.Region = "Haifa", ""
+	if got != want {
+		t.Errorf("patchDelivery() = %+v, want %+v", got, want)
+	}
+
+	for _, patch := range []string{
+		// patch применяется только к доставке: поля заказа - неизвестные поля
+		`{"order_uid":"other"}`,
+		`{"delivery":{"city":"Haifa"}}`,
+		`{"country":"IL"}`,
+		`{"city":{"name":"Haifa"}}`,
+		`{"phone":["+9720000000"]}`,
+		`"Haifa"`,
+		`null`,
+	} {
+		if _, err := patchDelivery(delivery, []byte(patch)); !errors.Is(err, ErrInvalidPatch) {
+			t.Errorf("patchDelivery(%s) error = %v, want %v", patch, err, ErrInvalidPatch)
+		}
+	}
+}
@@ -5,80 +5,312 @@ import (
 	"errors"
 	"fmt"
 	"github.com/Sergi-Ch/WB_L0_2025/domain"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/logger"
 	"github.com/Sergi-Ch/WB_L0_2025/internal/repository"
-	"log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
 	"regexp"
 	"strings"
 	"time"
 )
 
+// ErrInvalidOrder - заказ не прошел валидацию
+var ErrInvalidOrder = errors.New("invalid order")
+
+// ErrInvalidOrderID - order_uid в запросе пустой, слишком длинный или с недопустимыми символами
+var ErrInvalidOrderID = errors.New("invalid order id")
+
+// ErrUnavailable - хранилище недоступно (нет соединения, таймаут); запрос
+// можно повторить позже
+var ErrUnavailable = errors.New("storage unavailable")
+
 type OrderServiceInterface interface {
 	SaveOrder(ctx context.Context, order *domain.Order) error
-	GetOrderByID(ctx context.Context, id string) (*domain.Order, error)
+	GetOrderByID(ctx context.Context, id string, opts GetOptions) (*domain.Order, error)
+	// DeleteOrder мягко удаляет (отменяет) заказ: repository.ErrNotFound - его
+	// нет, repository.ErrAlreadyDeleted - он уже удален
+	DeleteOrder(ctx context.Context, id, reason, actor string) error
+	// UpdateDelivery применяет JSON merge patch к доставке заказа версии
+	// version (0 - текущей): repository.ErrVersionConflict - версия другая
+	UpdateDelivery(ctx context.Context, id string, patch []byte, version int64, actor string) (*domain.Order, error)
+	DeliveryHistory(ctx context.Context, id string) ([]domain.DeliveryChange, error)
+}
+
+type GetOptions struct {
+	// IncludeDeleted - вернуть и мягко удаленный заказ (с Deletion);
+	// иначе для него ErrNotFound
+	IncludeDeleted bool
 }
 
 type OrderService struct {
 	cache    repository.CacheInterface
 	postgres repository.PostgresRepInterface
+	log      *slog.Logger
 }
 
-func NewOrderService(pg repository.PostgresRepInterface, redis repository.RedisInterface) *OrderService {
+func NewOrderService(pg repository.PostgresRepInterface, redis repository.RedisInterface, log *slog.Logger) *OrderService {
 	return &OrderService{
 		postgres: pg,
 		cache:    redis,
+		log:      log.With(slog.String("component", "order_service")),
 	}
 }
 
-func (s *OrderService) SaveOrder(ctx context.Context, order *domain.Order) error {
+var tracer = otel.Tracer("github.com/Sergi-Ch/WB_L0_2025/internal/service")
 
-	if err := s.validateOrder(order); err != nil {
-		log.Printf("order validation failed: %v", err)
-		return fmt.Errorf("invalid order: %w", err)
+func (s *OrderService) SaveOrder(ctx context.Context, order *domain.Order) (err error) {
+	ctx, span := tracer.Start(ctx, "OrderService.SaveOrder")
+	defer func() { endSpan(span, err) }()
+	if order != nil {
+		span.SetAttributes(attribute.String("order_uid", order.OrderUid))
+	}
+
+	_, validateSpan := tracer.Start(ctx, "OrderService.validateOrder")
+	err = s.validateOrder(order)
+	endSpan(validateSpan, err)
+	if err != nil {
+		s.log.WarnContext(ctx, "order validation failed", orderUID(order), logger.Err(err))
+		return fmt.Errorf("%w: %w", ErrInvalidOrder, err)
 	}
 
 	if err := s.postgres.SaveOrders(ctx, order); err != nil {
-		log.Printf("failed to save order in postgres: %v", err)
+		s.log.ErrorContext(ctx, "failed to save order in postgres", orderUID(order), logger.Err(err))
+		return storageError(err)
+	}
+
+	// удаленные заказы (например, из импорта) в кэш не попадают
+	if order.Deletion == nil {
+		cached := *order
+		cached.Version = max(cached.Version, 1)
+		s.cache.Set(ctx, order.OrderUid, cached)
+	}
+
+	return nil
+}
+
+func (s *OrderService) GetOrderByID(ctx context.Context, id string, opts GetOptions) (_ *domain.Order, err error) {
+	ctx, span := tracer.Start(ctx, "OrderService.GetOrderByID",
+		trace.WithAttributes(attribute.String("order_uid", id)))
+	defer func() { endSpan(span, err) }()
+
+	if err := validateOrderID(id); err != nil {
+		return nil, err
+	}
+
+	// в кэше только действующие заказы: удаление его инвалидирует. Записи
+	// без версии остались от старых версий сервиса и перечитываются из базы.
+	if order, ok := s.cache.Get(ctx, id); ok && order.Deletion == nil && order.Version > 0 {
+		span.SetAttributes(attribute.Bool("cache.hit", true))
+		return order, nil
+	}
+
+	order, err := s.postgres.GetByID(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		s.log.InfoContext(ctx, "order not found", slog.String("order_uid", id))
+		return nil, err
+	}
+	if err != nil {
+		s.log.ErrorContext(ctx, "failed to load order from storage", slog.String("order_uid", id), logger.Err(err))
+		return nil, storageError(err)
+	}
+
+	if order.Deletion != nil {
+		if !opts.IncludeDeleted {
+			return nil, fmt.Errorf("%w: %s is deleted", repository.ErrNotFound, id)
+		}
+		return order, nil
+	}
+	s.cache.Set(ctx, order.OrderUid, *order)
+	return order, nil
+}
+
+func (s *OrderService) DeleteOrder(ctx context.Context, id, reason, actor string) (err error) {
+	ctx, span := tracer.Start(ctx, "OrderService.DeleteOrder",
+		trace.WithAttributes(attribute.String("order_uid", id)))
+	defer func() { endSpan(span, err) }()
+
+	if err := validateOrderID(id); err != nil {
 		return err
 	}
 
-	s.cache.Set(order.OrderUid, *order)
+	deletion := domain.Deletion{
+		// точность timestamptz в Postgres - микросекунды
+		At:     time.Now().UTC().Truncate(time.Microsecond),
+		Reason: reason,
+		Actor:  actor,
+	}
+	err = s.postgres.SoftDeleteOrder(ctx, id, deletion)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return err
+	case errors.Is(err, repository.ErrAlreadyDeleted):
+		// кэш мог остаться от прошлого удаления, если Redis был недоступен
+		s.cache.Delete(ctx, id)
+		return err
+	case err != nil:
+		s.log.ErrorContext(ctx, "failed to delete order", slog.String("order_uid", id), logger.Err(err))
+		return storageError(err)
+	}
 
+	s.cache.Delete(ctx, id)
+	s.log.InfoContext(ctx, "order deleted",
+		slog.String("order_uid", id),
+		slog.String("reason", reason),
+		slog.String("actor", actor),
+	)
 	return nil
 }
 
-func (s *OrderService) GetOrderByID(ctx context.Context, id string) (*domain.Order, error) {
+func (s *OrderService) UpdateDelivery(ctx context.Context, id string, patch []byte, version int64, actor string) (_ *domain.Order, err error) {
+	ctx, span := tracer.Start(ctx, "OrderService.UpdateDelivery",
+		trace.WithAttributes(attribute.String("order_uid", id)))
+	defer func() { endSpan(span, err) }()
+
+	if err := validateOrderID(id); err != nil {
+		return nil, err
+	}
+	order, err := s.activeOrder(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if version != 0 && version != order.Version {
+		return nil, fmt.Errorf("%w: %s has version %d, not %d", repository.ErrVersionConflict, id, order.Version, version)
+	}
 
+	delivery, err := patchDelivery(order.Delivery, patch)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validateDelivery(&delivery); err != nil {
+		return nil, fmt.Errorf("%w: delivery validation failed: %w", ErrInvalidOrder, err)
+	}
+	// ничего не изменилось: ни новой версии, ни записи в истории
+	if delivery == order.Delivery {
+		return order, nil
+	}
+
+	// заказ мог измениться после чтения: тогда UpdateDelivery вернет конфликт
+	updated, err := s.postgres.UpdateDelivery(ctx, id, repository.DeliveryUpdate{
+		Version:  order.Version,
+		Delivery: delivery,
+		Actor:    actor,
+		At:       time.Now().UTC().Truncate(time.Microsecond),
+	})
+	if err != nil {
+		if !errors.Is(err, repository.ErrVersionConflict) && !errors.Is(err, repository.ErrNotFound) {
+			s.log.ErrorContext(ctx, "failed to update delivery", slog.String("order_uid", id), logger.Err(err))
+		}
+		return nil, storageError(err)
+	}
+
+	s.cache.Set(ctx, updated.OrderUid, *updated)
+	s.log.InfoContext(ctx, "delivery updated",
+		slog.String("order_uid", id),
+		slog.Int64("version", updated.Version),
+		slog.String("actor", actor),
+	)
+	return updated, nil
+}
+
+// DeliveryHistory - изменения доставки действующего заказа
+func (s *OrderService) DeliveryHistory(ctx context.Context, id string) ([]domain.DeliveryChange, error) {
+	if err := validateOrderID(id); err != nil {
+		return nil, err
+	}
+	if _, err := s.activeOrder(ctx, id); err != nil {
+		return nil, err
+	}
+	history, err := s.postgres.DeliveryHistory(ctx, id)
+	if err != nil {
+		return nil, storageError(err)
+	}
+	return history, nil
+}
+
+// activeOrder читает заказ из базы (не из кэша); удаленный - ErrNotFound
+func (s *OrderService) activeOrder(ctx context.Context, id string) (*domain.Order, error) {
+	order, err := s.postgres.GetByID(ctx, id)
+	if err != nil {
+		return nil, storageError(err)
+	}
+	if order.Deletion != nil {
+		return nil, fmt.Errorf("%w: %s is deleted", repository.ErrNotFound, id)
+	}
+	return order, nil
+}
+
+// storageError помечает ошибки недоступного хранилища как ErrUnavailable
+func storageError(err error) error {
+	if repository.IsUnavailable(err) {
+		return fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+	return err
+}
+
+var validOrderID = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// validateOrderID проверяет order_uid из запроса
+func validateOrderID(id string) error {
 	if id == "" {
-		return nil, errors.New("order id is required")
+		return fmt.Errorf("%w: order id is required", ErrInvalidOrderID)
 	}
 
 	// Проверка длины ID
 	if len(id) > 50 {
-		return nil, errors.New("order id is too long")
+		return fmt.Errorf("%w: order id is too long", ErrInvalidOrderID)
 	}
 
 	// Проверка на допустимые символы
-	validID := regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
-	if !validID.MatchString(id) {
-		return nil, errors.New("order id contains invalid characters")
+	if !validOrderID.MatchString(id) {
+		return fmt.Errorf("%w: order id contains invalid characters", ErrInvalidOrderID)
 	}
+	return nil
+}
 
-	if order, ok := s.cache.Get(id); ok {
-		return order, nil
-	}
+// ListOrders возвращает заказы из базы по фильтру (кэш не используется);
+// удаленные - только с filter.IncludeDeleted
+func (s *OrderService) ListOrders(ctx context.Context, filter repository.OrderFilter) ([]domain.Order, error) {
+	return s.postgres.ListOrders(ctx, filter)
+}
 
-	order, err := s.postgres.GetByID(ctx, id)
+// WarmCache загружает в кэш limit самых свежих заказов и возвращает их количество
+func (s *OrderService) WarmCache(ctx context.Context, limit int) (int, error) {
+	orders, err := s.postgres.ListOrders(ctx, repository.OrderFilter{Limit: limit})
 	if err != nil {
-		log.Printf("order not found in postgres: %v", err)
-		return nil, err
+		return 0, fmt.Errorf("failed to list orders for cache warm-up: %w", err)
 	}
 
-	if order != nil {
-		s.cache.Set(order.OrderUid, *order)
-		return order, nil
+	for _, order := range orders {
+		s.cache.Set(ctx, order.OrderUid, order)
 	}
+	return len(orders), nil
+}
+
+func orderUID(order *domain.Order) slog.Attr {
+	if order == nil {
+		return slog.String("order_uid", "")
+	}
+	return slog.String("order_uid", order.OrderUid)
+}
+
+// endSpan закрывает спан, отмечая ошибку, если она есть
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
 
-	return nil, errors.New("order not found")
+// ValidateOrder проверяет заказ без сохранения (dry-run)
+func (s *OrderService) ValidateOrder(order *domain.Order) error {
+	if err := s.validateOrder(order); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidOrder, err)
+	}
+	return nil
 }
 
 func (s *OrderService) validateOrder(order *domain.Order) error {
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/Sergi-Ch/WB_L0_2025/domain"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/repository"
+)
+
+func TestGetOrderByIDErrors(t *testing.T) {
+	errDown := errors.New("connection refused")
+	svc := NewOrderService(repository.NewMemoryRepository(repository.MemoryOptions{}),
+		&MockCache{orders: make(map[string]*domain.Order)}, slog.New(slog.DiscardHandler))
+	broken := NewOrderService(
+		repository.NewMemoryRepository(repository.MemoryOptions{Err: func(string) error { return errDown }}),
+		&MockCache{orders: make(map[string]*domain.Order)}, slog.New(slog.DiscardHandler))
+	timeout := NewOrderService(
+		repository.NewMemoryRepository(repository.MemoryOptions{Err: func(string) error { return context.DeadlineExceeded }}),
+		&MockCache{orders: make(map[string]*domain.Order)}, slog.New(slog.DiscardHandler))
+
+	tests := []struct {
+		name string
+		svc  *OrderService
+		id   string
+		want error
+	}{
+		{"not found", svc, "missing-order", repository.ErrNotFound},
+		{"empty id", svc, "", ErrInvalidOrderID},
+		{"too long", svc, strings.Repeat("a", 51), ErrInvalidOrderID},
+		{"invalid characters", svc, "order/../1", ErrInvalidOrderID},
+		{"storage error", broken, "some-order", errDown},
+		{"storage timeout", timeout, "some-order", ErrUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order, err := tt.svc.GetOrderByID(context.Background(), tt.id, GetOptions{})
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("GetOrderByID(%q) error = %v, want %v", tt.id, err, tt.want)
+			}
+			if order != nil {
+				t.Errorf("GetOrderByID(%q) returned order %+v", tt.id, order)
+			}
+		})
+	}
+}
+
+func TestDeleteOrder(t *testing.T) {
+	ctx := context.Background()
+	cache := &MockCache{orders: make(map[string]*domain.Order)}
+	svc := NewOrderService(repository.NewMemoryRepository(repository.MemoryOptions{}), cache, slog.New(slog.DiscardHandler))
+
+	order := createTestOrder()
+	if err := svc.SaveOrder(ctx, order); err != nil {
+		t.Fatalf("SaveOrder: %v", err)
+	}
+	if _, ok := cache.orders[order.OrderUid]; !ok {
+		t.Fatal("saved order is not cached")
+	}
+
+	if err := svc.DeleteOrder(ctx, order.OrderUid, "customer request", "support"); err != nil {
+		t.Fatalf("DeleteOrder: %v", err)
+	}
+	if _, ok := cache.orders[order.OrderUid]; ok {
+		t.Error("deleted order is still cached")
+	}
+
+	if _, err := svc.GetOrderByID(ctx, order.OrderUid, GetOptions{}); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("GetOrderByID deleted order = %v, want ErrNotFound", err)
+	}
+	got, err := svc.GetOrderByID(ctx, order.OrderUid, GetOptions{IncludeDeleted: true})
+	if err != nil {
+		t.Fatalf("GetOrderByID with deleted: %v", err)
+	}
+	if got.Deletion == nil || got.Deletion.Reason != "customer request" || got.Deletion.Actor != "support" {
+		t.Errorf("deletion = %+v, want reason and actor from DeleteOrder", got.Deletion)
+	}
+	if _, ok := cache.orders[order.OrderUid]; ok {
+		t.Error("deleted order was cached on read")
+	}
+
+	if err := svc.DeleteOrder(ctx, order.OrderUid, "again", "kafka"); !errors.Is(err, repository.ErrAlreadyDeleted) {
+		t.Errorf("DeleteOrder twice = %v, want ErrAlreadyDeleted", err)
+	}
+	if err := svc.DeleteOrder(ctx, "missing-order", "", ""); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("DeleteOrder unknown order = %v, want ErrNotFound", err)
+	}
+	if err := svc.DeleteOrder(ctx, "bad id", "", ""); !errors.Is(err, ErrInvalidOrderID) {
+		t.Errorf("DeleteOrder invalid id = %v, want ErrInvalidOrderID", err)
+	}
+}
+
+func TestUpdateDelivery(t *testing.T) {
+	ctx := context.Background()
+	cache := &MockCache{orders: make(map[string]*domain.Order)}
+	svc := NewOrderService(repository.NewMemoryRepository(repository.MemoryOptions{}), cache, slog.New(slog.DiscardHandler))
+
+	order := createTestOrder()
+	if err := svc.SaveOrder(ctx, order); err != nil {
+		t.Fatalf("SaveOrder: %v", err)
+	}
+
+	updated, err := svc.UpdateDelivery(ctx, order.OrderUid,
+		[]byte(`{"city": "Tel Aviv", "address": "Dizengoff 1", "region": null}`), 1, "customer")
+	if err != nil {
+		t.Fatalf("UpdateDelivery: %v", err)
+	}
+	want := order.Delivery
+	want.City, want.Address, want.Region = "Tel Aviv", "Dizengoff 1", ""
+	if updated.Delivery != want || updated.Version != 2 {
+		t.Errorf("updated delivery = %+v version %d, want %+v version 2", updated.Delivery, updated.Version, want)
+	}
+	if cached := cache.orders[order.OrderUid]; cached == nil || cached.Delivery != want {
+		t.Errorf("cached delivery = %+v, want %+v", cached, want)
+	}
+
+	history, err := svc.DeliveryHistory(ctx, order.OrderUid)
+	if err != nil {
+		t.Fatalf("DeliveryHistory: %v", err)
+	}
+	if len(history) != 1 || history[0].Before != order.Delivery || history[0].After != want || history[0].Actor != "customer" {
+		t.Errorf("DeliveryHistory = %+v", history)
+	}
+
+	tests := []struct {
+		name    string
+		patch   string
+		version int64
+		want    error
+	}{
+		{"stale version", `{"city": "Haifa"}`, 1, repository.ErrVersionConflict},
+		{"required field removed", `{"phone": null}`, 2, ErrInvalidOrder},
+		{"unknown field", `{"country": "IL"}`, 2, ErrInvalidPatch},
+		{"wrong type", `{"zip": 123}`, 2, ErrInvalidPatch},
+		{"not an object", `["city"]`, 2, ErrInvalidPatch},
+		{"invalid json", `{`, 0, ErrInvalidPatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := svc.UpdateDelivery(ctx, order.OrderUid, []byte(tt.patch), tt.version, ""); !errors.Is(err, tt.want) {
+				t.Errorf("UpdateDelivery(%s) = %v, want %v", tt.patch, err, tt.want)
+			}
+		})
+	}
+
+	// пустой patch ничего не меняет и не создает новую версию
+	same, err := svc.UpdateDelivery(ctx, order.OrderUid, []byte(`{}`), 0, "")
+	if err != nil || same.Version != 2 {
+		t.Errorf("UpdateDelivery empty patch = version %v, %v, want version 2", same, err)
+	}
+}
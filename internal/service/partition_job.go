@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/Sergi-Ch/WB_L0_2025/internal/logger"
+)
+
+// PartitionMaintainer - хранилище с помесячными партициями (PostgresRepository)
+type PartitionMaintainer interface {
+	EnsurePartitions(ctx context.Context, from time.Time, months int) ([]time.Time, error)
+	ExpirePartitions(ctx context.Context, before time.Time, detach bool) ([]time.Time, error)
+}
+
+type PartitionJobConfig struct {
+	// Interval - период проверки партиций
+	Interval time.Duration
+	// Premake - сколько месяцев после текущего создавать заранее
+	Premake int
+	// RetentionMonths - сколько полных месяцев до текущего хранить,
+	// 0 - хранить все
+	RetentionMonths int
+	// Detach - отсоединять старые партиции вместо удаления
+	Detach bool
+}
+
+// PartitionJob создает партиции заранее и убирает устаревшие. Запускается
+// сразу при старте, затем раз в Interval.
+type PartitionJob struct {
+	store PartitionMaintainer
+	cfg   PartitionJobConfig
+	log   *slog.Logger
+	now   func() time.Time
+
+	mu      sync.RWMutex
+	lastRun time.Time
+	err     error
+}
+
+func NewPartitionJob(store PartitionMaintainer, cfg PartitionJobConfig, log *slog.Logger) *PartitionJob {
+	return &PartitionJob{
+		store: store,
+		cfg:   cfg,
+		log:   log.With(slog.String("component", "partitions")),
+		now:   time.Now,
+	}
+}
+
+// Run выполняет обслуживание до отмены ctx; ошибки видны в Check
+func (j *PartitionJob) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		_ = j.RunOnce(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce создает партиции текущего и Premake следующих месяцев, затем
+// убирает месяцы старше RetentionMonths
+func (j *PartitionJob) RunOnce(ctx context.Context) error {
+	now := j.now().UTC()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	err := j.run(ctx, month)
+	j.mu.Lock()
+	j.lastRun, j.err = now, err
+	j.mu.Unlock()
+
+	if err != nil && ctx.Err() == nil {
+		j.log.ErrorContext(ctx, "partition maintenance failed", logger.Err(err))
+	}
+	return err
+}
+
+func (j *PartitionJob) run(ctx context.Context, month time.Time) error {
+	if _, err := j.store.EnsurePartitions(ctx, month, j.cfg.Premake+1); err != nil {
+		return err
+	}
+	if j.cfg.RetentionMonths <= 0 {
+		return nil
+	}
+
+	before := month.AddDate(0, -j.cfg.RetentionMonths, 0)
+	expired, err := j.store.ExpirePartitions(ctx, before, j.cfg.Detach)
+	if len(expired) > 0 {
+		j.log.InfoContext(ctx, "retention applied",
+			slog.Int("months", len(expired)),
+			slog.Time("before", before),
+		)
+	}
+	return err
+}
+
+// Check реализует health.Checker: ошибка последнего запуска. Не критичная:
+// партиции создаются заранее, а недостающие - при записи заказа.
+func (j *PartitionJob) Check(context.Context) error {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	switch {
+	case j.lastRun.IsZero():
+		return errors.New("partition maintenance has not run yet")
+	case j.err != nil:
+		return fmt.Errorf("partition maintenance at %s failed: %w", j.lastRun.Format(time.RFC3339), j.err)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+type fakePartitions struct {
+	ensureFrom   time.Time
+	ensureMonths int
+	expireCalls  int
+	expireBefore time.Time
+	detach       bool
+	err          error
+}
+
+func (f *fakePartitions) EnsurePartitions(_ context.Context, from time.Time, months int) ([]time.Time, error) {
+	f.ensureFrom, f.ensureMonths = from, months
+	return nil, f.err
+}
+
+func (f *fakePartitions) ExpirePartitions(_ context.Context, before time.Time, detach bool) ([]time.Time, error) {
+	f.expireCalls++
+	f.expireBefore, f.detach = before, detach
+	return []time.Time{before.AddDate(0, -1, 0)}, nil
+}
+
+func TestPartitionJobRunOnce(t *testing.T) {
+	store := &fakePartitions{}
+	job := NewPartitionJob(store, PartitionJobConfig{Interval: time.Hour, Premake: 3, RetentionMonths: 12, Detach: true},
+		slog.New(slog.DiscardHandler))
+	// конец месяца в UTC+3 - уже следующий месяц в UTC
+	job.now = func() time.Time { return time.Date(2025, 4, 1, 1, 0, 0, 0, time.FixedZone("MSK", 3*3600)) }
+
+	if err := job.Check(context.Background()); err == nil {
+		t.Error("Check before the first run = nil, want error")
+	}
+	if err := job.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+
+	if want := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC); !store.ensureFrom.Equal(want) || store.ensureMonths != 4 {
+		t.Errorf("EnsurePartitions(%v, %d), want (%v, 4)", store.ensureFrom, store.ensureMonths, want)
+	}
+	if want := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC); !store.expireBefore.Equal(want) || !store.detach {
+		t.Errorf("ExpirePartitions(%v, %v), want (%v, true)", store.expireBefore, store.detach, want)
+	}
+	if err := job.Check(context.Background()); err != nil {
+		t.Errorf("Check after a successful run = %v", err)
+	}
+}
+
+func TestPartitionJobWithoutRetention(t *testing.T) {
+	store := &fakePartitions{}
+	job := NewPartitionJob(store, PartitionJobConfig{Interval: time.Hour}, slog.New(slog.DiscardHandler))
+
+	if err := job.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	if store.ensureMonths != 1 {
+		t.Errorf("EnsurePartitions months = %d, want 1 (current month only)", store.ensureMonths)
+	}
+	if store.expireCalls != 0 {
+		t.Error("ExpirePartitions called with retention disabled")
+	}
+}
+
+func TestPartitionJobFailure(t *testing.T) {
+	store := &fakePartitions{err: errors.New("permission denied")}
+	job := NewPartitionJob(store, PartitionJobConfig{Interval: time.Hour, RetentionMonths: 1},
+		slog.New(slog.DiscardHandler))
+
+	if err := job.RunOnce(context.Background()); !errors.Is(err, store.err) {
+		t.Fatalf("RunOnce = %v, want %v", err, store.err)
+	}
+	if store.expireCalls != 0 {
+		t.Error("retention applied after failed partition creation")
+	}
+	if err := job.Check(context.Background()); !errors.Is(err, store.err) {
+		t.Errorf("Check = %v, want %v", err, store.err)
+	}
+}
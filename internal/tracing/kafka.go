@@ -0,0 +1,72 @@
+package tracing
+
+import (
+	"context"
+
+	internalKafka "github.com/Sergi-Ch/WB_L0_2025/internal/kafka"
+	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// HeaderCarrier адаптирует заголовки сообщения kafka к propagation.TextMapCarrier
+type HeaderCarrier struct {
+	Headers *[]kafka.Header
+}
+
+func (c HeaderCarrier) Get(key string) string {
+	for _, h := range *c.Headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+func (c HeaderCarrier) Set(key, value string) {
+	for i, h := range *c.Headers {
+		if h.Key == key {
+			(*c.Headers)[i].Value = []byte(value)
+			return
+		}
+	}
+	*c.Headers = append(*c.Headers, kafka.Header{Key: key, Value: []byte(value)})
+}
+
+func (c HeaderCarrier) Keys() []string {
+	keys := make([]string, 0, len(*c.Headers))
+	for _, h := range *c.Headers {
+		keys = append(keys, h.Key)
+	}
+	return keys
+}
+
+// KafkaMiddleware продолжает трейс продюсера из W3C заголовков сообщения
+// (traceparent/tracestate) и создает consumer-спан на обработку.
+func KafkaMiddleware(next internalKafka.Handler) internalKafka.Handler {
+	tracer := otel.Tracer(instrumentationName)
+
+	return func(ctx context.Context, msg kafka.Message) error {
+		ctx = otel.GetTextMapPropagator().Extract(ctx, HeaderCarrier{Headers: &msg.Headers})
+		ctx, span := tracer.Start(ctx, msg.Topic+" process",
+			trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithAttributes(
+				attribute.String("messaging.system", "kafka"),
+				attribute.String("messaging.destination.name", msg.Topic),
+				attribute.Int("messaging.kafka.destination.partition", msg.Partition),
+				attribute.Int64("messaging.kafka.message.offset", msg.Offset),
+				attribute.String("messaging.kafka.message.key", string(msg.Key)),
+			),
+		)
+		defer span.End()
+
+		err := next(ctx, msg)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		return err
+	}
+}
@@ -0,0 +1,83 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Sergi-Ch/WB_L0_2025/internal/kafka/kafkatest"
+	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestKafkaTraceRoundTrip(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { _ = tp.Shutdown(context.Background()) })
+
+	// продюсер пишет traceparent в заголовки сообщения
+	ctx, producer := tp.Tracer("producer").Start(context.Background(), "orders publish",
+		trace.WithSpanKind(trace.SpanKindProducer))
+	msg := kafka.Message{Key: []byte("order-1"), Value: []byte(`{}`)}
+	otel.GetTextMapPropagator().Inject(ctx, HeaderCarrier{Headers: &msg.Headers})
+	producer.End()
+
+	broker := kafkatest.NewBroker()
+	if err := broker.Produce("orders", msg); err != nil {
+		t.Fatal(err)
+	}
+	reader := broker.NewReader(kafka.ReaderConfig{Topic: "orders"})
+	defer reader.Close()
+	fetchCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	consumed, err := reader.FetchMessage(fetchCtx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var handled trace.SpanContext
+	handler := KafkaMiddleware(func(ctx context.Context, _ kafka.Message) error {
+		handled = trace.SpanContextFromContext(ctx)
+		return nil
+	})
+	if err := handler(context.Background(), consumed); err != nil {
+		t.Fatal(err)
+	}
+
+	want := producer.SpanContext()
+	if handled.TraceID() != want.TraceID() {
+		t.Errorf("handler trace = %s, want producer trace %s", handled.TraceID(), want.TraceID())
+	}
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("recorded %d spans, want producer and consumer", len(spans))
+	}
+	consumer := spans[1]
+	if consumer.SpanKind() != trace.SpanKindConsumer || consumer.Parent().SpanID() != want.SpanID() {
+		t.Errorf("consumer span %q kind %s parent %s, want child of %s",
+			consumer.Name(), consumer.SpanKind(), consumer.Parent().SpanID(), want.SpanID())
+	}
+}
+
+func TestHeaderCarrier(t *testing.T) {
+	headers := []kafka.Header{{Key: "X-Request-ID", Value: []byte("req-1")}}
+	c := HeaderCarrier{Headers: &headers}
+
+	c.Set("traceparent", "00-a")
+	c.Set("traceparent", "00-b")
+	if got := c.Get("traceparent"); got != "00-b" || len(headers) != 2 {
+		t.Errorf("Get(traceparent) = %q with %d headers, want replaced value and 2 headers", got, len(headers))
+	}
+	if got := c.Get("tracestate"); got != "" {
+		t.Errorf("Get(tracestate) = %q, want empty", got)
+	}
+	if keys := c.Keys(); len(keys) != 2 || keys[0] != "X-Request-ID" || keys[1] != "traceparent" {
+		t.Errorf("Keys() = %v", keys)
+	}
+}
@@ -0,0 +1,133 @@
+-- Ограничения, индексы и типы для схемы из 001_init.
+-- Существующие строки приводятся к новым ограничениям до их включения.
+
+-- Дочерние строки без заказа недостижимы (GetByID ищет по order_uid)
+DELETE FROM deliveries WHERE order_uid IS NULL;
+DELETE FROM payments WHERE order_uid IS NULL;
+DELETE FROM items WHERE order_uid IS NULL;
+
+-- У заказа одна доставка и одна оплата: оставляем первую, ее же читал GetByID
+DELETE FROM deliveries d USING deliveries dup
+WHERE d.order_uid = dup.order_uid AND d.id > dup.id;
+DELETE FROM payments p USING payments dup
+WHERE p.order_uid = dup.order_uid AND p.id > dup.id;
+
+-- NULL -> нулевые значения, как их уже отдавал GetByID
+UPDATE orders SET
+    track_number = COALESCE(track_number, ''),
+    entry = COALESCE(entry, ''),
+    locale = COALESCE(locale, ''),
+    internal_signature = COALESCE(internal_signature, ''),
+    customer_id = COALESCE(customer_id, ''),
+    delivery_service = COALESCE(delivery_service, ''),
+    shardkey = COALESCE(shardkey, ''),
+    sm_id = COALESCE(sm_id, 0),
+    date_created = COALESCE(date_created, '0001-01-01'::timestamp),
+    oof_shard = COALESCE(oof_shard, '');
+
+UPDATE deliveries SET
+    name = COALESCE(name, ''),
+    phone = COALESCE(phone, ''),
+    zip = COALESCE(zip, ''),
+    city = COALESCE(city, ''),
+    address = COALESCE(address, ''),
+    region = COALESCE(region, ''),
+    email = COALESCE(email, '');
+
+UPDATE payments SET
+    transaction = COALESCE(transaction, ''),
+    request_id = COALESCE(request_id, ''),
+    currency = COALESCE(currency, ''),
+    provider = COALESCE(provider, ''),
+    amount = COALESCE(amount, 0),
+    payment_dt = COALESCE(payment_dt, 0),
+    bank = COALESCE(bank, ''),
+    delivery_cost = COALESCE(delivery_cost, 0),
+    goods_total = COALESCE(goods_total, 0),
+    custom_fee = COALESCE(custom_fee, 0);
+
+UPDATE items SET
+    chrt_id = COALESCE(chrt_id, 0),
+    track_number = COALESCE(track_number, ''),
+    price = COALESCE(price, 0),
+    rid = COALESCE(rid, ''),
+    name = COALESCE(name, ''),
+    sale = COALESCE(sale, 0),
+    size = COALESCE(size, ''),
+    total_price = COALESCE(total_price, 0),
+    nm_id = COALESCE(nm_id, 0),
+    brand = COALESCE(brand, ''),
+    status = COALESCE(status, 0);
+
+-- date_created писался из time.Time в UTC, поэтому AT TIME ZONE 'UTC'
+ALTER TABLE orders
+    ALTER COLUMN track_number SET DEFAULT '', ALTER COLUMN track_number SET NOT NULL,
+    ALTER COLUMN entry SET DEFAULT '', ALTER COLUMN entry SET NOT NULL,
+    ALTER COLUMN locale SET DEFAULT '', ALTER COLUMN locale SET NOT NULL,
+    ALTER COLUMN internal_signature SET DEFAULT '', ALTER COLUMN internal_signature SET NOT NULL,
+    ALTER COLUMN customer_id SET DEFAULT '', ALTER COLUMN customer_id SET NOT NULL,
+    ALTER COLUMN delivery_service SET DEFAULT '', ALTER COLUMN delivery_service SET NOT NULL,
+    ALTER COLUMN shardkey SET DEFAULT '', ALTER COLUMN shardkey SET NOT NULL,
+    ALTER COLUMN sm_id SET DEFAULT 0, ALTER COLUMN sm_id SET NOT NULL,
+    ALTER COLUMN date_created TYPE TIMESTAMPTZ USING date_created AT TIME ZONE 'UTC',
+    ALTER COLUMN date_created SET NOT NULL,
+    ALTER COLUMN oof_shard SET DEFAULT '', ALTER COLUMN oof_shard SET NOT NULL;
+
+ALTER TABLE deliveries
+    ALTER COLUMN order_uid SET NOT NULL,
+    ALTER COLUMN name SET DEFAULT '', ALTER COLUMN name SET NOT NULL,
+    ALTER COLUMN phone SET DEFAULT '', ALTER COLUMN phone SET NOT NULL,
+    ALTER COLUMN zip SET DEFAULT '', ALTER COLUMN zip SET NOT NULL,
+    ALTER COLUMN city SET DEFAULT '', ALTER COLUMN city SET NOT NULL,
+    ALTER COLUMN address SET DEFAULT '', ALTER COLUMN address SET NOT NULL,
+    ALTER COLUMN region SET DEFAULT '', ALTER COLUMN region SET NOT NULL,
+    ALTER COLUMN email SET DEFAULT '', ALTER COLUMN email SET NOT NULL,
+    DROP CONSTRAINT IF EXISTS deliveries_order_uid_fkey,
+    ADD CONSTRAINT deliveries_order_uid_fkey
+        FOREIGN KEY (order_uid) REFERENCES orders(order_uid) ON DELETE CASCADE,
+    ADD CONSTRAINT deliveries_order_uid_key UNIQUE (order_uid);
+
+-- суммы и payment_dt (unix-время) не помещаются в INT
+ALTER TABLE payments
+    ALTER COLUMN order_uid SET NOT NULL,
+    ALTER COLUMN transaction SET DEFAULT '', ALTER COLUMN transaction SET NOT NULL,
+    ALTER COLUMN request_id SET DEFAULT '', ALTER COLUMN request_id SET NOT NULL,
+    ALTER COLUMN currency SET DEFAULT '', ALTER COLUMN currency SET NOT NULL,
+    ALTER COLUMN provider SET DEFAULT '', ALTER COLUMN provider SET NOT NULL,
+    ALTER COLUMN amount TYPE BIGINT, ALTER COLUMN amount SET DEFAULT 0, ALTER COLUMN amount SET NOT NULL,
+    ALTER COLUMN payment_dt TYPE BIGINT, ALTER COLUMN payment_dt SET DEFAULT 0, ALTER COLUMN payment_dt SET NOT NULL,
+    ALTER COLUMN bank SET DEFAULT '', ALTER COLUMN bank SET NOT NULL,
+    ALTER COLUMN delivery_cost TYPE BIGINT, ALTER COLUMN delivery_cost SET DEFAULT 0,
+    ALTER COLUMN delivery_cost SET NOT NULL,
+    ALTER COLUMN goods_total TYPE BIGINT, ALTER COLUMN goods_total SET DEFAULT 0,
+    ALTER COLUMN goods_total SET NOT NULL,
+    ALTER COLUMN custom_fee TYPE BIGINT, ALTER COLUMN custom_fee SET DEFAULT 0,
+    ALTER COLUMN custom_fee SET NOT NULL,
+    DROP CONSTRAINT IF EXISTS payments_order_uid_fkey,
+    ADD CONSTRAINT payments_order_uid_fkey
+        FOREIGN KEY (order_uid) REFERENCES orders(order_uid) ON DELETE CASCADE,
+    ADD CONSTRAINT payments_order_uid_key UNIQUE (order_uid);
+
+ALTER TABLE items
+    ALTER COLUMN order_uid SET NOT NULL,
+    ALTER COLUMN chrt_id TYPE BIGINT, ALTER COLUMN chrt_id SET DEFAULT 0, ALTER COLUMN chrt_id SET NOT NULL,
+    ALTER COLUMN track_number SET DEFAULT '', ALTER COLUMN track_number SET NOT NULL,
+    ALTER COLUMN price TYPE BIGINT, ALTER COLUMN price SET DEFAULT 0, ALTER COLUMN price SET NOT NULL,
+    ALTER COLUMN rid SET DEFAULT '', ALTER COLUMN rid SET NOT NULL,
+    ALTER COLUMN name SET DEFAULT '', ALTER COLUMN name SET NOT NULL,
+    ALTER COLUMN sale SET DEFAULT 0, ALTER COLUMN sale SET NOT NULL,
+    ALTER COLUMN size SET DEFAULT '', ALTER COLUMN size SET NOT NULL,
+    ALTER COLUMN total_price TYPE BIGINT, ALTER COLUMN total_price SET DEFAULT 0,
+    ALTER COLUMN total_price SET NOT NULL,
+    ALTER COLUMN nm_id TYPE BIGINT, ALTER COLUMN nm_id SET DEFAULT 0, ALTER COLUMN nm_id SET NOT NULL,
+    ALTER COLUMN brand SET DEFAULT '', ALTER COLUMN brand SET NOT NULL,
+    ALTER COLUMN status SET DEFAULT 0, ALTER COLUMN status SET NOT NULL,
+    DROP CONSTRAINT IF EXISTS items_order_uid_fkey,
+    ADD CONSTRAINT items_order_uid_fkey
+        FOREIGN KEY (order_uid) REFERENCES orders(order_uid) ON DELETE CASCADE;
+
+-- deliveries и payments ищутся по индексу уникального ключа
+CREATE INDEX IF NOT EXISTS items_order_uid_idx ON items (order_uid, id);
+-- ListOrders: ORDER BY date_created DESC, order_uid и фильтр по customer_id
+CREATE INDEX IF NOT EXISTS orders_date_created_idx ON orders (date_created DESC, order_uid);
+CREATE INDEX IF NOT EXISTS orders_customer_id_idx ON orders (customer_id, date_created DESC);
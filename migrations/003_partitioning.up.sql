@@ -0,0 +1,148 @@
+-- Помесячное партиционирование по date_created (UTC). Партиции одного месяца
+-- у всех четырех таблиц: orders_p2025_03, deliveries_p2025_03 и т.д.
+-- Будущие партиции создает фоновая задача (partitions.*), старые она же
+-- отсоединяет или удаляет.
+
+-- Уникальный ключ партиционированной таблицы обязан включать date_created,
+-- поэтому уникальность order_uid держит отдельная таблица. По ней же
+-- GetByID находит месяц заказа.
+CREATE TABLE order_uids (
+    order_uid VARCHAR PRIMARY KEY,
+    date_created TIMESTAMPTZ NOT NULL
+);
+
+-- Создает партиции месяца, в который попадает ts, если их еще нет.
+-- Возвращает true, если что-то создано.
+CREATE FUNCTION create_order_partitions(ts TIMESTAMPTZ) RETURNS BOOLEAN AS $$
+DECLARE
+    m TIMESTAMP := date_trunc('month', ts AT TIME ZONE 'UTC');
+    suffix TEXT := to_char(m, '"p"YYYY_MM');
+    t TEXT;
+    created BOOLEAN := false;
+BEGIN
+    -- параллельные вызовы (фоновая задача и SaveOrders) не создают одно и то же
+    PERFORM pg_advisory_xact_lock(hashtext('create_order_partitions'));
+    FOREACH t IN ARRAY ARRAY['orders', 'deliveries', 'payments', 'items'] LOOP
+        IF to_regclass(t || '_' || suffix) IS NULL THEN
+            EXECUTE format('CREATE TABLE %I PARTITION OF %I FOR VALUES FROM (%L) TO (%L)',
+                t || '_' || suffix, t, m AT TIME ZONE 'UTC', (m + INTERVAL '1 month') AT TIME ZONE 'UTC');
+            created := true;
+        END IF;
+    END LOOP;
+    RETURN created;
+END
+$$ LANGUAGE plpgsql;
+
+-- Данные копируются в новые таблицы, старые удаляются
+CREATE TABLE orders_old AS SELECT * FROM orders;
+CREATE TABLE deliveries_old AS SELECT * FROM deliveries;
+CREATE TABLE payments_old AS SELECT * FROM payments;
+CREATE TABLE items_old AS SELECT * FROM items;
+DROP TABLE items, payments, deliveries, orders;
+
+CREATE TABLE orders (
+    order_uid VARCHAR NOT NULL REFERENCES order_uids(order_uid) ON DELETE CASCADE,
+    track_number VARCHAR NOT NULL DEFAULT '',
+    entry VARCHAR NOT NULL DEFAULT '',
+    locale VARCHAR NOT NULL DEFAULT '',
+    internal_signature VARCHAR NOT NULL DEFAULT '',
+    customer_id VARCHAR NOT NULL DEFAULT '',
+    delivery_service VARCHAR NOT NULL DEFAULT '',
+    shardkey VARCHAR NOT NULL DEFAULT '',
+    sm_id INT NOT NULL DEFAULT 0,
+    date_created TIMESTAMPTZ NOT NULL,
+    oof_shard VARCHAR NOT NULL DEFAULT '',
+    PRIMARY KEY (order_uid, date_created)
+) PARTITION BY RANGE (date_created);
+
+CREATE TABLE deliveries (
+    id BIGSERIAL,
+    order_uid VARCHAR NOT NULL,
+    date_created TIMESTAMPTZ NOT NULL,
+    name VARCHAR NOT NULL DEFAULT '',
+    phone VARCHAR NOT NULL DEFAULT '',
+    zip VARCHAR NOT NULL DEFAULT '',
+    city VARCHAR NOT NULL DEFAULT '',
+    address VARCHAR NOT NULL DEFAULT '',
+    region VARCHAR NOT NULL DEFAULT '',
+    email VARCHAR NOT NULL DEFAULT '',
+    PRIMARY KEY (id, date_created),
+    UNIQUE (order_uid, date_created),
+    FOREIGN KEY (order_uid, date_created) REFERENCES orders(order_uid, date_created) ON DELETE CASCADE
+) PARTITION BY RANGE (date_created);
+
+CREATE TABLE payments (
+    id BIGSERIAL,
+    order_uid VARCHAR NOT NULL,
+    date_created TIMESTAMPTZ NOT NULL,
+    transaction VARCHAR NOT NULL DEFAULT '',
+    request_id VARCHAR NOT NULL DEFAULT '',
+    currency VARCHAR NOT NULL DEFAULT '',
+    provider VARCHAR NOT NULL DEFAULT '',
+    amount BIGINT NOT NULL DEFAULT 0,
+    payment_dt BIGINT NOT NULL DEFAULT 0,
+    bank VARCHAR NOT NULL DEFAULT '',
+    delivery_cost BIGINT NOT NULL DEFAULT 0,
+    goods_total BIGINT NOT NULL DEFAULT 0,
+    custom_fee BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (id, date_created),
+    UNIQUE (order_uid, date_created),
+    FOREIGN KEY (order_uid, date_created) REFERENCES orders(order_uid, date_created) ON DELETE CASCADE
+) PARTITION BY RANGE (date_created);
+
+CREATE TABLE items (
+    id BIGSERIAL,
+    order_uid VARCHAR NOT NULL,
+    date_created TIMESTAMPTZ NOT NULL,
+    chrt_id BIGINT NOT NULL DEFAULT 0,
+    track_number VARCHAR NOT NULL DEFAULT '',
+    price BIGINT NOT NULL DEFAULT 0,
+    rid VARCHAR NOT NULL DEFAULT '',
+    name VARCHAR NOT NULL DEFAULT '',
+    sale INT NOT NULL DEFAULT 0,
+    size VARCHAR NOT NULL DEFAULT '',
+    total_price BIGINT NOT NULL DEFAULT 0,
+    nm_id BIGINT NOT NULL DEFAULT 0,
+    brand VARCHAR NOT NULL DEFAULT '',
+    status INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (id, date_created),
+    FOREIGN KEY (order_uid, date_created) REFERENCES orders(order_uid, date_created) ON DELETE CASCADE
+) PARTITION BY RANGE (date_created);
+
+CREATE INDEX items_order_uid_idx ON items (order_uid, id);
+CREATE INDEX orders_date_created_idx ON orders (date_created DESC, order_uid);
+CREATE INDEX orders_customer_id_idx ON orders (customer_id, date_created DESC);
+
+-- партиции для всех месяцев с данными, текущего и трех следующих
+SELECT create_order_partitions(date_created) FROM (
+    SELECT DISTINCT date_trunc('month', date_created AT TIME ZONE 'UTC') AT TIME ZONE 'UTC' AS date_created
+    FROM orders_old
+) months;
+SELECT create_order_partitions(now() + make_interval(months => n)) FROM generate_series(0, 3) AS n;
+
+INSERT INTO order_uids SELECT order_uid, date_created FROM orders_old;
+INSERT INTO orders (order_uid, track_number, entry, locale, internal_signature, customer_id,
+    delivery_service, shardkey, sm_id, date_created, oof_shard)
+SELECT order_uid, track_number, entry, locale, internal_signature, customer_id,
+    delivery_service, shardkey, sm_id, date_created, oof_shard
+FROM orders_old;
+INSERT INTO deliveries (id, order_uid, date_created, name, phone, zip, city, address, region, email)
+SELECT d.id, d.order_uid, o.date_created, d.name, d.phone, d.zip, d.city, d.address, d.region, d.email
+FROM deliveries_old d JOIN orders_old o USING (order_uid);
+INSERT INTO payments (id, order_uid, date_created, transaction, request_id, currency, provider,
+    amount, payment_dt, bank, delivery_cost, goods_total, custom_fee)
+SELECT p.id, p.order_uid, o.date_created, p.transaction, p.request_id, p.currency, p.provider,
+    p.amount, p.payment_dt, p.bank, p.delivery_cost, p.goods_total, p.custom_fee
+FROM payments_old p JOIN orders_old o USING (order_uid);
+INSERT INTO items (id, order_uid, date_created, chrt_id, track_number, price, rid, name,
+    sale, size, total_price, nm_id, brand, status)
+SELECT i.id, i.order_uid, o.date_created, i.chrt_id, i.track_number, i.price, i.rid, i.name,
+    i.sale, i.size, i.total_price, i.nm_id, i.brand, i.status
+FROM items_old i JOIN orders_old o USING (order_uid);
+
+-- id перенесены как есть, последовательности продолжают после них
+SELECT setval(pg_get_serial_sequence('deliveries', 'id'), COALESCE(MAX(id), 0) + 1, false) FROM deliveries;
+SELECT setval(pg_get_serial_sequence('payments', 'id'), COALESCE(MAX(id), 0) + 1, false) FROM payments;
+SELECT setval(pg_get_serial_sequence('items', 'id'), COALESCE(MAX(id), 0) + 1, false) FROM items;
+
+DROP TABLE items_old, payments_old, deliveries_old, orders_old;
@@ -0,0 +1,6 @@
+-- Мягкое удаление (отмена) заказов: строка остается, чтения ее пропускают.
+-- Колонки добавляются и во все партиции orders.
+ALTER TABLE orders
+    ADD COLUMN deleted_at TIMESTAMPTZ,
+    ADD COLUMN delete_reason TEXT NOT NULL DEFAULT '',
+    ADD COLUMN deleted_by TEXT NOT NULL DEFAULT '';
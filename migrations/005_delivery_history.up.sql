@@ -0,0 +1,18 @@
+-- Версия заказа для оптимистичной блокировки (ETag/If-Match) и история
+-- изменений доставки. Версия растет при каждом изменении заказа.
+ALTER TABLE orders
+    ADD COLUMN version BIGINT NOT NULL DEFAULT 1,
+    ADD COLUMN updated_at TIMESTAMPTZ;
+
+-- Ссылка на order_uids: история удаляется вместе с заказом, в том числе
+-- при удалении или отсоединении его партиции
+CREATE TABLE delivery_history (
+    id BIGSERIAL PRIMARY KEY,
+    order_uid VARCHAR NOT NULL REFERENCES order_uids(order_uid) ON DELETE CASCADE,
+    version BIGINT NOT NULL,
+    changed_at TIMESTAMPTZ NOT NULL,
+    actor VARCHAR NOT NULL DEFAULT '',
+    before JSONB NOT NULL,
+    after JSONB NOT NULL,
+    UNIQUE (order_uid, version)
+);
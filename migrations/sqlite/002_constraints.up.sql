@@ -0,0 +1,104 @@
+-- migrations/002_constraints.up.sql для SQLite. ALTER TABLE в SQLite не
+-- меняет ограничения, поэтому таблицы пересоздаются с копированием данных.
+-- INTEGER в SQLite и так 64-битный, date_created остается текстом в UTC.
+CREATE TABLE orders_new (
+    order_uid TEXT PRIMARY KEY,
+    track_number TEXT NOT NULL DEFAULT '',
+    entry TEXT NOT NULL DEFAULT '',
+    locale TEXT NOT NULL DEFAULT '',
+    internal_signature TEXT NOT NULL DEFAULT '',
+    customer_id TEXT NOT NULL DEFAULT '',
+    delivery_service TEXT NOT NULL DEFAULT '',
+    shardkey TEXT NOT NULL DEFAULT '',
+    sm_id INTEGER NOT NULL DEFAULT 0,
+    date_created TEXT NOT NULL,
+    oof_shard TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE deliveries_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    order_uid TEXT NOT NULL UNIQUE REFERENCES orders_new(order_uid) ON DELETE CASCADE,
+    name TEXT NOT NULL DEFAULT '',
+    phone TEXT NOT NULL DEFAULT '',
+    zip TEXT NOT NULL DEFAULT '',
+    city TEXT NOT NULL DEFAULT '',
+    address TEXT NOT NULL DEFAULT '',
+    region TEXT NOT NULL DEFAULT '',
+    email TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE payments_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    order_uid TEXT NOT NULL UNIQUE REFERENCES orders_new(order_uid) ON DELETE CASCADE,
+    "transaction" TEXT NOT NULL DEFAULT '',
+    request_id TEXT NOT NULL DEFAULT '',
+    currency TEXT NOT NULL DEFAULT '',
+    provider TEXT NOT NULL DEFAULT '',
+    amount INTEGER NOT NULL DEFAULT 0,
+    payment_dt INTEGER NOT NULL DEFAULT 0,
+    bank TEXT NOT NULL DEFAULT '',
+    delivery_cost INTEGER NOT NULL DEFAULT 0,
+    goods_total INTEGER NOT NULL DEFAULT 0,
+    custom_fee INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE items_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    order_uid TEXT NOT NULL REFERENCES orders_new(order_uid) ON DELETE CASCADE,
+    chrt_id INTEGER NOT NULL DEFAULT 0,
+    track_number TEXT NOT NULL DEFAULT '',
+    price INTEGER NOT NULL DEFAULT 0,
+    rid TEXT NOT NULL DEFAULT '',
+    name TEXT NOT NULL DEFAULT '',
+    sale INTEGER NOT NULL DEFAULT 0,
+    size TEXT NOT NULL DEFAULT '',
+    total_price INTEGER NOT NULL DEFAULT 0,
+    nm_id INTEGER NOT NULL DEFAULT 0,
+    brand TEXT NOT NULL DEFAULT '',
+    status INTEGER NOT NULL DEFAULT 0
+);
+
+-- NULL -> нулевые значения, как их уже отдавал GetByID
+INSERT INTO orders_new
+SELECT order_uid, COALESCE(track_number, ''), COALESCE(entry, ''), COALESCE(locale, ''),
+    COALESCE(internal_signature, ''), COALESCE(customer_id, ''), COALESCE(delivery_service, ''),
+    COALESCE(shardkey, ''), COALESCE(sm_id, 0), COALESCE(date_created, '0001-01-01 00:00:00.000000'),
+    COALESCE(oof_shard, '')
+FROM orders;
+
+-- одна доставка и одна оплата на заказ: первая, ее же читал GetByID;
+-- строки без order_uid недостижимы и не переносятся
+INSERT INTO deliveries_new
+SELECT id, order_uid, COALESCE(name, ''), COALESCE(phone, ''), COALESCE(zip, ''), COALESCE(city, ''),
+    COALESCE(address, ''), COALESCE(region, ''), COALESCE(email, '')
+FROM deliveries
+WHERE id IN (SELECT MIN(id) FROM deliveries WHERE order_uid IS NOT NULL GROUP BY order_uid);
+
+INSERT INTO payments_new
+SELECT id, order_uid, COALESCE("transaction", ''), COALESCE(request_id, ''), COALESCE(currency, ''),
+    COALESCE(provider, ''), COALESCE(amount, 0), COALESCE(payment_dt, 0), COALESCE(bank, ''),
+    COALESCE(delivery_cost, 0), COALESCE(goods_total, 0), COALESCE(custom_fee, 0)
+FROM payments
+WHERE id IN (SELECT MIN(id) FROM payments WHERE order_uid IS NOT NULL GROUP BY order_uid);
+
+INSERT INTO items_new
+SELECT id, order_uid, COALESCE(chrt_id, 0), COALESCE(track_number, ''), COALESCE(price, 0),
+    COALESCE(rid, ''), COALESCE(name, ''), COALESCE(sale, 0), COALESCE(size, ''),
+    COALESCE(total_price, 0), COALESCE(nm_id, 0), COALESCE(brand, ''), COALESCE(status, 0)
+FROM items
+WHERE order_uid IS NOT NULL;
+
+DROP TABLE items;
+DROP TABLE payments;
+DROP TABLE deliveries;
+DROP TABLE orders;
+
+-- RENAME обновляет ссылки REFERENCES orders_new в дочерних таблицах
+ALTER TABLE orders_new RENAME TO orders;
+ALTER TABLE deliveries_new RENAME TO deliveries;
+ALTER TABLE payments_new RENAME TO payments;
+ALTER TABLE items_new RENAME TO items;
+
+CREATE INDEX items_order_uid_idx ON items (order_uid, id);
+CREATE INDEX orders_date_created_idx ON orders (date_created DESC, order_uid);
+CREATE INDEX orders_customer_id_idx ON orders (customer_id, date_created DESC);
@@ -0,0 +1,5 @@
+-- migrations/004_soft_delete.up.sql для SQLite; deleted_at - текст в UTC,
+-- как date_created
+ALTER TABLE orders ADD COLUMN deleted_at TEXT;
+ALTER TABLE orders ADD COLUMN delete_reason TEXT NOT NULL DEFAULT '';
+ALTER TABLE orders ADD COLUMN deleted_by TEXT NOT NULL DEFAULT '';
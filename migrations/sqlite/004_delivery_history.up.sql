@@ -0,0 +1,14 @@
+-- migrations/005_delivery_history.up.sql для SQLite; before и after - JSON текстом
+ALTER TABLE orders ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE orders ADD COLUMN updated_at TEXT;
+
+CREATE TABLE delivery_history (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    order_uid TEXT NOT NULL REFERENCES orders(order_uid) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    changed_at TEXT NOT NULL,
+    actor TEXT NOT NULL DEFAULT '',
+    before TEXT NOT NULL,
+    after TEXT NOT NULL,
+    UNIQUE (order_uid, version)
+);
@@ -1,3 +1,5 @@
+//go:build ignore
+
 // scripts/performance_benchmark.go
 package main
 
@@ -5,12 +7,17 @@ import (
 	"context"
 	"fmt"
 	"github.com/Sergi-Ch/WB_L0_2025/domain"
+	"github.com/Sergi-Ch/WB_L0_2025/internal/repository"
 	"github.com/Sergi-Ch/WB_L0_2025/internal/service"
 	"log"
+	"log/slog"
 	"sync"
 	"time"
 )
 
+// dbLatency - имитация сетевого запроса к Postgres
+const dbLatency = time.Millisecond
+
 type MockRedis struct {
 	orders map[string]*domain.Order
 	mu     sync.RWMutex // для потокобезопасности
@@ -22,55 +29,23 @@ func NewMockRedis() *MockRedis {
 	}
 }
 
-func (m *MockRedis) Get(orderUID string) (*domain.Order, bool) {
+func (m *MockRedis) Get(_ context.Context, orderUID string) (*domain.Order, bool) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 	order, exists := m.orders[orderUID]
 	return order, exists
 }
 
-func (m *MockRedis) Set(orderUID string, order domain.Order) {
+func (m *MockRedis) Set(_ context.Context, orderUID string, order domain.Order) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.orders[orderUID] = &order
 }
 
-type MockPostgres struct {
-	orders map[string]*domain.Order
-	mu     sync.RWMutex
-}
-
-func NewMockPostgres() *MockPostgres {
-	return &MockPostgres{
-		orders: make(map[string]*domain.Order),
-	}
-}
-
-func (m *MockPostgres) SaveOrders(ctx context.Context, order *domain.Order) error {
+func (m *MockRedis) Delete(_ context.Context, orderUID string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-
-	for i := 0; i < 1000000; i++ {
-		_ = i * i
-	}
-
-	m.orders[order.OrderUid] = order
-	return nil
-}
-
-func (m *MockPostgres) GetByID(ctx context.Context, orderUID string) (*domain.Order, error) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-
-	for i := 0; i < 1000000; i++ {
-		_ = i * i
-	}
-
-	order, exists := m.orders[orderUID]
-	if !exists {
-		return nil, fmt.Errorf("order not found")
-	}
-	return order, nil
+	delete(m.orders, orderUID)
 }
 
 func main() {
@@ -88,6 +63,7 @@ func main() {
 		},
 		Payment: domain.Payment{
 			Transaction: "PERF_TXN_123456",
+			Currency:    "RUB",
 			Amount:      2500,
 		},
 		Items: []domain.Item{
@@ -101,13 +77,13 @@ func main() {
 	}
 
 	redisCache := NewMockRedis()
-	postgres := NewMockPostgres()
+	postgres := repository.NewMemoryRepository(repository.MemoryOptions{Latency: dbLatency})
 
-	service := service.NewOrderService(postgres, redisCache)
+	orderService := service.NewOrderService(postgres, redisCache, slog.New(slog.DiscardHandler))
 	ctx := context.Background()
 
 	fmt.Println("Сохраняем тестовый заказ...")
-	if err := service.SaveOrder(ctx, order); err != nil {
+	if err := orderService.SaveOrder(ctx, order); err != nil {
 		log.Fatal("Ошибка сохранения заказа:", err)
 	}
 
@@ -115,7 +91,7 @@ func main() {
 	start := time.Now()
 
 	for i := 0; i < 100; i++ {
-		_, err := service.GetOrderByID(ctx, order.OrderUid)
+		_, err := orderService.GetOrderByID(ctx, order.OrderUid, service.GetOptions{})
 		if err != nil {
 			log.Printf("Ошибка: %v", err)
 		}
@@ -126,12 +102,16 @@ func main() {
 	fmt.Printf("Среднее время одного запроса: %v\n", cacheTime/100)
 
 	fmt.Println("\nТест 2: Получение из БД (100 запросов) ")
-	redisCache.orders = make(map[string]*domain.Order)
 
 	start = time.Now()
 
 	for i := 0; i < 100; i++ {
-		_, err := service.GetOrderByID(ctx, order.OrderUid)
+		// сервис кладет прочитанный из БД заказ в кеш - сбрасываем каждый раз
+		redisCache.mu.Lock()
+		redisCache.orders = make(map[string]*domain.Order)
+		redisCache.mu.Unlock()
+
+		_, err := orderService.GetOrderByID(ctx, order.OrderUid, service.GetOptions{})
 		if err != nil {
 			log.Printf("Ошибка: %v", err)
 		}
@@ -143,7 +123,7 @@ func main() {
 
 	fmt.Println("\n РЕЗУЛЬТАТЫ")
 	fmt.Printf("Память (кеш):  %v всего (%v среднее)\n", cacheTime, cacheTime/100)
-	fmt.Printf("Вычисления (БД): %v всего (%v среднее)\n", dbTime, dbTime/100)
+	fmt.Printf("БД (задержка %v): %v всего (%v среднее)\n", dbLatency, dbTime, dbTime/100)
 
 	if cacheTime > 0 {
 		ratio := float64(dbTime) / float64(cacheTime)
@@ -1,28 +1,54 @@
+//go:build ignore
+
 package main
 
 import (
 	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
 	"math/rand"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/Sergi-Ch/WB_L0_2025/domain"
+	internalKafka "github.com/Sergi-Ch/WB_L0_2025/internal/kafka"
 	"github.com/segmentio/kafka-go"
 )
 
 func main() {
 	rand.Seed(time.Now().UnixNano())
 
-	brokers := []string{"kafka:29092"}
-	topic := "orders"
+	brokers := flag.String("brokers", "kafka:29092", "comma-separated Kafka brokers")
+	topic := flag.String("topic", "orders", "topic to send orders to")
+
+	// те же настройки подключения, что и у сервиса (kafka.tls.*, kafka.sasl.*)
+	var sec internalKafka.SecurityConfig
+	flag.BoolVar(&sec.TLS.Enabled, "tls", false, "connect over TLS")
+	flag.StringVar(&sec.TLS.CAFile, "tls-ca", "", "PEM file with CA certificates")
+	flag.StringVar(&sec.TLS.CertFile, "tls-cert", "", "PEM client certificate")
+	flag.StringVar(&sec.TLS.KeyFile, "tls-key", "", "PEM client key")
+	flag.StringVar(&sec.TLS.ServerName, "tls-server-name", "", "expected broker certificate name")
+	flag.BoolVar(&sec.TLS.InsecureSkipVerify, "tls-insecure", false, "do not verify broker certificates")
+	flag.StringVar(&sec.SASL.Mechanism, "sasl-mechanism", "none", "none, plain, scram-sha-256, scram-sha-512")
+	flag.StringVar(&sec.SASL.Username, "sasl-username", "", "SASL username")
+	flag.StringVar(&sec.SASL.PasswordFile, "sasl-password-file", "", "file with the SASL password (or env KAFKA_SASL_PASSWORD)")
+	flag.Parse()
+	sec.SASL.Password = os.Getenv("KAFKA_SASL_PASSWORD")
 
-	writer := kafka.NewWriter(kafka.WriterConfig{
-		Brokers:  brokers,
-		Topic:    topic,
-		Balancer: &kafka.LeastBytes{},
-	})
+	transport, err := sec.Transport("order-generator")
+	if err != nil {
+		log.Fatalf("invalid kafka settings: %v", err)
+	}
+
+	writer := &kafka.Writer{
+		Addr:      kafka.TCP(strings.Split(*brokers, ",")...),
+		Topic:     *topic,
+		Balancer:  &kafka.LeastBytes{},
+		Transport: transport,
+	}
 	defer writer.Close()
 
 	numOrders := rand.Intn(11) + 5 // 5–15 заказов
@@ -59,15 +85,15 @@ func generateRandomOrder() domain.Order {
 	items := make([]domain.Item, numItems)
 	for i := 0; i < numItems; i++ {
 		items[i] = domain.Item{
-			ChrtId:      rand.Intn(100000),
+			ChrtId:      rand.Int63n(100000),
 			TrackNumber: fmt.Sprintf("TRACK-%d", rand.Intn(9999)),
-			Price:       rand.Intn(1000),
+			Price:       rand.Int63n(1000),
 			Rid:         fmt.Sprintf("rid-%d", rand.Intn(100000)),
 			Name:        fmt.Sprintf("Product-%d", i+1),
 			Sale:        rand.Intn(50),
 			Size:        "M",
-			TotalPrice:  rand.Intn(1000),
-			NmId:        rand.Intn(10000),
+			TotalPrice:  rand.Int63n(1000),
+			NmId:        rand.Int63n(10000),
 			Brand:       "BrandX",
 			Status:      202,
 		}
@@ -91,7 +117,7 @@ func generateRandomOrder() domain.Order {
 			Transaction:  uid,
 			Currency:     "USD",
 			Provider:     "wbpay",
-			Amount:       rand.Intn(5000),
+			Amount:       rand.Int63n(5000),
 			DeliveryCost: 1500,
 			GoodsTotal:   1000,
 			CustomFee:    0,